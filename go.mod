@@ -3,19 +3,20 @@ module github.com/dspasibenko/twin-go
 go 1.23.3
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/gdamore/tcell/v2 v2.9.0
+	github.com/mattn/go-runewidth v0.0.16
+	github.com/rivo/uniseg v0.4.3
 	github.com/stretchr/testify v1.11.1
+	golang.org/x/sys v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/gdamore/encoding v1.0.1 // indirect
 	github.com/lucasb-eyer/go-colorful v1.2.0 // indirect
-	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/rivo/uniseg v0.4.3 // indirect
-	golang.org/x/sys v0.35.0 // indirect
 	golang.org/x/term v0.34.0 // indirect
 	golang.org/x/text v0.28.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
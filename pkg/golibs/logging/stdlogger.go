@@ -24,27 +24,35 @@ import (
 
 type (
 	stdLogger struct {
-		writer io.Writer
-		name   string
-		vars   map[string]string
+		name string
+		vars map[string]string
 	}
 )
 
 var (
-	stdMx    sync.Mutex
-	stdLevel int32 = int32(INFO)
-	levels         = map[Level]string{ERROR: "ERROR", DEBUG: "DEBUG", INFO: "INFO", WARN: "WARN", TRACE: "TRACE"}
+	stdMx     sync.Mutex
+	stdLevel  int32     = int32(INFO)
+	stdWriter io.Writer = os.Stdout
+	levels              = map[Level]string{ERROR: "ERROR", DEBUG: "DEBUG", INFO: "INFO", WARN: "WARN", TRACE: "TRACE"}
 )
 
 // stdNewLogger returns a Logger interface by its name
 func stdNewLogger(name string) Logger {
 	sl := new(stdLogger)
 	sl.name = name
-	sl.writer = os.Stdout
 	sl.vars = map[string]string{}
 	return sl
 }
 
+// SetOutput sets the writer for the std loggers, including the ones created already. The
+// default is os.Stdout. Every message is written by one or more Write calls, and the message
+// ends with the new line.
+func SetOutput(w io.Writer) {
+	stdMx.Lock()
+	defer stdMx.Unlock()
+	stdWriter = w
+}
+
 func stdSetLevel(lvl Level) {
 	atomic.SwapInt32(&stdLevel, int32(lvl))
 }
@@ -85,11 +93,11 @@ func (sl *stdLogger) logf(lvl Level, format string, args ...interface{}) {
 		return
 	}
 	now := time.Now()
-	fmt.Fprint(sl.writer, "[", now.Format("15:04:05.000000"), "] ", levels[lvl], "\t", sl.name, ": ")
-	fmt.Fprintf(sl.writer, format, args...)
+	fmt.Fprint(stdWriter, "[", now.Format("15:04:05.000000"), "] ", levels[lvl], "\t", sl.name, ": ")
+	fmt.Fprintf(stdWriter, format, args...)
 	if len(sl.vars) > 0 {
-		fmt.Fprintf(sl.writer, " %v", sl.vars)
+		fmt.Fprintf(stdWriter, " %v", sl.vars)
 	}
-	fmt.Fprintln(sl.writer)
+	fmt.Fprintln(stdWriter)
 	stdMx.Unlock()
 }
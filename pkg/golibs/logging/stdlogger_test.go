@@ -1,7 +1,9 @@
 package logging
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
+	"os"
 	"testing"
 )
 
@@ -15,3 +17,15 @@ func TestAll(t *testing.T) {
 	l.Debugf("hello world %d", 2)
 	l.Tracef("hello world %d", 3)
 }
+
+func TestSetOutput(t *testing.T) {
+	l := NewLogger("test")
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(os.Stdout)
+	SetLevel(INFO)
+	l.Infof("hello %d", 1)
+	l.Debugf("hidden")
+	assert.Contains(t, buf.String(), "INFO\ttest: hello 1\n")
+	assert.NotContains(t, buf.String(), "hidden")
+}
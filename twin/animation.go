@@ -0,0 +1,74 @@
+package twin
+
+import (
+	"time"
+)
+
+// AnimationFunc is called once per frame with the frame time, while it returns true
+type AnimationFunc func(now time.Time) bool
+
+type animationHolder struct {
+	af AnimationFunc
+}
+
+// animate calls the animations, if the next animation frame is due, and returns the time
+// till the next one, or 0 if there are no animations
+func (c *controller) animate() time.Duration {
+	tick := time.Duration(c.frameInterval.Load())
+	if tick <= 0 {
+		tick = time.Second / defaultFrameRate
+	}
+	c.lock.Lock()
+	if len(c.animations) == 0 {
+		c.lock.Unlock()
+		return 0
+	}
+	now := time.Now()
+	if wait := tick - now.Sub(c.lastAnimation); wait > 0 {
+		c.lock.Unlock()
+		return wait
+	}
+	c.lastAnimation = now
+	ahs := c.animations
+	c.lock.Unlock()
+
+	finished := map[*animationHolder]bool{}
+	for _, h := range ahs {
+		if !h.af(now) {
+			finished[h] = true
+		}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(finished) > 0 {
+		c.removeAnimations(finished)
+	}
+	if len(c.animations) == 0 {
+		return 0
+	}
+	return tick
+}
+
+func (c *controller) addAnimation(af AnimationFunc) func() {
+	h := &animationHolder{af: af}
+	c.lock.Lock()
+	c.animations = append(c.animations[:len(c.animations):len(c.animations)], h)
+	c.lock.Unlock()
+	c.screen().PostEvent(&frameEvent{}) // the loop may sleep, wake it up
+	return func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.removeAnimations(map[*animationHolder]bool{h: true})
+	}
+}
+
+// removeAnimations removes the animations from the list, c.lock must be held
+func (c *controller) removeAnimations(ahs map[*animationHolder]bool) {
+	res := make([]*animationHolder, 0, len(c.animations))
+	for _, h := range c.animations {
+		if !ahs[h] {
+			res = append(res, h)
+		}
+	}
+	c.animations = res
+}
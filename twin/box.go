@@ -5,13 +5,15 @@ import (
 	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
 	"github.com/gdamore/tcell/v2"
 	"reflect"
+	"sync"
 	"sync/atomic"
 )
 
 type Box struct {
-	visible atomic.Bool
-	active  atomic.Bool
-	bounds  atomic.Value
+	visible  atomic.Bool
+	disabled atomic.Bool
+	active   atomic.Bool
+	bounds   atomic.Value
 	// owner contains a reference to the owner of the component
 	owner Component
 	// this is the reference to the BaseComponent holder. This is because
@@ -21,6 +23,22 @@ type Box struct {
 	tpName atomic.Value
 	closed atomic.Bool
 	chldrn atomic.Value
+	// tabOrder contains the custom focus traversal order, see SetTabOrder()
+	tabOrder   atomic.Value
+	focusScope atomic.Bool
+	// the style selection, see StyleOf()
+	styleType    atomic.Value
+	styleClasses atomic.Value
+	styleLock    sync.Mutex
+	stateStyles  map[StyleState]tcell.Style
+	// cached is true if the component rendering is cached in cache, see SetCached()
+	cached atomic.Bool
+	cache  atomic.Pointer[CellBuffer]
+	// opaque is true if the component fills all its bounds, see SetOpaque()
+	opaque atomic.Bool
+	// zIndex and zSeq define the order the component is drawn among its siblings, see SetZIndex()
+	zIndex atomic.Int32
+	zSeq   atomic.Int64
 }
 
 // Init initializes Box. owner should be non-nil the owner of the Component,
@@ -50,7 +68,7 @@ func (b *Box) Init(owner Component, this Component) error {
 	if err != nil {
 		b.this = nil
 	} else {
-		b.owner = owner
+		c.notify(this, ComponentEvent{Type: ComponentAdded})
 	}
 	return err
 }
@@ -82,10 +100,75 @@ func (b *Box) IsVisible() bool {
 func (b *Box) SetVisible(visible bool) {
 	before := b.visible.Swap(visible)
 	if before != visible {
+		c.notify(b.this, ComponentEvent{Type: ComponentVisibilityChanged, Visible: visible})
 		c.reDrawNeeded(b.this, &tcell.EventTime{})
 	}
 }
 
+// IsEnabled returns whether the component is enabled or not
+func (b *Box) IsEnabled() bool {
+	return !b.disabled.Load()
+}
+
+// SetEnabled allows to enable or disable the component. The disabled component is drawn with
+// the disabled style and cannot be focused. If the component or one of its descendants is
+// focused, the focus is moved to the next component.
+func (b *Box) SetEnabled(enabled bool) {
+	before := !b.disabled.Swap(!enabled)
+	if before != enabled {
+		c.notify(b.this, ComponentEvent{Type: ComponentEnabledChanged, Enabled: enabled})
+		c.reDrawNeeded(b.this, &tcell.EventTime{})
+	}
+}
+
+// SetCached turns on or off caching of the component rendering. The cached component and its
+// children are drawn off-screen once, and the result is reused until the component or one of its
+// descendants is redrawn. It is useful for the expensive static components. The cached
+// rendering is opaque, so the component should fill all its bounds.
+func (b *Box) SetCached(cached bool) {
+	b.cached.Store(cached)
+	b.cache.Store(nil)
+	c.reDrawNeeded(b.this, &tcell.EventTime{})
+}
+
+// SetOpaque tells whether the component fills all its bounds when it is drawn. The siblings,
+// which are fully covered by the opaque component, are not drawn.
+func (b *Box) SetOpaque(opaque bool) {
+	b.opaque.Store(opaque)
+	c.reDrawNeeded(b.this, &tcell.EventTime{})
+}
+
+// IsOpaque returns whether the component fills all its bounds, see SetOpaque()
+func (b *Box) IsOpaque() bool {
+	return b.opaque.Load()
+}
+
+// SetZIndex assigns the layer of the component among its siblings. The components with the
+// bigger z-index are drawn over the ones with the smaller one and receive the mouse events first.
+// The siblings with the same z-index are drawn in the order they were added to the owner.
+func (b *Box) SetZIndex(z int) {
+	if b.zIndex.Swap(int32(z)) != int32(z) {
+		c.reDrawNeeded(b.this, &tcell.EventTime{})
+	}
+}
+
+// ZIndex returns the component z-index, see SetZIndex()
+func (b *Box) ZIndex() int {
+	return int(b.zIndex.Load())
+}
+
+// BringToFront moves the component over all its siblings with the same z-index
+func (b *Box) BringToFront() {
+	b.zSeq.Store(c.zSeq.Add(1))
+	c.reDrawNeeded(b.this, &tcell.EventTime{})
+}
+
+// SendToBack moves the component under all its siblings with the same z-index
+func (b *Box) SendToBack() {
+	b.zSeq.Store(-c.zSeq.Add(1))
+	c.reDrawNeeded(b.this, &tcell.EventTime{})
+}
+
 // children returns list of owned components
 func (b *Box) children() []Component {
 	return b.chldrn.Load().([]Component)
@@ -107,16 +190,26 @@ func (b *Box) CanBeFocused() bool { return false }
 func (b *Box) OnDraw(cc *CanvasContext) {
 }
 
+// OnKeyPressed moves the focus: Tab and Backtab traverse the whole focus scope, Down and Up move
+// it between the children and return false at their ends, so the owner may handle the key
 func (b *Box) OnKeyPressed(ke *tcell.EventKey) bool {
-	if ke.Key() == tcell.KeyTab || ke.Key() == tcell.KeyDown {
-		return b.nextActive()
-	}
-	if ke.Key() == tcell.KeyBacktab || ke.Key() == tcell.KeyUp {
-		return b.prevActive()
+	switch ke.Key() {
+	case tcell.KeyTab:
+		return c.focusNext(1)
+	case tcell.KeyBacktab:
+		return c.focusNext(-1)
+	case tcell.KeyDown:
+		return c.focusSibling(b.this, 1)
+	case tcell.KeyUp:
+		return c.focusSibling(b.this, -1)
 	}
 	return false
 }
 
+func (b *Box) OnPaste(text string) bool { return false }
+
+func (b *Box) OnLinkActivated(url string) bool { return false }
+
 func (b *Box) OnFocus(focused bool) {}
 
 func (b *Box) OnOwnerResized() {}
@@ -125,6 +218,8 @@ func (b *Box) OnMousePressed(p Point) bool { return false }
 
 func (b *Box) OnMouseWheel(p Point, wheel MouseWheel) bool { return false }
 
+func (b *Box) OnMouseDrag(p Point, state MouseDragState) bool { return false }
+
 func (b *Box) OnClosed() {}
 
 func (b *Box) OnChildClosed(child Component) {}
@@ -152,7 +247,13 @@ func (b *Box) isActive() bool {
 // SetBounds allows to assign the component position and dimensions by the `r`
 // This call is always trigger re-drawing
 func (b *Box) SetBounds(r Rectangle) {
-	b.bounds.Store(r.Mend())
+	r = r.Mend()
+	// the area, where the component was, must be redrawn by the components under it
+	pb := c.physicalBounds(b.this)
+	if old := b.bounds.Swap(r); old != r {
+		c.addDamage(pb, &tcell.EventTime{})
+		c.notify(b.this, ComponentEvent{Type: ComponentBoundsChanged, Bounds: r})
+	}
 	c.resize(b.this)
 }
 
@@ -182,6 +283,7 @@ func (b *Box) addChild(comp Component) error {
 	}
 	nv = append(nv, comp)
 	b.chldrn.Store(nv)
+	cb.owner = b.this
 	return nil
 }
 
@@ -190,7 +292,7 @@ func (b *Box) updateChildren(children []Component) {
 }
 
 func (b *Box) setActive(active bool) {
-	if !b.IsVisible() {
+	if active && !b.IsVisible() {
 		panic("box is not visible")
 	}
 	if b.active.Load() == active {
@@ -198,6 +300,7 @@ func (b *Box) setActive(active bool) {
 	}
 	b.active.Store(active)
 	b.this.OnFocus(active)
+	c.notify(b.this, ComponentEvent{Type: ComponentFocused, Focused: active})
 	c.reDrawNeeded(b.this, &tcell.EventTime{})
 }
 
@@ -217,11 +320,14 @@ func (b *Box) removeClosedChildren() {
 }
 
 func (b *Box) closeActually() {
+	c.notify(b.this, ComponentEvent{Type: ComponentClosed})
 	b.this.OnClosed()
 	if b.owner != nil {
 		b.owner.OnChildClosed(b.this)
 	}
+	c.lock.Lock()
 	b.owner = nil
+	c.lock.Unlock()
 	b.this = nil
 }
 
@@ -244,41 +350,6 @@ func (b *Box) getActiveChild() (int, Component) {
 	return -1, nil
 }
 
-func (b *Box) nextActive() bool {
-	i, comp := b.getActiveChild()
-	i++
-	setActiveFalse(comp)
-	children := b.children()
-	for i < len(children) {
-		child := children[i]
-		if child.IsVisible() && child.CanBeFocused() {
-			child.box().setActive(true)
-			return true
-		}
-		i++
-	}
-	return false
-}
-
-func (b *Box) prevActive() bool {
-	i, comp := b.getActiveChild()
-	setActiveFalse(comp)
-	children := b.children()
-	if i < 0 {
-		i = len(children)
-	}
-	i--
-	for i >= 0 && i < len(children) {
-		child := children[i]
-		if child.IsVisible() && child.CanBeFocused() {
-			child.box().setActive(true)
-			return true
-		}
-		i--
-	}
-	return false
-}
-
 func setActiveFalse(comp Component) {
 	if comp == nil {
 		return
@@ -0,0 +1,224 @@
+package twin
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"strings"
+)
+
+// Cell is the content of one screen cell
+type Cell struct {
+	Main  rune
+	Comb  []rune
+	Style tcell.Style
+	// Width is 2 for the wide chars, and 0 for the cell covered by the wide char on the left
+	Width int
+	// Link is the hyperlink URL of the cell, if any
+	Link string
+}
+
+// CellBuffer is the in-memory screen, the components may be drawn to. It allows to render the
+// components off-screen and to cache, to compose or to export the result.
+type CellBuffer struct {
+	size  Size
+	cells []Cell
+}
+
+// cellTarget is where the CanvasContext puts the cells to, tcell.Screen or CellBuffer
+type cellTarget interface {
+	SetContent(x, y int, mainc rune, combc []rune, style tcell.Style)
+	GetContent(x, y int) (rune, []rune, tcell.Style, int)
+}
+
+// NewCellBuffer creates the buffer of the size sz filled by the spaces with the default style
+func NewCellBuffer(sz Size) *CellBuffer {
+	sz.Width, sz.Height = max(0, sz.Width), max(0, sz.Height)
+	cb := &CellBuffer{size: sz, cells: make([]Cell, sz.Width*sz.Height)}
+	cb.Clear(tcell.StyleDefault)
+	return cb
+}
+
+// NewBufferCanvas returns the CanvasContext, which draws to the buffer cb
+func NewBufferCanvas(cb *CellBuffer) *CanvasContext {
+	cc := newCanvas(cb.size)
+	cc.t, cc.screen = cb, false
+	return cc
+}
+
+// Snapshot renders comp with all its children to the new buffer of the comp size. The components
+// are drawn in the calling go-routine, so call it from the components notifications, where the
+// components are not drawn concurrently.
+func Snapshot(comp Component) *CellBuffer {
+	b := comp.Bounds()
+	cb := NewCellBuffer(b.Size())
+	cc := NewBufferCanvas(cb)
+	cc.pushRelativeRegion(b.TopLeft(), Rectangle{Width: b.Width, Height: b.Height})
+	c.drawSubtree(cc, comp)
+	cc.pop()
+	return cb
+}
+
+// Size returns the buffer size
+func (cb *CellBuffer) Size() Size {
+	return cb.size
+}
+
+// Clear fills the buffer by the spaces with the style
+func (cb *CellBuffer) Clear(style tcell.Style) {
+	for i := range cb.cells {
+		cb.cells[i] = Cell{Main: ' ', Style: style, Width: 1}
+	}
+}
+
+// Cell returns the cell at x, y or the empty cell if the point is out of the buffer
+func (cb *CellBuffer) Cell(x, y int) Cell {
+	if x < 0 || y < 0 || x >= cb.size.Width || y >= cb.size.Height {
+		return Cell{}
+	}
+	return cb.cells[y*cb.size.Width+x]
+}
+
+// SetContent sets the cell content, the same way as tcell.Screen does
+func (cb *CellBuffer) SetContent(x, y int, mainc rune, combc []rune, style tcell.Style) {
+	if x < 0 || y < 0 || x >= cb.size.Width || y >= cb.size.Height {
+		return
+	}
+	w := runeWidth(mainc)
+	i := y*cb.size.Width + x
+	if old := cb.cells[i]; old.Width == 2 && x+1 < cb.size.Width {
+		cb.cells[i+1] = Cell{Main: ' ', Style: old.Style, Width: 1}
+	}
+	cb.cells[i] = Cell{Main: mainc, Comb: append([]rune(nil), combc...), Style: style, Width: w}
+	if w == 2 && x+1 < cb.size.Width {
+		cb.cells[i+1] = Cell{Style: style}
+	}
+}
+
+// setLink sets the hyperlink of the cell, it is called after SetContent, which resets the link
+func (cb *CellBuffer) setLink(x, y int, link string) {
+	if x < 0 || y < 0 || x >= cb.size.Width || y >= cb.size.Height {
+		return
+	}
+	cb.cells[y*cb.size.Width+x].Link = link
+}
+
+// GetContent returns the cell content, the same way as tcell.Screen does
+func (cb *CellBuffer) GetContent(x, y int) (rune, []rune, tcell.Style, int) {
+	cl := cb.Cell(x, y)
+	return cl.Main, cl.Comb, cl.Style, cl.Width
+}
+
+// String returns the buffer text, the lines are separated by '\n'
+func (cb *CellBuffer) String() string {
+	var sb strings.Builder
+	for y := 0; y < cb.size.Height; y++ {
+		if y > 0 {
+			sb.WriteByte('\n')
+		}
+		for x := 0; x < cb.size.Width; x++ {
+			cl := cb.cells[y*cb.size.Width+x]
+			if cl.Width == 0 {
+				continue
+			}
+			sb.WriteRune(cl.Main)
+			for _, r := range cl.Comb {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// ANSI returns the buffer text with the ANSI escape sequences for the styles and the OSC 8
+// hyperlinks, so it can be printed to a terminal
+func (cb *CellBuffer) ANSI() string {
+	var sb strings.Builder
+	for y := 0; y < cb.size.Height; y++ {
+		last, link := "", ""
+		for x := 0; x < cb.size.Width; x++ {
+			cl := cb.cells[y*cb.size.Width+x]
+			if cl.Width == 0 {
+				continue
+			}
+			if cl.Link != link {
+				sb.WriteString(osc8(cl.Link))
+				link = cl.Link
+			}
+			if sgr := styleSGR(cl.Style); sgr != last {
+				sb.WriteString(sgr)
+				last = sgr
+			}
+			sb.WriteRune(cl.Main)
+			for _, r := range cl.Comb {
+				sb.WriteRune(r)
+			}
+		}
+		if link != "" {
+			sb.WriteString(osc8(""))
+		}
+		sb.WriteString("\x1b[0m\n")
+	}
+	return sb.String()
+}
+
+// osc8 returns the OSC 8 sequence, which starts the hyperlink to the url, or ends it if the url is empty
+func osc8(url string) string {
+	return "\x1b]8;;" + url + "\x1b\\"
+}
+
+// DrawBuffer puts the buffer cb content to the canvas at the point p
+func (cc *CanvasContext) DrawBuffer(p Point, cb *CellBuffer) {
+	pr := cc.physicalRegion()
+	tl := cc.physicalPointXY(p)
+	for y := 0; y < cb.size.Height; y++ {
+		for x := 0; x < cb.size.Width; x++ {
+			cl := cb.cells[y*cb.size.Width+x]
+			pp := Point{X: tl.X + x, Y: tl.Y + y}
+			if cl.Width == 0 || !pr.Contains(pp) {
+				continue
+			}
+			if cl.Width == 2 && !pr.Contains(Point{X: pp.X + 1, Y: pp.Y}) {
+				// the wide char is cut by the region
+				cc.setContent(pp, ' ', nil, cl.Style)
+				continue
+			}
+			cc.link = cl.Link
+			cc.setContent(pp, cl.Main, cl.Comb, cl.Style)
+			cc.link = ""
+		}
+	}
+}
+
+// styleSGR returns the ANSI SGR sequence for the style
+func styleSGR(style tcell.Style) string {
+	fg, bg, attrs := style.Decompose()
+	params := []string{"0"}
+	for _, a := range []struct {
+		attr tcell.AttrMask
+		code string
+	}{
+		{tcell.AttrBold, "1"}, {tcell.AttrDim, "2"}, {tcell.AttrItalic, "3"}, {tcell.AttrUnderline, "4"},
+		{tcell.AttrBlink, "5"}, {tcell.AttrReverse, "7"}, {tcell.AttrStrikeThrough, "9"},
+	} {
+		if attrs&a.attr != 0 {
+			params = append(params, a.code)
+		}
+	}
+	for _, p := range []string{colorSGR(fg, 38), colorSGR(bg, 48)} {
+		if p != "" {
+			params = append(params, p)
+		}
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+func colorSGR(clr tcell.Color, base int) string {
+	switch {
+	case !clr.Valid():
+		return ""
+	case clr&tcell.ColorIsRGB != 0:
+		r, g, b := clr.RGB()
+		return fmt.Sprintf("%d;2;%d;%d;%d", base, r, g, b)
+	}
+	return fmt.Sprintf("%d;5;%d", base, clr-tcell.ColorValid)
+}
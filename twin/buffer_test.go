@@ -0,0 +1,137 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type testBox struct {
+	Box
+	text  string
+	style tcell.Style
+	draws int
+	// resizes counts the OnOwnerResized calls
+	resizes int
+}
+
+func (tb *testBox) OnOwnerResized() {
+	tb.resizes++
+}
+
+func (tb *testBox) OnDraw(cc *CanvasContext) {
+	tb.draws++
+	cc.FilledRectangle(tb.Bounds().Normalized(), tb.style)
+	cc.Print(Point{}, tb.text, tb.style)
+}
+
+func newTestBox(t testing.TB, owner Component, text string, r Rectangle) *testBox {
+	tb := &testBox{text: text, style: tcell.StyleDefault}
+	assert.Nil(t, tb.Init(owner, tb))
+	tb.SetBounds(r)
+	t.Cleanup(func() {
+		Close(tb)
+		c.onLoop()
+	})
+	return tb
+}
+
+func TestSnapshot(t *testing.T) {
+	p := newTestBox(t, Root(), "parent", Rectangle{X: 5, Y: 3, Width: 10, Height: 3})
+	newTestBox(t, p, "日本語", Rectangle{X: 2, Y: 1, Width: 5, Height: 2})
+	newTestBox(t, p, "é", Rectangle{X: 9, Y: 2, Width: 3, Height: 1})
+
+	cb := Snapshot(p)
+	assert.Equal(t, Size{Width: 10, Height: 3}, cb.Size())
+	assert.Equal(t, strings.Join([]string{
+		"parent    ",
+		"  日本    ",
+		"         é",
+	}, "\n"), cb.String())
+	assert.Equal(t, 0, cb.Cell(3, 1).Width)
+	assert.Equal(t, Cell{}, cb.Cell(10, 0))
+}
+
+func TestCellBuffer_ANSI(t *testing.T) {
+	cb := NewCellBuffer(Size{Width: 4, Height: 2})
+	cc := NewBufferCanvas(cb)
+	cc.Print(Point{X: 1, Y: 0}, "ab", tcell.StyleDefault.Foreground(tcell.ColorRed).Bold(true))
+	cc.Print(Point{X: 0, Y: 1}, "x", tcell.StyleDefault.Background(tcell.NewRGBColor(1, 2, 3)))
+	assert.Equal(t, "\x1b[0m \x1b[0;1;38;5;9mab\x1b[0m \x1b[0m\n"+
+		"\x1b[0;48;2;1;2;3mx\x1b[0m   \x1b[0m\n", cb.ANSI())
+}
+
+func TestCellBuffer_Link(t *testing.T) {
+	cb := NewCellBuffer(Size{Width: 3, Height: 1})
+	cc := NewBufferCanvas(cb)
+	cc.PrintLink(Point{X: 1}, "ab", "http://a", tcell.StyleDefault)
+	assert.Equal(t, "http://a", cb.Cell(2, 0).Link)
+	assert.Equal(t, "", cb.Cell(0, 0).Link)
+	assert.Equal(t, "\x1b[0m \x1b]8;;http://a\x1b\\ab\x1b]8;;\x1b\\\x1b[0m\n", cb.ANSI())
+
+	// the cached rendering keeps the links
+	dst := NewCellBuffer(Size{Width: 3, Height: 1})
+	NewBufferCanvas(dst).DrawBuffer(Point{}, cb)
+	assert.Equal(t, "http://a", dst.Cell(1, 0).Link)
+	cc.Print(Point{X: 1}, "c", tcell.StyleDefault)
+	assert.Equal(t, "", cb.Cell(1, 0).Link)
+}
+
+func TestCellBuffer_SetContent(t *testing.T) {
+	cb := NewCellBuffer(Size{Width: 3, Height: 1})
+	cb.SetContent(0, 0, '日', nil, tcell.StyleDefault)
+	assert.Equal(t, "日 ", cb.String())
+	// overwriting the wide char frees the cell on its right
+	cb.SetContent(0, 0, 'a', nil, tcell.StyleDefault)
+	assert.Equal(t, "a  ", cb.String())
+	cb.SetContent(-1, 0, 'b', nil, tcell.StyleDefault)
+	cb.SetContent(3, 0, 'b', nil, tcell.StyleDefault)
+	assert.Equal(t, "a  ", cb.String())
+}
+
+func TestDrawBuffer(t *testing.T) {
+	src := NewCellBuffer(Size{Width: 4, Height: 1})
+	NewBufferCanvas(src).Print(Point{}, "a日b", tcell.StyleDefault)
+	dst := NewCellBuffer(Size{Width: 6, Height: 2})
+	cc := NewBufferCanvas(dst)
+	cc.DrawBuffer(Point{X: 1, Y: 1}, src)
+	// the wide char is cut by the region
+	cc.pushRelativeRegion(Point{}, Rectangle{X: 0, Y: 0, Width: 2, Height: 1})
+	cc.DrawBuffer(Point{X: 0, Y: 0}, src)
+	cc.pop()
+	assert.Equal(t, "a     \n a日b ", dst.String())
+}
+
+func TestCachedRendering(t *testing.T) {
+	c.onScreenResize()
+	p := newTestBox(t, Root(), "parent", Rectangle{X: 0, Y: 0, Width: 10, Height: 3})
+	chld := newTestBox(t, p, "child", Rectangle{X: 1, Y: 1, Width: 5, Height: 1})
+	p.SetCached(true)
+
+	draw := func() string {
+		cb := NewCellBuffer(Size{Width: 10, Height: 3})
+		c.draw(NewBufferCanvas(cb), c.root)
+		return cb.String()
+	}
+	exp := "parent    \n child    \n          "
+	assert.Equal(t, exp, draw())
+	assert.Equal(t, 1, p.draws)
+	assert.Equal(t, 1, chld.draws)
+
+	// the cached rendering is used
+	assert.Equal(t, exp, draw())
+	assert.Equal(t, 1, p.draws)
+	assert.Equal(t, 1, chld.draws)
+
+	// the child redrawing invalidates the cache
+	chld.text = "kid"
+	Redraw(chld)
+	assert.Equal(t, "parent    \n kid      \n          ", draw())
+	assert.Equal(t, 2, p.draws)
+	assert.Equal(t, 2, chld.draws)
+
+	p.SetCached(false)
+	draw()
+	assert.Equal(t, 3, p.draws)
+}
@@ -3,12 +3,20 @@ package twin
 import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
 	"strings"
 )
 
 // CanvasContext struct allows to track the stack of regions, so that one includes another.
 type CanvasContext struct {
 	stack []ctxStackElem
+	// t is where the cells are put to, the screen or a CellBuffer, see target()
+	t cellTarget
+	// screen is true if the colors must be adapted to the terminal
+	screen bool
+	// comp is the component being drawn, and link is the URL of the cells being printed
+	comp Component
+	link string
 }
 
 type CanvasRectangleStyle int
@@ -37,27 +45,7 @@ func (cc *CanvasContext) isVisible(r Rectangle) bool {
 // Print prints the text using the style provided. x and y are coordinates of the text
 // on the owner's rectangle
 func (cc *CanvasContext) Print(p Point, str string, style tcell.Style) {
-	pp := cc.physicalPointXY(p)
-	pr := cc.physicalRegion()
-	prBR := pr.BottomRight()
-	if pp.Y < pr.Y || prBR.Y < pp.Y {
-		return // is not visible vertically
-	}
-	for _, chr := range str {
-		w := runewidth.RuneWidth(chr)
-		if w == 0 {
-			chr = ' '
-			w = 1
-		}
-		if pp.X > prBR.X || prBR.X < pp.X+w-1 {
-			return // moving right of the rightest border
-		}
-		if pp.X >= pr.X {
-			// only put the chr on the screen if it's completely visible on the rectangle (pr)
-			c.s.SetContent(int(pp.X), int(pp.Y), chr, nil, style)
-		}
-		pp.X += w
-	}
+	cc.print(p, str, -1, style)
 }
 
 // PrintL within limits (no longer than lim)
@@ -65,37 +53,169 @@ func (cc *CanvasContext) PrintL(p Point, str string, lim int, style tcell.Style)
 	if lim <= 0 {
 		return
 	}
+	cc.print(p, str, lim, style)
+}
+
+// PrintText prints the styled text t, the spans styles are applied to the base style. The spans
+// with the links are printed as the OSC 8 hyperlinks, and may be activated by the mouse click
+// or Enter (see Component.OnLinkActivated)
+func (cc *CanvasContext) PrintText(p Point, t Text, base tcell.Style) {
+	for _, s := range t {
+		p.X, _ = cc.printSpan(p, s, -1, base)
+	}
+}
+
+// PrintTextL prints the styled text t within limits (no longer than lim)
+func (cc *CanvasContext) PrintTextL(p Point, t Text, lim int, base tcell.Style) {
+	for _, s := range t {
+		if lim <= 0 {
+			return
+		}
+		p.X, lim = cc.printSpan(p, s, lim, base)
+	}
+}
+
+// PrintLink prints the str as the hyperlink to the url (see PrintText)
+func (cc *CanvasContext) PrintLink(p Point, str, url string, style tcell.Style) {
+	cc.printSpan(p, Span{Text: str, Link: url}, -1, style)
+}
+
+func (cc *CanvasContext) printSpan(p Point, s Span, lim int, base tcell.Style) (int, int) {
+	style := s.Style.Apply(base)
+	if s.Link == "" {
+		return cc.print(p, s.Text, lim, style)
+	}
+	cc.link = s.Link
+	defer func() { cc.link = "" }()
+	return cc.print(p, s.Text, lim, style.Url(s.Link))
+}
+
+// print prints the str at p. If lim is not negative, no more than lim grapheme clusters are put
+// on the screen. It returns the X coordinate next to the last cluster and the rest of lim.
+// The wide clusters, which are cut by the region borders, are shown as spaces.
+func (cc *CanvasContext) print(p Point, str string, lim int, style tcell.Style) (int, int) {
 	pp := cc.physicalPointXY(p)
 	pr := cc.physicalRegion()
 	prBR := pr.BottomRight()
 	if pp.Y < pr.Y || prBR.Y < pp.Y {
-		return // is not visible vertically
+		return p.X + StringWidth(str), lim // is not visible vertically
 	}
-	for _, chr := range str {
-		w := runewidth.RuneWidth(chr)
-		if w == 0 {
-			chr = ' '
-			w = 1
+	state := -1
+	for str != "" {
+		var cl string
+		cl, str, _, state = uniseg.FirstGraphemeClusterInString(str, state)
+		mainc, combc := clusterRunes(cl)
+		w := runeWidth(mainc)
+		if pp.X > prBR.X {
+			return p.X + w, lim // moving right of the rightest border
 		}
-		if pp.X > prBR.X || prBR.X < pp.X+w-1 {
-			return // moving right of the rightest border
+		if lim == 0 {
+			return p.X, lim
 		}
-		if pp.X >= pr.X {
-			if lim <= 0 {
-				return
+		if pp.X+w-1 >= pr.X {
+			if lim > 0 {
+				lim--
+			}
+			if pp.X < pr.X || prBR.X < pp.X+w-1 {
+				// the cluster is cut by the region, so only its visible cells are filled
+				for x := max(pp.X, pr.X); x <= min(pp.X+w-1, prBR.X); x++ {
+					cc.setContent(Point{X: x, Y: pp.Y}, ' ', nil, style)
+				}
+			} else {
+				cc.setContent(pp, mainc, combc, style)
 			}
-			lim--
-			// only put the chr on the screen if it's completely visible on the rectangle (pr)
-			c.s.SetContent(int(pp.X), int(pp.Y), chr, nil, style)
 		}
 		pp.X += w
+		p.X += w
+	}
+	return p.X, lim
+}
+
+// runeWidth returns the width of the cluster with the main rune r. tcell defines the cell width by
+// the main rune, so the same width is used to keep the screen cells consistent.
+func runeWidth(r rune) int {
+	return max(1, runewidth.RuneWidth(r))
+}
+
+// clusterRunes returns the main and the combining runes of the grapheme cluster cl. The control
+// chars are replaced by the space, and the combining marks without the base char are put on the space.
+func clusterRunes(cl string) (rune, []rune) {
+	runes := []rune(cl)
+	switch {
+	case runes[0] < ' ' || runes[0] == 0x7f:
+		return ' ', nil
+	case uniseg.StringWidth(string(runes[0])) == 0:
+		return ' ', runes
+	case len(runes) == 1:
+		return runes[0], nil
 	}
+	return runes[0], runes[1:]
 }
 
+// GradientRectangle fills the rectangle r with the background color changing from the color from
+// to the color to, from left to right, or from top to bottom if vertical is true
+func (cc *CanvasContext) GradientRectangle(r Rectangle, from, to tcell.Color, vertical bool) {
+	n := r.Width
+	if vertical {
+		n = r.Height
+	}
+	for i, clr := range Gradient(from, to, n) {
+		style := tcell.StyleDefault.Background(clr)
+		if vertical {
+			cc.Print(Point{X: r.X, Y: r.Y + i}, strings.Repeat(" ", r.Width), style)
+			continue
+		}
+		for y := 0; y < r.Height; y++ {
+			cc.Print(Point{X: r.X + i, Y: r.Y + y}, " ", style)
+		}
+	}
+}
+
+// BlendRectangle mixes the colors of the cells, which are already drawn in the rectangle r,
+// with clr (see Blend). It allows to dim or to tint the area, for example behind a modal component.
+func (cc *CanvasContext) BlendRectangle(r Rectangle, clr tcell.Color, alpha float64) {
+	pr := cc.physicalRegion()
+	tl := cc.physicalPointXY(r.TopLeft())
+	x1, y1 := max(tl.X, pr.X), max(tl.Y, pr.Y)
+	x2, y2 := min(tl.X+r.Width, pr.X+pr.Width), min(tl.Y+r.Height, pr.Y+pr.Height)
+	for y := y1; y < y2; y++ {
+		for x := x1; x < x2; x++ {
+			mainc, combc, style, _ := cc.target().GetContent(x, y)
+			cc.setContent(Point{X: x, Y: y}, mainc, combc, BlendStyle(style, clr, alpha))
+		}
+	}
+}
+
+// setContent puts the chr with the combining runes to the physical point p, the style colors are
+// adapted to the terminal, if the canvas draws to the screen
+func (cc *CanvasContext) setContent(p Point, chr rune, combc []rune, style tcell.Style) {
+	if cc.screen {
+		style = AdaptStyle(style)
+		c.setLink(p, cc.comp, cc.link)
+	}
+	cc.target().SetContent(p.X, p.Y, chr, combc, style)
+	if cb, ok := cc.t.(*CellBuffer); ok && cc.link != "" {
+		cb.setLink(p.X, p.Y, cc.link)
+	}
+}
+
+// target returns where the cells are put to. The screen canvas gets the screen, when it draws
+// first, so the canvases made for the coordinates only don't create the screen.
+func (cc *CanvasContext) target() cellTarget {
+	if cc.t == nil {
+		cc.t = c.screen()
+	}
+	return cc.t
+}
+
+// FilledRectangle fills the rectangle r with the spaces of the style. Only the cells within the
+// canvas region are touched.
 func (cc *CanvasContext) FilledRectangle(r Rectangle, style tcell.Style) {
-	str := strings.Repeat(" ", int(r.Width))
-	for i := 0; i < r.Height; i++ {
-		cc.Print(Point{X: r.X, Y: r.Y + i}, str, style)
+	r = r.Move(cc.physicalPointXY(r.TopLeft())).Intersection(cc.physicalRegion())
+	for y := r.Y; y < r.Y+r.Height; y++ {
+		for x := r.X; x < r.X+r.Width; x++ {
+			cc.setContent(Point{X: x, Y: y}, ' ', nil, style)
+		}
 	}
 }
 
@@ -167,90 +287,135 @@ func (cc *CanvasContext) HLine(pos Point, length int, style tcell.Style) {
 	cc.Print(pos, str, style)
 }
 
-// DrawVScrollBar draws the vertical scroll bar at the position pos:
-// sbSize - the size of scroll bar in cells
-// virtSize - the size of the field
-// wSize - the visible window size (wSize <= virtSize)
-// vOffset - the virtual offset >= 0
-func (cc *CanvasContext) DrawVScrollBar(pos Point, sbSize, virtSize, wSize, vOffset int, style tcell.Style) {
-	maxOffset := max(0, virtSize-wSize)
-	vOffset = max(0, min(vOffset, maxOffset))
-
-	bar := " "
-	bg := "░"
-	switch {
-	case sbSize == 1:
-		cc.Print(pos, bar, style)
-		return
+// ScrollBarPart is the part of a scroll bar
+type ScrollBarPart int
 
-	case sbSize == 2:
-		cc.Print(pos, bar, style)
-		pos.Y++
-		cc.Print(pos, bar, style)
-		return
+const (
+	ScrollBarNone = ScrollBarPart(iota)
+	// ScrollBarBackArrow is the up (left) arrow
+	ScrollBarBackArrow
+	// ScrollBarBackTrack is the track before the thumb
+	ScrollBarBackTrack
+	ScrollBarThumb
+	// ScrollBarForwardTrack is the track after the thumb
+	ScrollBarForwardTrack
+	// ScrollBarForwardArrow is the down (right) arrow
+	ScrollBarForwardArrow
+)
 
-	default:
-		cc.Print(pos, "▲", style)
-		cc.Print(Point{X: pos.X, Y: pos.Y + sbSize - 1}, "▼", style)
-		pos.Y++
+// ScrollBar is the geometry of a scroll bar. It is used for drawing the bar as well as for
+// handling the mouse, so the both always agree on where the arrows and the thumb are.
+type ScrollBar struct {
+	// Size is the size of the scroll bar in cells
+	Size int
+	// MaxOffset is the maximum virtual offset
+	MaxOffset int
+	// Offset is the virtual offset, 0 <= Offset <= MaxOffset
+	Offset int
+	// TrackLen is the length of the track between the arrows
+	TrackLen int
+	// ThumbLen is the length of the thumb
+	ThumbLen int
+	// ThumbPos is the position of the thumb in the track
+	ThumbPos int
+}
 
-		trackLen := sbSize - 2
+// NewScrollBar calculates the scroll bar geometry:
+// sbSize - the size of scroll bar in cells
+// virtSize - the size of the field
+// wSize - the visible window size (wSize <= virtSize)
+// offset - the virtual offset >= 0
+func NewScrollBar(sbSize, virtSize, wSize, offset int) ScrollBar {
+	sb := ScrollBar{Size: max(0, sbSize), MaxOffset: max(0, virtSize-wSize)}
+	sb.Offset = max(0, min(offset, sb.MaxOffset))
+	if sb.Size < 3 {
+		// no room for the arrows, the whole bar is the thumb
+		sb.TrackLen = sb.Size
+		sb.ThumbLen = sb.Size
+		return sb
+	}
+	sb.TrackLen = sb.Size - 2
+	sb.ThumbLen = sb.TrackLen
+	if virtSize > 0 {
+		sb.ThumbLen = min(sb.TrackLen, max(1, wSize*sb.TrackLen/virtSize))
+	}
+	if sb.MaxOffset > 0 {
+		sb.ThumbPos = sb.Offset * (sb.TrackLen - sb.ThumbLen) / sb.MaxOffset
+	}
+	return sb
+}
 
-		thumbLen := min(trackLen, max(1, wSize*trackLen/virtSize))
-		offs := 0
-		if maxOffset > 0 {
-			offs = vOffset * (trackLen - thumbLen) / maxOffset
-		}
+// HasArrows returns whether the scroll bar is big enough for the arrows
+func (sb ScrollBar) HasArrows() bool {
+	return sb.Size >= 3
+}
 
-		for i := 0; i < offs; i++ {
-			cc.Print(pos, bg, style)
-			pos.Y++
-		}
-		fg, _, _ := style.Decompose()
-		for i := 0; i < thumbLen; i++ {
-			cc.Print(pos, bar, style.Background(fg))
-			pos.Y++
-		}
-		for i := 0; i < trackLen-offs-thumbLen; i++ {
-			cc.Print(pos, bg, style)
-			pos.Y++
-		}
+// Part returns the part of the scroll bar at the cell i (0 is the first cell of the bar)
+func (sb ScrollBar) Part(i int) ScrollBarPart {
+	if i < 0 || i >= sb.Size {
+		return ScrollBarNone
+	}
+	if !sb.HasArrows() {
+		return ScrollBarThumb
 	}
+	switch {
+	case i == 0:
+		return ScrollBarBackArrow
+	case i == sb.Size-1:
+		return ScrollBarForwardArrow
+	case i-1 < sb.ThumbPos:
+		return ScrollBarBackTrack
+	case i-1 < sb.ThumbPos+sb.ThumbLen:
+		return ScrollBarThumb
+	}
+	return ScrollBarForwardTrack
 }
 
-func (cc *CanvasContext) DrawHScrollBar(pos Point, sbSize, virtSize, wSize, hOffset int, style tcell.Style) {
-	if sbSize <= 0 || virtSize <= 0 || wSize <= 0 {
-		return
+// ThumbStart returns the cell where the thumb starts
+func (sb ScrollBar) ThumbStart() int {
+	if !sb.HasArrows() {
+		return 0
 	}
+	return 1 + sb.ThumbPos
+}
 
-	maxOffset := max(0, virtSize-wSize)
-	hOffset = max(0, min(hOffset, maxOffset))
-
-	bar := "█"
-	bg := "░"
-
-	switch {
-	case sbSize == 1:
-		cc.Print(pos, bar, style)
-
-	case sbSize == 2:
-		cc.Print(pos, bar+bar, style)
+// OffsetAt returns the virtual offset for the thumb placed at the cell i, it is the reverse
+// of the thumb position calculation
+func (sb ScrollBar) OffsetAt(i int) int {
+	free := sb.TrackLen - sb.ThumbLen
+	if !sb.HasArrows() || free <= 0 {
+		return sb.Offset
+	}
+	pos := max(0, min(i-1, free))
+	return (pos*sb.MaxOffset + free/2) / free
+}
 
-	default:
-		// стрелки
-		cc.Print(pos, "◀", style)
-		cc.Print(Point{X: pos.X + sbSize - 1, Y: pos.Y}, "▶", style)
+// DrawVScrollBar draws the vertical scroll bar at the position pos, see NewScrollBar()
+// for the parameters
+func (cc *CanvasContext) DrawVScrollBar(pos Point, sbSize, virtSize, wSize, vOffset int, style tcell.Style) {
+	cc.drawScrollBar(pos, Point{Y: 1}, NewScrollBar(sbSize, virtSize, wSize, vOffset), "▲", "▼", style)
+}
 
-		trackLen := sbSize - 2
-		thumbLen := min(trackLen, max(1, wSize*trackLen/virtSize))
+// DrawHScrollBar draws the horizontal scroll bar at the position pos, see NewScrollBar()
+// for the parameters
+func (cc *CanvasContext) DrawHScrollBar(pos Point, sbSize, virtSize, wSize, hOffset int, style tcell.Style) {
+	cc.drawScrollBar(pos, Point{X: 1}, NewScrollBar(sbSize, virtSize, wSize, hOffset), "◀", "▶", style)
+}
 
-		offs := 0
-		if maxOffset > 0 {
-			offs = hOffset * (trackLen - thumbLen) / maxOffset
+func (cc *CanvasContext) drawScrollBar(pos, step Point, sb ScrollBar, back, forward string, style tcell.Style) {
+	for i := 0; i < sb.Size; i++ {
+		var str string
+		switch sb.Part(i) {
+		case ScrollBarBackArrow:
+			str = back
+		case ScrollBarForwardArrow:
+			str = forward
+		case ScrollBarThumb:
+			str = "█"
+		default:
+			str = "░"
 		}
-
-		cc.Print(Point{X: pos.X + 1, Y: pos.Y}, strings.Repeat(bg, trackLen), style)
-		cc.Print(Point{X: pos.X + 1 + offs, Y: pos.Y}, strings.Repeat(bar, thumbLen), style)
+		cc.Print(Point{X: pos.X + i*step.X, Y: pos.Y + i*step.Y}, str, style)
 	}
 }
 
@@ -266,9 +431,10 @@ func (cc *CanvasContext) physicalRegion() Rectangle {
 	return cc.stack[len(cc.stack)-1].r
 }
 
-// newCanvas constructs the new instance of CanvasContext with the physical dimensions
+// newCanvas constructs the new instance of CanvasContext with the physical dimensions,
+// which draws to the screen
 func newCanvas(s Size) *CanvasContext {
-	cc := &CanvasContext{}
+	cc := &CanvasContext{screen: true}
 	disp := ctxStackElem{r: Rectangle{X: 0, Y: 0, Width: s.Width, Height: s.Height}}
 	cc.stack = append(cc.stack, disp) // the cc.stack[0] is always the display resolution
 	return cc
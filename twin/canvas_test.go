@@ -0,0 +1,240 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// screenLine returns the clusters put on the screen line y from x, the cells covered by
+// the wide clusters are skipped
+func screenLine(x, y, width int) string {
+	var sb strings.Builder
+	for x1 := x; x1 < x+width; {
+		mainc, combc, _, w := c.s.GetContent(x1, y)
+		sb.WriteRune(mainc)
+		for _, r := range combc {
+			sb.WriteRune(r)
+		}
+		x1 += max(1, w)
+	}
+	return sb.String()
+}
+
+func testCanvas() *CanvasContext {
+	c.s.Clear()
+	w, h := c.s.Size()
+	return newCanvas(Size{Width: w, Height: h})
+}
+
+func TestPrint_Multilingual(t *testing.T) {
+	for _, str := range []string{
+		"Hello, World!",
+		"Привет, мир!",
+		"Γειά σου Κόσμε",
+		"שלום עולם",
+		"مرحبا بالعالم",
+		"e\u0301te\u0301 cafe\u0301",
+		"Ελληνικα\u0301",
+	} {
+		cc := testCanvas()
+		cc.Print(Point{X: 1, Y: 1}, str, tcell.StyleDefault)
+		assert.Equal(t, str+"  ", screenLine(1, 1, StringWidth(str)+2), str)
+	}
+}
+
+func TestPrint_CombiningRunes(t *testing.T) {
+	cc := testCanvas()
+	cc.Print(Point{X: 0, Y: 0}, "e\u0301a\u0308\u0301x", tcell.StyleDefault)
+	mainc, combc, _, _ := c.s.GetContent(0, 0)
+	assert.Equal(t, 'e', mainc)
+	assert.Equal(t, []rune{0x301}, combc)
+	mainc, combc, _, _ = c.s.GetContent(1, 0)
+	assert.Equal(t, 'a', mainc)
+	assert.Equal(t, []rune{0x308, 0x301}, combc)
+	mainc, combc, _, _ = c.s.GetContent(2, 0)
+	assert.Equal(t, 'x', mainc)
+	assert.Empty(t, combc)
+
+	// the mark without the base char is put on the space
+	cc.Print(Point{X: 0, Y: 1}, "\u0301b", tcell.StyleDefault)
+	mainc, combc, _, _ = c.s.GetContent(0, 1)
+	assert.Equal(t, ' ', mainc)
+	assert.Equal(t, []rune{0x301}, combc)
+	assert.Equal(t, " \u0301b", screenLine(0, 1, 2))
+}
+
+func TestPrint_WideClusters(t *testing.T) {
+	for _, tc := range []struct {
+		str   string
+		width int
+	}{
+		{"日本語のテキスト", 16},
+		{"한국어 텍스트", 13},
+		{"\U0001F469\u200D\U0001F469\u200D\U0001F467 family", 9},
+		{"\U0001F1EF\U0001F1F5\U0001F1FA\U0001F1E6 flags", 8},
+		{"\U0001F44D\U0001F3FD ok", 5},
+		{"\u0928\u092E\u0938\u094D\u0924\u0947", StringWidth("\u0928\u092E\u0938\u094D\u0924\u0947")},
+	} {
+		cc := testCanvas()
+		cc.Print(Point{X: 0, Y: 0}, tc.str, tcell.StyleDefault)
+		assert.Equal(t, tc.width, StringWidth(tc.str), tc.str)
+		assert.Equal(t, tc.str+" ", screenLine(0, 0, tc.width+1), tc.str)
+	}
+
+	// the flag is one cluster, so it is not split between the cells
+	cc := testCanvas()
+	cc.Print(Point{X: 0, Y: 0}, "\U0001F1EF\U0001F1F5x", tcell.StyleDefault)
+	mainc, combc, _, _ := c.s.GetContent(0, 0)
+	assert.Equal(t, rune(0x1F1EF), mainc)
+	assert.Equal(t, []rune{0x1F1F5}, combc)
+
+	cc = testCanvas()
+	cc.Print(Point{X: 0, Y: 0}, "\U0001F469\u200D\U0001F469\u200D\U0001F467x", tcell.StyleDefault)
+	mainc, combc, _, w := c.s.GetContent(0, 0)
+	assert.Equal(t, rune(0x1F469), mainc)
+	assert.Equal(t, []rune("\u200D\U0001F469\u200D\U0001F467"), combc)
+	assert.Equal(t, 2, w)
+	mainc, _, _, _ = c.s.GetContent(2, 0)
+	assert.Equal(t, 'x', mainc)
+}
+
+func TestPrint_WideClustersClipped(t *testing.T) {
+	cc := testCanvas()
+	c.s.SetContent(5, 0, '#', nil, tcell.StyleDefault)
+	// the region is 5 cells wide, so the third wide char is cut by the right border
+	cc.pushRelativeRegion(Point{}, Rectangle{X: 0, Y: 0, Width: 5, Height: 1})
+	cc.Print(Point{X: 0, Y: 0}, "日本語", tcell.StyleDefault)
+	cc.pop()
+	assert.Equal(t, "日本 #", screenLine(0, 0, 6))
+
+	// the region starts at 1, so the first wide char is cut by the left border
+	cc = testCanvas()
+	c.s.SetContent(0, 0, '#', nil, tcell.StyleDefault)
+	cc.pushRelativeRegion(Point{}, Rectangle{X: 1, Y: 0, Width: 10, Height: 1})
+	cc.Print(Point{X: -1, Y: 0}, "日本語", tcell.StyleDefault)
+	cc.pop()
+	assert.Equal(t, "# 本語", screenLine(0, 0, 6))
+}
+
+func TestPrintL(t *testing.T) {
+	cc := testCanvas()
+	cc.PrintL(Point{X: 0, Y: 0}, "e\u0301日x", 2, tcell.StyleDefault)
+	assert.Equal(t, "e\u0301日  ", screenLine(0, 0, 5))
+
+	cc.PrintL(Point{X: 0, Y: 1}, "abc", 0, tcell.StyleDefault)
+	assert.Equal(t, "   ", screenLine(0, 1, 3))
+}
+
+func TestPrintText(t *testing.T) {
+	cc := testCanvas()
+	cc.PrintText(Point{X: 0, Y: 0}, ParseMarkup("[red]日本[-] e\u0301[::b]x"), tcell.StyleDefault)
+	assert.Equal(t, "日本 e\u0301x ", screenLine(0, 0, 8))
+	_, _, style, _ := c.s.GetContent(2, 0)
+	fg, _, _ := style.Decompose()
+	assert.Equal(t, tcell.ColorRed, AdaptColor(fg))
+	_, _, style, _ = c.s.GetContent(6, 0)
+	_, _, attrs := style.Decompose()
+	assert.Equal(t, tcell.AttrBold, attrs)
+}
+
+func TestText_Truncate(t *testing.T) {
+	txt := ParseMarkup("ab[red]日本e\u0301")
+	assert.Equal(t, "ab日", txt.Truncate(5).String())
+	assert.Equal(t, "ab日本e\u0301", txt.Truncate(7).String())
+	assert.Equal(t, 7, txt.Width())
+}
+
+// linkBox prints the text with a link and records the activated links
+type linkBox struct {
+	Box
+	activated []string
+}
+
+func (lb *linkBox) CanBeFocused() bool { return true }
+
+func (lb *linkBox) OnDraw(cc *CanvasContext) {
+	cc.PrintText(Point{}, ParseMarkup("see [::u:https://x.io/a]here[-]"), tcell.StyleDefault)
+}
+
+func (lb *linkBox) OnLinkActivated(url string) bool {
+	lb.activated = append(lb.activated, url)
+	return true
+}
+
+func TestPrintText_Link(t *testing.T) {
+	c.onScreenResize()
+	lb := &linkBox{}
+	assert.Nil(t, lb.Init(Root(), lb))
+	lb.SetBounds(Rectangle{X: 1, Y: 1, Width: 10, Height: 1})
+	t.Cleanup(func() {
+		Close(lb)
+		c.onLoop()
+	})
+	c.drawDamage(newCanvas(c.root.Bounds().Size()), []Rectangle{lb.Bounds()})
+	assert.Equal(t, linkCell{comp: lb, link: "https://x.io/a"}, c.links[Point{X: 5, Y: 1}])
+	_, ok := c.links[Point{X: 4, Y: 1}]
+	assert.False(t, ok)
+
+	// click on the link
+	c.onEvent(tcell.NewEventMouse(6, 1, tcell.Button1, 0))
+	c.onEvent(tcell.NewEventMouse(6, 1, tcell.ButtonNone, 0))
+	assert.Equal(t, []string{"https://x.io/a"}, lb.activated)
+
+	// Enter on the focused component
+	assert.True(t, c.setActive(lb))
+	c.onEvent(tcell.NewEventKey(tcell.KeyEnter, 0, 0))
+	assert.Equal(t, []string{"https://x.io/a", "https://x.io/a"}, lb.activated)
+
+	// the link cells are overwritten
+	c.drawDamage(newCanvas(c.root.Bounds().Size()), []Rectangle{{X: 5, Y: 1, Width: 1, Height: 1}})
+	assert.Len(t, c.links, 4)
+	Close(lb)
+	c.onLoop()
+	c.drawDamage(newCanvas(c.root.Bounds().Size()), takeDamage())
+	assert.Empty(t, c.links)
+
+	SetTitle("twin")
+	assert.Equal(t, "twin", c.s.(tcell.SimulationScreen).GetTitle())
+}
+
+func TestScrollBar(t *testing.T) {
+	// 10 cells: 2 arrows and 8 track cells, the window is the quarter of the field
+	sb := NewScrollBar(10, 100, 25, 75)
+	assert.Equal(t, 8, sb.TrackLen)
+	assert.Equal(t, 2, sb.ThumbLen)
+	assert.Equal(t, 6, sb.ThumbPos)
+	assert.Equal(t, ScrollBarBackArrow, sb.Part(0))
+	assert.Equal(t, ScrollBarBackTrack, sb.Part(6))
+	assert.Equal(t, ScrollBarThumb, sb.Part(7))
+	assert.Equal(t, ScrollBarThumb, sb.Part(8))
+	assert.Equal(t, ScrollBarForwardArrow, sb.Part(9))
+	assert.Equal(t, ScrollBarNone, sb.Part(10))
+
+	// the thumb position and the offset are reverse to each other
+	for offs := 0; offs <= sb.MaxOffset; offs += 25 {
+		sb1 := NewScrollBar(10, 100, 25, offs)
+		assert.Equal(t, offs, sb1.OffsetAt(sb1.ThumbStart()))
+	}
+	assert.Equal(t, 0, sb.OffsetAt(-5))
+	assert.Equal(t, 75, sb.OffsetAt(20))
+
+	sb = NewScrollBar(2, 100, 25, 10)
+	assert.False(t, sb.HasArrows())
+	assert.Equal(t, ScrollBarThumb, sb.Part(1))
+	assert.Equal(t, 10, sb.OffsetAt(0))
+}
+
+func TestDrawScrollBar_Same(t *testing.T) {
+	cc := testCanvas()
+	cc.DrawVScrollBar(Point{X: 0, Y: 0}, 6, 20, 5, 0, tcell.StyleDefault)
+	cc.DrawHScrollBar(Point{X: 1, Y: 0}, 6, 20, 5, 0, tcell.StyleDefault)
+	var v []rune
+	for y := 0; y < 6; y++ {
+		r, _, _, _ := c.s.GetContent(0, y)
+		v = append(v, r)
+	}
+	assert.Equal(t, "▲█░░░▼", string(v))
+	assert.Equal(t, "◀█░░░▶", screenLine(1, 0, 6))
+}
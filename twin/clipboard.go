@@ -0,0 +1,101 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ClipboardService is the clipboard shared by all the components. The text is kept in the internal
+// buffer, and, if the system clipboard is enabled, it is also sent to the terminal with the OSC 52
+// escape sequence, so the copying works over SSH as well.
+type ClipboardService struct {
+	lock   sync.Mutex
+	text   string
+	system atomic.Bool
+}
+
+// ClipboardAction is the clipboard operation requested by a key
+type ClipboardAction int
+
+const (
+	ClipboardNone = ClipboardAction(iota)
+	ClipboardCopy
+	ClipboardCut
+	ClipboardPaste
+)
+
+type clipboardPasteEvent struct {
+	tcell.EventTime
+	text string
+}
+
+// Clipboard is the clipboard service of the application
+var Clipboard = &ClipboardService{}
+
+// SetSystem enables or disables copying to the system clipboard. The terminal must support
+// OSC 52, otherwise the system clipboard is not changed.
+func (cs *ClipboardService) SetSystem(enabled bool) {
+	cs.system.Store(enabled)
+}
+
+// IsSystem returns whether the text is copied to the system clipboard, see SetSystem()
+func (cs *ClipboardService) IsSystem() bool {
+	return cs.system.Load()
+}
+
+// Copy puts the text to the clipboard
+func (cs *ClipboardService) Copy(text string) {
+	cs.lock.Lock()
+	cs.text = text
+	cs.lock.Unlock()
+	if cs.system.Load() {
+		c.screen().SetClipboard([]byte(text))
+	}
+}
+
+// CopyRows puts the rows to the clipboard as the tab separated values, the tabs and line breaks
+// in the cells are replaced by the spaces
+func (cs *ClipboardService) CopyRows(rows [][]string) {
+	r := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+	var sb strings.Builder
+	for _, row := range rows {
+		for i, cell := range row {
+			if i > 0 {
+				sb.WriteByte('\t')
+			}
+			sb.WriteString(r.Replace(cell))
+		}
+		sb.WriteByte('\n')
+	}
+	cs.Copy(sb.String())
+}
+
+// Text returns the clipboard text
+func (cs *ClipboardService) Text() string {
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	return cs.text
+}
+
+// Paste delivers the clipboard text to the focused component as the pasted one (see
+// Component.OnPaste)
+func (cs *ClipboardService) Paste() {
+	c.screen().PostEvent(&clipboardPasteEvent{text: cs.Text()})
+}
+
+// ClipboardKey returns the clipboard action for the key: Ctrl+Insert copies, Ctrl+X and
+// Shift+Delete cut, Ctrl+V and Shift+Insert paste. Ctrl+C closes the application, so it
+// is not used for copying.
+func ClipboardKey(ke *tcell.EventKey) ClipboardAction {
+	switch {
+	case ke.Key() == tcell.KeyInsert && ke.Modifiers() == tcell.ModCtrl:
+		return ClipboardCopy
+	case ke.Key() == tcell.KeyCtrlX, ke.Key() == tcell.KeyDelete && ke.Modifiers() == tcell.ModShift:
+		return ClipboardCut
+	case ke.Key() == tcell.KeyCtrlV, ke.Key() == tcell.KeyInsert && ke.Modifiers() == tcell.ModShift:
+		return ClipboardPaste
+	}
+	return ClipboardNone
+}
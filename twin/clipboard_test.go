@@ -0,0 +1,45 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestClipboard_Copy(t *testing.T) {
+	ss := c.s.(tcell.SimulationScreen)
+	ss.SetClipboard(nil)
+	Clipboard.Copy("abc")
+	assert.Equal(t, "abc", Clipboard.Text())
+	assert.Empty(t, ss.GetClipboardData())
+
+	Clipboard.SetSystem(true)
+	defer Clipboard.SetSystem(false)
+	Clipboard.CopyRows([][]string{{"a", "b\tc"}, {"d\ne", ""}})
+	assert.Equal(t, "a\tb c\nd e\t\n", Clipboard.Text())
+	assert.Equal(t, "a\tb c\nd e\t\n", string(ss.GetClipboardData()))
+}
+
+func TestClipboard_Paste(t *testing.T) {
+	ib := &inputBox{handlePaste: true}
+	assert.Nil(t, ib.Init(Root(), ib))
+	ib.SetBounds(Rectangle{Width: 5, Height: 1})
+	t.Cleanup(func() {
+		Close(ib)
+		c.onLoop()
+	})
+	assert.True(t, c.setActive(ib))
+
+	Clipboard.Copy("text")
+	c.onEvent(&clipboardPasteEvent{text: Clipboard.Text()})
+	assert.Equal(t, "text", ib.pasted)
+}
+
+func TestClipboardKey(t *testing.T) {
+	assert.Equal(t, ClipboardCopy, ClipboardKey(tcell.NewEventKey(tcell.KeyInsert, 0, tcell.ModCtrl)))
+	assert.Equal(t, ClipboardCut, ClipboardKey(tcell.NewEventKey(tcell.KeyCtrlX, 0, tcell.ModCtrl)))
+	assert.Equal(t, ClipboardCut, ClipboardKey(tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModShift)))
+	assert.Equal(t, ClipboardPaste, ClipboardKey(tcell.NewEventKey(tcell.KeyCtrlV, 0, tcell.ModCtrl)))
+	assert.Equal(t, ClipboardPaste, ClipboardKey(tcell.NewEventKey(tcell.KeyInsert, 0, tcell.ModShift)))
+	assert.Equal(t, ClipboardNone, ClipboardKey(tcell.NewEventKey(tcell.KeyInsert, 0, 0)))
+}
@@ -0,0 +1,117 @@
+package twin
+
+import (
+	"github.com/dspasibenko/twin-go/pkg/golibs/container/lru"
+	"github.com/gdamore/tcell/v2"
+)
+
+// colorKey is the key of the palette cache, the nearest color depends on the number of colors
+type colorKey struct {
+	clr    tcell.Color
+	colors int
+}
+
+// paletteCacheSize is the number of the colors, which nearest palette colors are cached
+const paletteCacheSize = 4096
+
+var paletteCache, _ = lru.NewCache[colorKey, tcell.Color](paletteCacheSize, paletteColor, nil)
+
+// ColorDepth returns the number of colors the terminal supports. It is 1<<24 for the
+// truecolor terminals.
+func ColorDepth() int {
+	c.screen()
+	return int(c.colors.Load())
+}
+
+// AdaptColor returns the color, which can be shown by the terminal. If the terminal doesn't support
+// the color clr, the nearest one from the terminal palette is returned.
+func AdaptColor(clr tcell.Color) tcell.Color {
+	return nearestColor(clr, ColorDepth())
+}
+
+// AdaptStyle returns the style with the foreground and background colors adapted to
+// the terminal color depth (see AdaptColor)
+func AdaptStyle(style tcell.Style) tcell.Style {
+	colors := ColorDepth()
+	fg, bg, _ := style.Decompose()
+	return style.Foreground(nearestColor(fg, colors)).Background(nearestColor(bg, colors))
+}
+
+// Blend mixes the colors from and to. alpha is in [0..1] range, 0 means the color from,
+// and 1 means the color to. The result is the RGB color. If any of the colors is the
+// terminal default one, the colors cannot be mixed, so the closest one by alpha is returned.
+func Blend(from, to tcell.Color, alpha float64) tcell.Color {
+	alpha = min(1.0, max(0.0, alpha))
+	if !from.Valid() || !to.Valid() {
+		if alpha < 0.5 {
+			return from
+		}
+		return to
+	}
+	r1, g1, b1 := from.RGB()
+	r2, g2, b2 := to.RGB()
+	mix := func(a, b int32) int32 {
+		return a + int32(float64(b-a)*alpha+0.5)
+	}
+	return tcell.NewRGBColor(mix(r1, r2), mix(g1, g2), mix(b1, b2))
+}
+
+// BlendStyle mixes the foreground and background colors of the style with clr (see Blend)
+func BlendStyle(style tcell.Style, clr tcell.Color, alpha float64) tcell.Style {
+	fg, bg, _ := style.Decompose()
+	return style.Foreground(Blend(fg, clr, alpha)).Background(Blend(bg, clr, alpha))
+}
+
+// Gradient returns n colors, which evenly change from the color from to the color to
+func Gradient(from, to tcell.Color, n int) []tcell.Color {
+	if n <= 0 {
+		return nil
+	}
+	res := make([]tcell.Color, n)
+	if n == 1 {
+		res[0] = from
+		return res
+	}
+	for i := range res {
+		res[i] = Blend(from, to, float64(i)/float64(n-1))
+	}
+	return res
+}
+
+// nearestColor returns the palette color for clr, if the number of colors is not enough to
+// show clr as is.
+func nearestColor(clr tcell.Color, colors int) tcell.Color {
+	if !clr.Valid() || colors <= 0 {
+		return clr
+	}
+	if clr&tcell.ColorIsRGB == 0 {
+		if int(clr-tcell.ColorValid) < colors {
+			return clr // the palette color is supported
+		}
+	} else if colors >= 1<<24 {
+		return clr
+	}
+	res, _ := paletteCache.GetOrCreate(colorKey{clr: clr, colors: colors})
+	return res
+}
+
+// paletteColor finds the nearest palette color for the key
+func paletteColor(key colorKey) (tcell.Color, error) {
+	r, g, b := key.clr.RGB()
+	res, best := key.clr, int64(-1)
+	for i := 0; i < min(key.colors, 256); i++ {
+		pc := tcell.PaletteColor(i)
+		pr, pg, pb := pc.RGB()
+		if d := colorDistance(r, g, b, pr, pg, pb); best < 0 || d < best {
+			res, best = pc, d
+		}
+	}
+	return res, nil
+}
+
+// colorDistance is the "redmean" approximation of the perceived colors difference
+func colorDistance(r1, g1, b1, r2, g2, b2 int32) int64 {
+	rm := int64(r1+r2) / 2
+	dr, dg, db := int64(r1-r2), int64(g1-g2), int64(b1-b2)
+	return ((512+rm)*dr*dr)>>8 + 4*dg*dg + ((767-rm)*db*db)>>8
+}
@@ -0,0 +1,36 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNearestColor(t *testing.T) {
+	assert.Equal(t, tcell.ColorDefault, nearestColor(tcell.ColorDefault, 8))
+	assert.Equal(t, tcell.ColorRed, nearestColor(tcell.ColorRed, 16))
+	assert.Equal(t, tcell.ColorMaroon, nearestColor(tcell.ColorRed, 8))
+	rgb := tcell.NewRGBColor(250, 5, 5)
+	assert.Equal(t, rgb, nearestColor(rgb, 1<<24))
+	assert.Equal(t, tcell.ColorRed, nearestColor(rgb, 256))
+	assert.Equal(t, tcell.ColorMaroon, nearestColor(rgb, 8))
+	assert.Equal(t, tcell.ColorMaroon, nearestColor(rgb, 8))
+}
+
+func TestNearestColor_CacheSize(t *testing.T) {
+	for i := int32(0); i < paletteCacheSize+100; i++ {
+		nearestColor(tcell.NewRGBColor(i&0xff, i>>8, 1), 256)
+	}
+	assert.Equal(t, paletteCacheSize, paletteCache.Clear())
+}
+
+func TestBlend(t *testing.T) {
+	from, to := tcell.NewRGBColor(0, 0, 0), tcell.NewRGBColor(200, 100, 50)
+	assert.Equal(t, from, Blend(from, to, -1))
+	assert.Equal(t, tcell.NewRGBColor(100, 50, 25), Blend(from, to, 0.5))
+	assert.Equal(t, to, Blend(from, to, 2))
+	assert.Equal(t, tcell.ColorDefault, Blend(tcell.ColorDefault, to, 0.4))
+	assert.Equal(t, to, Blend(tcell.ColorDefault, to, 0.6))
+	assert.Equal(t, []tcell.Color{from, tcell.NewRGBColor(100, 50, 25), to}, Gradient(from, to, 3))
+	assert.Nil(t, Gradient(from, to, 0))
+}
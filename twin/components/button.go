@@ -3,7 +3,6 @@ package components
 import (
 	"github.com/dspasibenko/twin-go/twin"
 	"github.com/gdamore/tcell/v2"
-	"github.com/mattn/go-runewidth"
 	"sync"
 	"sync/atomic"
 )
@@ -11,8 +10,10 @@ import (
 type Button struct {
 	twin.Box
 	bs      ButtonStyle
+	txt     twin.Text
 	lock    sync.Mutex
 	txtOffs atomic.Int32
+	ver     atomic.Int64
 }
 
 type ButtonStyle struct {
@@ -20,6 +21,7 @@ type ButtonStyle struct {
 	style       *tcell.Style
 	activeStyle *tcell.Style
 	text        string
+	markup      bool
 	allignment  *TextAlignment
 	rect        twin.Rectangle
 	onEnter     func(b *Button)
@@ -34,37 +36,30 @@ func (bs ButtonStyle) WithStyle(style tcell.Style) ButtonStyle {
 	return bs
 }
 
-func (bs ButtonStyle) Style() tcell.Style {
-	if bs.style != nil {
-		return *bs.style
-	}
-	return GetThemeValue[ButtonTheme](string(bs.button)).NotActive
-}
-
 func (bs ButtonStyle) WithActiveStyle(style tcell.Style) ButtonStyle {
 	bs.activeStyle = &style
 	return bs
 }
 
-func (bs ButtonStyle) ActiveStyle() tcell.Style {
-	if bs.activeStyle != nil {
-		return *bs.activeStyle
-	}
-	return GetThemeValue[ButtonTheme](string(bs.button)).Active
-}
-
 func (bs ButtonStyle) WithText(text string) ButtonStyle {
 	bs.text = text
 	return bs
 }
 
+// WithMarkup sets the button text with the markup tags (see twin.ParseMarkup)
+func (bs ButtonStyle) WithMarkup(markup string) ButtonStyle {
+	bs.text = markup
+	bs.markup = true
+	return bs
+}
+
 func (bs ButtonStyle) WithAllignment(a TextAlignment) ButtonStyle {
 	bs.allignment = &a
 	return bs
 }
 
 func (bs ButtonStyle) Allignment() TextAlignment {
-	if bs.style != nil {
+	if bs.allignment != nil {
 		return *bs.allignment
 	}
 	return GetThemeValue[ButtonTheme](string(bs.button)).Alignment
@@ -84,11 +79,16 @@ func NewButton(owner twin.Component, bs ButtonStyle) (*Button, error) {
 	if bs.button == "" {
 		bs.button = NormalButtonType
 	}
-	b := &Button{bs: bs}
+	b := &Button{bs: bs, txt: twin.PlainText(bs.text)}
+	if bs.markup {
+		b.txt = twin.ParseMarkup(bs.text)
+	}
 	err := b.Box.Init(owner, b)
 	if err != nil {
 		return nil, err
 	}
+	b.SetOpaque(true)
+	initStyles(&b.Box, string(bs.button), bs.style, bs.activeStyle)
 	b.Box.SetBounds(bs.rect)
 	b.recalc(bs.rect)
 	return b, nil
@@ -96,6 +96,12 @@ func NewButton(owner twin.Component, bs ButtonStyle) (*Button, error) {
 
 func (b *Button) CanBeFocused() bool { return true }
 
+// IsEnabled returns false if the button has no onEnter function, so it cannot be pressed
+func (b *Button) IsEnabled() bool { return b.Box.IsEnabled() && b.bs.onEnter != nil }
+
+// Hotkey returns the hotkey defined in the button text markup, or 0 if there is no one
+func (b *Button) Hotkey() rune { return b.txt.Hotkey() }
+
 func (b *Button) OnMousePressed(p twin.Point) bool { return b.onEnter() }
 
 func (b *Button) OnKeyPressed(ke *tcell.EventKey) bool {
@@ -118,19 +124,17 @@ func (b *Button) SetBounds(rect twin.Rectangle) {
 
 func (b *Button) OnDraw(cc *twin.CanvasContext) {
 	r := b.Bounds().Normalized()
-	var style tcell.Style
-	if twin.IsActive(b) {
-		style = b.bs.ActiveStyle()
-	} else {
-		style = b.bs.Style()
+	if b.ver.Load() != themeVersion.Load() {
+		b.recalc(b.Bounds())
 	}
+	style := twin.EffectiveStyle(b)
 
 	cc.FilledRectangle(r, style)
-	cc.Print(twin.Point{X: int(b.txtOffs.Load()), Y: 0}, b.bs.text, style)
+	cc.PrintText(twin.Point{X: int(b.txtOffs.Load()), Y: 0}, b.txt, style)
 }
 
 func (b *Button) recalc(r twin.Rectangle) {
-	w := runewidth.StringWidth(b.bs.text)
+	w := b.txt.Width()
 	offs := int32(0)
 	switch b.bs.Allignment() {
 	case AllignLeft:
@@ -142,6 +146,7 @@ func (b *Button) recalc(r twin.Rectangle) {
 	}
 	b.bs.rect = r // not necessary, but just in case
 	b.txtOffs.Store(offs)
+	b.ver.Store(themeVersion.Load())
 }
 
 func (b *Button) onEnter() bool {
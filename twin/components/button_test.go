@@ -0,0 +1,30 @@
+package components
+
+import (
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestButton(t *testing.T) {
+	onTwin(func() {
+		var pressed int
+		b, err := NewButton(twin.Root(), ButtonStyle{}.WithMarkup("&Save").WithOnEnter(func(b *Button) { pressed++ }))
+		assert.Nil(t, err)
+		defer twin.Close(b)
+		assert.Equal(t, 'S', b.Hotkey())
+		assert.True(t, b.OnKeyPressed(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)))
+		assert.True(t, b.OnMousePressed(twin.Point{}))
+		assert.Equal(t, 2, pressed)
+
+		// the button without the function is disabled, so it cannot be focused
+		b1, err := NewButton(twin.Root(), ButtonStyle{}.WithText("OK"))
+		assert.Nil(t, err)
+		defer twin.Close(b1)
+		assert.False(t, b1.IsEnabled())
+		assert.False(t, twin.IsEnabled(b1))
+		assert.True(t, twin.IsEnabled(b))
+		assert.False(t, b1.OnKeyPressed(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)))
+	})
+}
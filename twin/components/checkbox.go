@@ -0,0 +1,128 @@
+package components
+
+import (
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"sync/atomic"
+)
+
+// CheckBox is the boolean input which is drawn as "[x] text"
+type CheckBox struct {
+	twin.Box
+	cbs     CheckBoxStyle
+	checked atomic.Bool
+}
+
+type CheckBoxStyle struct {
+	checkbox    string
+	style       *tcell.Style
+	activeStyle *tcell.Style
+	text        string
+	checked     bool
+	rect        twin.Rectangle
+	onChange    func(cb *CheckBox)
+}
+
+func (cbs CheckBoxStyle) WithCheckBox(checkbox string) CheckBoxStyle {
+	cbs.checkbox = checkbox
+	return cbs
+}
+
+func (cbs CheckBoxStyle) WithStyle(style tcell.Style) CheckBoxStyle {
+	cbs.style = &style
+	return cbs
+}
+
+func (cbs CheckBoxStyle) WithActiveStyle(style tcell.Style) CheckBoxStyle {
+	cbs.activeStyle = &style
+	return cbs
+}
+
+func (cbs CheckBoxStyle) WithText(text string) CheckBoxStyle {
+	cbs.text = text
+	return cbs
+}
+
+func (cbs CheckBoxStyle) WithChecked(checked bool) CheckBoxStyle {
+	cbs.checked = checked
+	return cbs
+}
+
+func (cbs CheckBoxStyle) WithRectangle(rect twin.Rectangle) CheckBoxStyle {
+	cbs.rect = rect
+	return cbs
+}
+
+func (cbs CheckBoxStyle) WithOnChange(f func(cb *CheckBox)) CheckBoxStyle {
+	cbs.onChange = f
+	return cbs
+}
+
+func NewCheckBox(owner twin.Component, cbs CheckBoxStyle) (*CheckBox, error) {
+	if cbs.checkbox == "" {
+		cbs.checkbox = "checkbox"
+	}
+	cb := &CheckBox{cbs: cbs}
+	cb.checked.Store(cbs.checked)
+	err := cb.Box.Init(owner, cb)
+	if err != nil {
+		return nil, err
+	}
+	cb.SetOpaque(true)
+	initStyles(&cb.Box, cbs.checkbox, cbs.style, cbs.activeStyle)
+	cb.Box.SetBounds(cbs.rect)
+	return cb, nil
+}
+
+func (cb *CheckBox) CanBeFocused() bool { return true }
+
+func (cb *CheckBox) IsChecked() bool {
+	return cb.checked.Load()
+}
+
+func (cb *CheckBox) SetChecked(checked bool) {
+	if cb.checked.Swap(checked) == checked {
+		return
+	}
+	if cb.cbs.onChange != nil {
+		cb.cbs.onChange(cb)
+	}
+	twin.Redraw(twin.This(cb))
+}
+
+// FormValue returns the bool value, so the CheckBox can be used as FormInput
+func (cb *CheckBox) FormValue() any {
+	return cb.IsChecked()
+}
+
+func (cb *CheckBox) SetFormValue(v any) error {
+	b, ok := v.(bool)
+	if !ok {
+		return errFormValueType(v, b)
+	}
+	cb.SetChecked(b)
+	return nil
+}
+
+func (cb *CheckBox) OnKeyPressed(ke *tcell.EventKey) bool {
+	if ke.Key() == tcell.KeyRune && ke.Rune() == ' ' {
+		cb.SetChecked(!cb.IsChecked())
+		return true
+	}
+	return false
+}
+
+func (cb *CheckBox) OnMousePressed(p twin.Point) bool {
+	cb.SetChecked(!cb.IsChecked())
+	return true
+}
+
+func (cb *CheckBox) OnDraw(cc *twin.CanvasContext) {
+	style := twin.EffectiveStyle(cb)
+	cc.FilledRectangle(cb.Bounds().Normalized(), style)
+	mark := "[ ] "
+	if cb.IsChecked() {
+		mark = "[x] "
+	}
+	cc.Print(twin.Point{}, mark+cb.cbs.text, style)
+}
@@ -0,0 +1,154 @@
+package components
+
+import (
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"sync/atomic"
+)
+
+// ChoiceBox allows to select one value from the list of choices. The value is
+// changed by Left, Right, Space keys and by the mouse click.
+type ChoiceBox struct {
+	twin.Box
+	chs      ChoiceBoxStyle
+	selected atomic.Int32
+}
+
+type ChoiceBoxStyle struct {
+	choicebox   string
+	style       *tcell.Style
+	activeStyle *tcell.Style
+	choices     []string
+	selected    int
+	rect        twin.Rectangle
+	onChange    func(ch *ChoiceBox)
+}
+
+func (chs ChoiceBoxStyle) WithChoiceBox(choicebox string) ChoiceBoxStyle {
+	chs.choicebox = choicebox
+	return chs
+}
+
+func (chs ChoiceBoxStyle) WithStyle(style tcell.Style) ChoiceBoxStyle {
+	chs.style = &style
+	return chs
+}
+
+func (chs ChoiceBoxStyle) WithActiveStyle(style tcell.Style) ChoiceBoxStyle {
+	chs.activeStyle = &style
+	return chs
+}
+
+func (chs ChoiceBoxStyle) WithChoices(choices ...string) ChoiceBoxStyle {
+	chs.choices = choices
+	return chs
+}
+
+func (chs ChoiceBoxStyle) WithSelected(idx int) ChoiceBoxStyle {
+	chs.selected = idx
+	return chs
+}
+
+func (chs ChoiceBoxStyle) WithRectangle(rect twin.Rectangle) ChoiceBoxStyle {
+	chs.rect = rect
+	return chs
+}
+
+func (chs ChoiceBoxStyle) WithOnChange(f func(ch *ChoiceBox)) ChoiceBoxStyle {
+	chs.onChange = f
+	return chs
+}
+
+func NewChoiceBox(owner twin.Component, chs ChoiceBoxStyle) (*ChoiceBox, error) {
+	if len(chs.choices) == 0 {
+		return nil, fmt.Errorf("at least one choice must be provided: %w", errors.ErrInvalid)
+	}
+	if chs.choicebox == "" {
+		chs.choicebox = "choicebox"
+	}
+	ch := &ChoiceBox{chs: chs}
+	ch.selected.Store(int32(max(0, min(chs.selected, len(chs.choices)-1))))
+	err := ch.Box.Init(owner, ch)
+	if err != nil {
+		return nil, err
+	}
+	ch.SetOpaque(true)
+	initStyles(&ch.Box, chs.choicebox, chs.style, chs.activeStyle)
+	ch.Box.SetBounds(chs.rect)
+	return ch, nil
+}
+
+func (ch *ChoiceBox) CanBeFocused() bool { return true }
+
+// Selected returns the index of the selected choice
+func (ch *ChoiceBox) Selected() int {
+	return int(ch.selected.Load())
+}
+
+// Value returns the selected choice
+func (ch *ChoiceBox) Value() string {
+	return ch.chs.choices[ch.Selected()]
+}
+
+func (ch *ChoiceBox) SetSelected(idx int) {
+	n := len(ch.chs.choices)
+	idx = (idx%n + n) % n
+	if int(ch.selected.Swap(int32(idx))) == idx {
+		return
+	}
+	if ch.chs.onChange != nil {
+		ch.chs.onChange(ch)
+	}
+	twin.Redraw(twin.This(ch))
+}
+
+// FormValue returns the selected choice, so the ChoiceBox can be used as FormInput
+func (ch *ChoiceBox) FormValue() any {
+	return ch.Value()
+}
+
+func (ch *ChoiceBox) SetFormValue(v any) error {
+	s, ok := v.(string)
+	if !ok {
+		return errFormValueType(v, s)
+	}
+	for i, c := range ch.chs.choices {
+		if c == s {
+			ch.SetSelected(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("the value %q is not in the choices %v: %w", s, ch.chs.choices, errors.ErrInvalid)
+}
+
+func (ch *ChoiceBox) OnKeyPressed(ke *tcell.EventKey) bool {
+	switch {
+	case ke.Key() == tcell.KeyLeft:
+		ch.SetSelected(ch.Selected() - 1)
+	case ke.Key() == tcell.KeyRight, ke.Key() == tcell.KeyRune && ke.Rune() == ' ':
+		ch.SetSelected(ch.Selected() + 1)
+	default:
+		return false
+	}
+	return true
+}
+
+func (ch *ChoiceBox) OnMousePressed(p twin.Point) bool {
+	if p.X == 0 {
+		ch.SetSelected(ch.Selected() - 1)
+	} else {
+		ch.SetSelected(ch.Selected() + 1)
+	}
+	return true
+}
+
+func (ch *ChoiceBox) OnDraw(cc *twin.CanvasContext) {
+	r := ch.Bounds().Normalized()
+	style := twin.EffectiveStyle(ch)
+	cc.FilledRectangle(r, style)
+	cc.Print(twin.Point{}, "<", style)
+	cc.PrintL(twin.Point{X: 2}, ch.Value(), r.Width-4, style)
+	cc.Print(twin.Point{X: r.Width - 1}, ">", style)
+}
@@ -3,8 +3,13 @@ package components
 import (
 	"fmt"
 	"github.com/dspasibenko/twin-go/pkg/golibs/container"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/pkg/golibs/logging"
 	"github.com/dspasibenko/twin-go/twin"
 	"github.com/gdamore/tcell/v2"
+	"reflect"
+	"sort"
+	"strings"
 	"sync/atomic"
 )
 
@@ -32,27 +37,142 @@ const (
 	WindowFlagAutoHideScrollBM      = WindowFlags(8)
 )
 
+var logger = logging.NewLogger("twin.components")
+
 func init() {
-	SetTheme(GetDefaultTheme())
+	_ = SetTheme(GetDefaultTheme())
 }
 
 type Theme map[string]any
 
-var theme atomic.Value
+// StyleRules is the theme value with the name ThemeRulesKey, it contains the CSS-like selectors
+// (see twin.StyleRule) and the styles for them. The rules are added to the rules made from the
+// other theme values, which are: "name" for the NotActive or Style field, "name:focused" for the
+// Active field, and "name:disabled", "name:hovered", "name:pressed" for the fields with
+// the state names.
+type StyleRules map[string]tcell.Style
+
+// ThemeRulesKey is the theme key for StyleRules
+const ThemeRulesKey = "rules"
+
+var (
+	theme atomic.Value
+	// themeVersion is increased every time the theme is changed, so the components
+	// may recalculate the values which depend on the theme
+	themeVersion atomic.Int64
+)
 
-func SetTheme(t Theme) {
-	t = container.CopyMap(t)
-	theme.Store(t)
+// SetTheme sets the theme t and redraws all the components. The values, which are
+// not defined in t, are taken from the default theme. It returns an error if a value
+// in t has the type different from the one in the default theme.
+func SetTheme(t Theme) error {
+	res := GetDefaultTheme()
+	for name, v := range t {
+		if dv, ok := res[name]; ok && reflect.TypeOf(dv) != reflect.TypeOf(v) {
+			return fmt.Errorf("the theme value %q must be %T, but it is %T: %w", name, dv, v, errors.ErrInvalid)
+		}
+		res[name] = v
+	}
+	ss, err := themeStyleSheet(res)
+	if err != nil {
+		return err
+	}
+	theme.Store(res)
+	twin.SetStyleSheet(ss)
+	themeVersion.Add(1)
+	twin.Redraw(twin.Root())
+	return nil
 }
 
-// GeThemeValue for the name. It panics if there is no such name in the theme
-func GetThemeValue[T any](name string) T {
+// LookupThemeValue returns the theme value for the name. It returns an error if there is no
+// such name in the theme or the value has a different type.
+func LookupThemeValue[T any](name string) (T, error) {
+	var res T
 	t := theme.Load().(Theme)
 	v, ok := t[name]
 	if !ok {
-		panic(fmt.Sprintf("Theme value %s not found", name))
+		return res, fmt.Errorf("theme value %q not found: %w", name, errors.ErrNotExist)
 	}
-	return v.(T)
+	res, ok = v.(T)
+	if !ok {
+		return res, fmt.Errorf("theme value %q is %T, but %T is expected: %w", name, v, res, errors.ErrInvalid)
+	}
+	return res, nil
+}
+
+// GetThemeValue for the name. If there is no such name in the theme, or its type is different,
+// the error is logged and the default theme value is returned. Use LookupThemeValue to get the
+// error.
+func GetThemeValue[T any](name string) T {
+	res, err := LookupThemeValue[T](name)
+	if err != nil {
+		logger.Errorf("%s, the default theme value is used", err)
+		res, _ = GetDefaultTheme()[name].(T)
+	}
+	return res
+}
+
+// initStyles sets the component style type and the states styles, which override the theme
+func initStyles(b *twin.Box, tp string, normal, focused *tcell.Style) {
+	b.SetStyleType(tp)
+	if normal != nil {
+		b.SetStateStyle(twin.StateNormal, *normal)
+	}
+	if focused != nil {
+		b.SetStateStyle(twin.StateFocused, *focused)
+	}
+}
+
+// themeStyleSheet builds the style rules from the theme values
+func themeStyleSheet(t Theme) (*twin.StyleSheet, error) {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var rules []twin.StyleRule
+	for _, name := range names {
+		v := reflect.ValueOf(t[name])
+		if v.Kind() != reflect.Struct {
+			continue
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.Type != styleType || f.Anonymous {
+				continue
+			}
+			sel := name
+			style := v.Field(i).Interface().(tcell.Style)
+			switch f.Name {
+			case "NotActive", "Style":
+			case "Active":
+				sel += ":focused"
+			case "Disabled", "Hovered", "Pressed":
+				if style == tcell.StyleDefault {
+					// not defined, the normal style is used for the state
+					continue
+				}
+				sel += ":" + strings.ToLower(f.Name)
+			default:
+				continue
+			}
+			rules = append(rules, twin.StyleRule{Selector: sel, Style: style})
+		}
+	}
+	sr, _ := t[ThemeRulesKey].(StyleRules)
+	sels := make([]string, 0, len(sr))
+	for sel := range sr {
+		sels = append(sels, sel)
+	}
+	sort.Strings(sels)
+	for _, sel := range sels {
+		rules = append(rules, twin.StyleRule{Selector: sel, Style: sr[sel]})
+	}
+	return twin.NewStyleSheet(rules)
+}
+
+func copyTheme(t Theme) Theme {
+	return container.CopyMap(t)
 }
 
 func GetThemeValueIfNoVal[T any](val *T, name string) T {
@@ -66,17 +186,20 @@ type (
 	ButtonTheme struct {
 		NotActive tcell.Style
 		Active    tcell.Style
+		Disabled  tcell.Style
 		Alignment TextAlignment
 	}
 
 	LabelTheme struct {
 		Style     tcell.Style
+		Disabled  tcell.Style
 		Alignment TextAlignment
 	}
 
 	WindowTheme struct {
 		NotActive       tcell.Style
 		Active          tcell.Style
+		Disabled        tcell.Style
 		Flags           WindowFlags
 		NaRectStyle     twin.CanvasRectangleStyle
 		ActiveRectStyle twin.CanvasRectangleStyle
@@ -87,13 +210,53 @@ type (
 		SelStyle  tcell.Style
 		SelActive tcell.Style
 	}
+
+	EditLineTheme struct {
+		NotActive tcell.Style
+		Active    tcell.Style
+		Disabled  tcell.Style
+	}
+
+	CheckBoxTheme struct {
+		NotActive tcell.Style
+		Active    tcell.Style
+		Disabled  tcell.Style
+	}
+
+	ChoiceBoxTheme struct {
+		NotActive tcell.Style
+		Active    tcell.Style
+		Disabled  tcell.Style
+	}
+
+	FormTheme struct {
+		Style tcell.Style
+	}
+
+	// LogViewTheme defines the colors of the LogView lines by their levels, the lines without
+	// the level and the info ones are shown with the window style
+	LogViewTheme struct {
+		Error tcell.Color
+		Warn  tcell.Color
+		Debug tcell.Color
+		Trace tcell.Color
+		// Match is the style of the text found
+		Match tcell.Style
+	}
+
+	// TerminalTheme defines the colors of the Terminal cells, which have the default ones
+	TerminalTheme struct {
+		Style tcell.Style
+	}
 )
 
 func GetDefaultTheme() Theme {
 	return Theme{
+		ThemeRulesKey: StyleRules{},
 		"win": WindowTheme{
 			NotActive:       tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorWhite),
 			Active:          tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorWhite),
+			Disabled:        tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorGrey),
 			Flags:           WindowFlagHasBorderBM | WindowFlagHasBothScrollsBM | WindowFlagAutoHideScrollBM,
 			NaRectStyle:     twin.CanvasRectangleRounded,
 			ActiveRectStyle: twin.CanvasRectangleDouble,
@@ -101,6 +264,7 @@ func GetDefaultTheme() Theme {
 		"button": ButtonTheme{
 			NotActive: tcell.StyleDefault.Background(tcell.ColorGrey).Foreground(tcell.ColorWhite),
 			Active:    tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack),
+			Disabled:  tcell.StyleDefault.Background(tcell.ColorGrey).Foreground(tcell.ColorSilver),
 			Alignment: AllignCenter,
 		},
 		"label": LabelTheme{
@@ -114,9 +278,47 @@ func GetDefaultTheme() Theme {
 		"listboxWin": WindowTheme{
 			NotActive:       tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorWhite),
 			Active:          tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorWhite),
+			Disabled:        tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorGrey),
 			Flags:           WindowFlagHasBorderBM | WindowFlagHasBothScrollsBM | WindowFlagAutoHideScrollBM,
 			NaRectStyle:     twin.CanvasRectangleSingle,
 			ActiveRectStyle: twin.CanvasRectangleDouble,
 		},
+		"editline": EditLineTheme{
+			NotActive: tcell.StyleDefault.Background(tcell.ColorDarkCyan).Foreground(tcell.ColorWhite),
+			Active:    tcell.StyleDefault.Background(tcell.ColorTeal).Foreground(tcell.ColorWhite),
+			Disabled:  tcell.StyleDefault.Background(tcell.ColorDarkCyan).Foreground(tcell.ColorGrey),
+		},
+		"checkbox": CheckBoxTheme{
+			NotActive: tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorWhite),
+			Active:    tcell.StyleDefault.Background(tcell.ColorTeal).Foreground(tcell.ColorWhite),
+			Disabled:  tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorGrey),
+		},
+		"choicebox": ChoiceBoxTheme{
+			NotActive: tcell.StyleDefault.Background(tcell.ColorDarkCyan).Foreground(tcell.ColorWhite),
+			Active:    tcell.StyleDefault.Background(tcell.ColorTeal).Foreground(tcell.ColorWhite),
+			Disabled:  tcell.StyleDefault.Background(tcell.ColorDarkCyan).Foreground(tcell.ColorGrey),
+		},
+		"form": FormTheme{
+			Style: tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorWhite),
+		},
+		"formLabel": LabelTheme{
+			Style:     tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorWhite),
+			Disabled:  tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorGrey),
+			Alignment: AllignLeft,
+		},
+		"formError": LabelTheme{
+			Style:     tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorRed),
+			Alignment: AllignLeft,
+		},
+		"logview": LogViewTheme{
+			Error: tcell.ColorRed,
+			Warn:  tcell.ColorYellow,
+			Debug: tcell.ColorSilver,
+			Trace: tcell.ColorGrey,
+			Match: tcell.StyleDefault.Background(tcell.ColorYellow).Foreground(tcell.ColorBlack),
+		},
+		"terminal": TerminalTheme{
+			Style: tcell.StyleDefault,
+		},
 	}
 }
@@ -0,0 +1,30 @@
+package components
+
+import (
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"os"
+	"testing"
+)
+
+// the tests run without the terminal
+func TestMain(m *testing.M) {
+	if err := twin.SetScreen(tcell.NewSimulationScreen("UTF-8")); err != nil {
+		panic(err)
+	}
+	_, cancel := twin.Run()
+	res := m.Run()
+	cancel()
+	<-twin.Done()
+	os.Exit(res)
+}
+
+// onTwin runs f in the twin go-routine, where the components are handled, and waits for it
+func onTwin(f func()) {
+	done := make(chan struct{})
+	twin.Invoke(func() {
+		defer close(done)
+		f()
+	})
+	<-done
+}
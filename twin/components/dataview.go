@@ -0,0 +1,214 @@
+package components
+
+import (
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/container/lru"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"sync"
+)
+
+// DataSource provides the rows for the virtualized components (see DataView), every row is
+// the list of its cells
+type DataSource interface {
+	// Len returns the number of the rows
+	Len() int
+	// Fetch requests count rows from the offset. The rows are passed to done, which may be called
+	// synchronously or later from any go-routine. The number of the rows may be less than count,
+	// if the end of the data is reached.
+	Fetch(offset, count int, done func(rows [][]string, err error))
+}
+
+// DataChangeType is the type of the DataView change
+type DataChangeType int
+
+const (
+	// DataLoaded is sent when the rows of a page are loaded, the placeholders shown for them, if any,
+	// must be redrawn
+	DataLoaded = DataChangeType(iota)
+	// DataInserted is sent when the rows are inserted to the source
+	DataInserted
+	// DataRemoved is sent when the rows are removed from the source
+	DataRemoved
+	// DataReset is sent when the source data is changed completely
+	DataReset
+)
+
+// DataChange describes the DataView change
+type DataChange struct {
+	Type DataChangeType
+	// Offset is the index of the first row changed
+	Offset int
+	// Count is the number of the rows changed
+	Count int
+}
+
+// DataListener is notified about the DataView changes
+type DataListener func(dc DataChange)
+
+// DataView keeps the rows of a DataSource in the LRU cache of the pages, so the memory it takes
+// depends on the number of the rows shown, not on the data size. The rows, which are not loaded
+// yet, are returned as not found, the components show the placeholders for them, and they are
+// redrawn when the rows are loaded.
+//
+// The data source owner must report the rows inserted or removed by Inserted() and Removed()
+// after the source is changed.
+type DataView struct {
+	ds        DataSource
+	pageSize  int
+	pages     *lru.Cache[int, *dataPage]
+	lock      sync.Mutex
+	listeners []*dataListenerHolder
+}
+
+type dataPage struct {
+	rows    [][]string
+	loaded  bool
+	failed  bool
+	dropped bool
+	// fetching is true while the Fetch call is not returned
+	fetching bool
+}
+
+type dataListenerHolder struct {
+	dl DataListener
+}
+
+// NewDataView creates the view for the ds, which keeps up to maxPages pages of pageSize rows.
+// The page size is usually about the component height, and maxPages must be at least 2, so
+// the rows shown, which are on the two pages, are kept in the cache.
+func NewDataView(ds DataSource, pageSize, maxPages int) (*DataView, error) {
+	if pageSize < 1 {
+		return nil, fmt.Errorf("the page size must be positive, but it is %d: %w", pageSize, errors.ErrInvalid)
+	}
+	dv := &DataView{ds: ds, pageSize: pageSize}
+	pages, err := lru.NewCache[int, *dataPage](maxPages, dv.newPage, dv.dropPage)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the pages cache: %s: %w", err, errors.ErrInvalid)
+	}
+	dv.pages = pages
+	return dv, nil
+}
+
+// Len returns the number of the rows in the data source
+func (dv *DataView) Len() int {
+	return dv.ds.Len()
+}
+
+// Row returns the row by its index. It returns false, if the row is out of the data or it
+// is not loaded yet. In the latter case the page with the row is requested from the source.
+func (dv *DataView) Row(idx int) ([]string, bool) {
+	if idx < 0 || idx >= dv.ds.Len() {
+		return nil, false
+	}
+	pi := idx / dv.pageSize
+	p, _ := dv.pages.GetOrCreate(pi)
+	dv.lock.Lock()
+	failed := p.failed
+	loaded := p.loaded
+	rows := p.rows
+	dv.lock.Unlock()
+	if failed {
+		// the page is requested again, when the row is needed next time
+		dv.pages.Remove(pi)
+	}
+	if !loaded || idx%dv.pageSize >= len(rows) {
+		return nil, false
+	}
+	return rows[idx%dv.pageSize], true
+}
+
+// Inserted must be called when count rows are inserted to the source at the offset. The rows
+// appended to the end keep the cached pages, the rows inserted in the middle drop them.
+func (dv *DataView) Inserted(offset, count int) {
+	if count <= 0 {
+		return
+	}
+	if offset >= dv.ds.Len()-count {
+		// the last page may be loaded not full
+		dv.pages.Remove(offset / dv.pageSize)
+	} else {
+		dv.pages.Clear()
+	}
+	dv.notify(DataChange{Type: DataInserted, Offset: offset, Count: count})
+}
+
+// Removed must be called when count rows from the offset are removed from the source
+func (dv *DataView) Removed(offset, count int) {
+	if count <= 0 {
+		return
+	}
+	dv.pages.Clear()
+	dv.notify(DataChange{Type: DataRemoved, Offset: offset, Count: count})
+}
+
+// Reset must be called when the source data is changed completely
+func (dv *DataView) Reset() {
+	dv.pages.Clear()
+	dv.notify(DataChange{Type: DataReset})
+}
+
+// AddListener registers the dl to be notified about the view changes. The listener is called from
+// the go-routine, which reports the change, or, for DataLoaded, which delivers the rows. The
+// returned function removes the listener.
+func (dv *DataView) AddListener(dl DataListener) func() {
+	h := &dataListenerHolder{dl: dl}
+	dv.lock.Lock()
+	defer dv.lock.Unlock()
+	dv.listeners = append(dv.listeners[:len(dv.listeners):len(dv.listeners)], h)
+	return func() {
+		dv.lock.Lock()
+		defer dv.lock.Unlock()
+		dls := make([]*dataListenerHolder, 0, len(dv.listeners))
+		for _, h1 := range dv.listeners {
+			if h1 != h {
+				dls = append(dls, h1)
+			}
+		}
+		dv.listeners = dls
+	}
+}
+
+func (dv *DataView) notify(dc DataChange) {
+	dv.lock.Lock()
+	dls := dv.listeners
+	dv.lock.Unlock()
+	for _, h := range dls {
+		h.dl(dc)
+	}
+}
+
+// newPage creates the page and requests its rows
+func (dv *DataView) newPage(pi int) (*dataPage, error) {
+	p := &dataPage{fetching: true}
+	offset := pi * dv.pageSize
+	dv.ds.Fetch(offset, dv.pageSize, func(rows [][]string, err error) {
+		dv.lock.Lock()
+		if p.dropped {
+			dv.lock.Unlock()
+			return
+		}
+		p.rows, p.loaded, p.failed = rows, err == nil, err != nil
+		notify := !p.fetching && p.loaded
+		dv.lock.Unlock()
+		if notify {
+			dv.notify(DataChange{Type: DataLoaded, Offset: offset, Count: len(rows)})
+		}
+	})
+	dv.lock.Lock()
+	p.fetching = false
+	// the rows could be delivered by another go-routine before Fetch returned, the page is
+	// created within Row(), which may be not called again for the rows then
+	notify, count := p.loaded, len(p.rows)
+	dv.lock.Unlock()
+	if notify {
+		dv.notify(DataChange{Type: DataLoaded, Offset: offset, Count: count})
+	}
+	return p, nil
+}
+
+func (dv *DataView) dropPage(pi int, p *dataPage) {
+	dv.lock.Lock()
+	p.dropped = true
+	p.rows = nil
+	dv.lock.Unlock()
+}
@@ -0,0 +1,217 @@
+package components
+
+import (
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+// testSource returns the rows "r<idx>", the fetch requests are recorded, and they are answered
+// synchronously, unless async is set
+type testSource struct {
+	lock    sync.Mutex
+	n       int
+	async   bool
+	err     error
+	fetches []int
+	pending []func()
+}
+
+func (ts *testSource) Len() int {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	return ts.n
+}
+
+func (ts *testSource) Fetch(offset, count int, done func(rows [][]string, err error)) {
+	ts.lock.Lock()
+	ts.fetches = append(ts.fetches, offset)
+	var rows [][]string
+	for i := offset; i < min(ts.n, offset+count); i++ {
+		rows = append(rows, []string{fmt.Sprintf("r%d", i)})
+	}
+	err, async := ts.err, ts.async
+	answer := func() { done(rows, err) }
+	if async {
+		ts.pending = append(ts.pending, answer)
+	}
+	ts.lock.Unlock()
+	if !async {
+		answer()
+	}
+}
+
+// answer delivers the pending rows
+func (ts *testSource) answer() {
+	ts.lock.Lock()
+	pending := ts.pending
+	ts.pending = nil
+	ts.lock.Unlock()
+	for _, f := range pending {
+		f()
+	}
+}
+
+func (ts *testSource) fetched() []int {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	return append([]int(nil), ts.fetches...)
+}
+
+func collectChanges(dv *DataView) *[]DataChange {
+	var res []DataChange
+	var lock sync.Mutex
+	dv.AddListener(func(dc DataChange) {
+		lock.Lock()
+		defer lock.Unlock()
+		res = append(res, dc)
+	})
+	return &res
+}
+
+func TestNewDataView(t *testing.T) {
+	_, err := NewDataView(&testSource{}, 0, 2)
+	assert.ErrorIs(t, err, errors.ErrInvalid)
+	_, err = NewDataView(&testSource{}, 10, 0)
+	assert.ErrorIs(t, err, errors.ErrInvalid)
+}
+
+func TestDataView_Paging(t *testing.T) {
+	ts := &testSource{n: 35}
+	dv, err := NewDataView(ts, 10, 2)
+	assert.Nil(t, err)
+
+	row, ok := dv.Row(5)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"r5"}, row)
+	row, ok = dv.Row(9)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"r9"}, row)
+	assert.Equal(t, []int{0}, ts.fetched())
+
+	row, ok = dv.Row(34)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"r34"}, row)
+	_, ok = dv.Row(35)
+	assert.False(t, ok)
+	_, ok = dv.Row(-1)
+	assert.False(t, ok)
+	assert.Equal(t, []int{0, 30}, ts.fetched())
+
+	// the least recently used page 0 is evicted
+	dv.Row(15)
+	assert.Equal(t, []int{0, 30, 10}, ts.fetched())
+	dv.Row(31)
+	dv.Row(0)
+	assert.Equal(t, []int{0, 30, 10, 0}, ts.fetched())
+}
+
+func TestDataView_Async(t *testing.T) {
+	ts := &testSource{n: 15, async: true}
+	dv, _ := NewDataView(ts, 10, 2)
+	changes := collectChanges(dv)
+
+	_, ok := dv.Row(12)
+	assert.False(t, ok)
+	_, ok = dv.Row(13)
+	assert.False(t, ok)
+	assert.Equal(t, []int{10}, ts.fetched())
+	assert.Empty(t, *changes)
+
+	ts.answer()
+	assert.Equal(t, []DataChange{{Type: DataLoaded, Offset: 10, Count: 5}}, *changes)
+	row, ok := dv.Row(13)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"r13"}, row)
+}
+
+// asyncSource delivers the rows from another go-routine before Fetch returns
+type asyncSource struct {
+	testSource
+}
+
+func (as *asyncSource) Fetch(offset, count int, done func(rows [][]string, err error)) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		as.testSource.Fetch(offset, count, done)
+	}()
+	wg.Wait()
+}
+
+func TestDataView_LoadedBeforeFetchReturns(t *testing.T) {
+	as := &asyncSource{testSource{n: 5}}
+	dv, _ := NewDataView(as, 10, 2)
+	changes := collectChanges(dv)
+	dv.Row(1)
+	assert.Equal(t, []DataChange{{Type: DataLoaded, Offset: 0, Count: 5}}, *changes)
+}
+
+func TestDataView_Failed(t *testing.T) {
+	ts := &testSource{n: 5, err: errors.ErrCommunication}
+	dv, _ := NewDataView(ts, 10, 2)
+	_, ok := dv.Row(1)
+	assert.False(t, ok)
+	// the failed page is requested again
+	ts.err = nil
+	row, ok := dv.Row(1)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"r1"}, row)
+	assert.Equal(t, []int{0, 0}, ts.fetched())
+}
+
+func TestDataView_InsertedRemoved(t *testing.T) {
+	ts := &testSource{n: 15}
+	dv, _ := NewDataView(ts, 10, 3)
+	changes := collectChanges(dv)
+	dv.Row(0)
+	dv.Row(10)
+
+	// the appended rows drop the last page only
+	ts.n = 17
+	dv.Inserted(15, 2)
+	row, ok := dv.Row(16)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"r16"}, row)
+	dv.Row(0)
+	assert.Equal(t, []int{0, 10, 10}, ts.fetched())
+
+	// the rows inserted in the middle drop all the pages
+	ts.n = 20
+	dv.Inserted(3, 3)
+	dv.Row(0)
+	assert.Equal(t, []int{0, 10, 10, 0}, ts.fetched())
+
+	ts.n = 18
+	dv.Removed(0, 2)
+	dv.Row(0)
+	assert.Equal(t, []int{0, 10, 10, 0, 0}, ts.fetched())
+	dv.Reset()
+	dv.Inserted(0, 0)
+	assert.Equal(t, []DataChange{
+		{Type: DataLoaded, Offset: 0, Count: 10},
+		{Type: DataLoaded, Offset: 10, Count: 5},
+		{Type: DataInserted, Offset: 15, Count: 2},
+		{Type: DataLoaded, Offset: 10, Count: 7},
+		{Type: DataInserted, Offset: 3, Count: 3},
+		{Type: DataLoaded, Offset: 0, Count: 10},
+		{Type: DataRemoved, Offset: 0, Count: 2},
+		{Type: DataLoaded, Offset: 0, Count: 10},
+		{Type: DataReset},
+	}, *changes)
+}
+
+func TestDataView_AddListener(t *testing.T) {
+	dv, _ := NewDataView(&testSource{n: 1}, 10, 2)
+	var calls1, calls2 int
+	remove := dv.AddListener(func(dc DataChange) { calls1++ })
+	dv.AddListener(func(dc DataChange) { calls2++ })
+	dv.Reset()
+	remove()
+	dv.Reset()
+	assert.Equal(t, 1, calls1)
+	assert.Equal(t, 2, calls2)
+}
@@ -3,15 +3,336 @@ package components
 import (
 	"github.com/dspasibenko/twin-go/twin"
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"strings"
+	"sync"
 )
 
+// EditLine is the one-line text input
 type EditLine struct {
 	twin.Box
-	els EditLineSettings
+	els    EditLineStyle
+	lock   sync.Mutex
+	text   []rune
+	cursor int
+	offset int
+	// selecting is true if the text between anchor and cursor is selected
+	selecting bool
+	anchor    int
 }
 
-type EditLineSettings struct {
-	style      tcell.Style
-	activeStye tcell.Style
-	curStyle   tcell.CursorStyle
+type EditLineStyle struct {
+	editline    string
+	style       *tcell.Style
+	activeStyle *tcell.Style
+	text        string
+	maxLen      int
+	rect        twin.Rectangle
+	onChange    func(el *EditLine)
+	onEnter     func(el *EditLine)
+}
+
+func (els EditLineStyle) WithEditLine(editline string) EditLineStyle {
+	els.editline = editline
+	return els
+}
+
+func (els EditLineStyle) WithStyle(style tcell.Style) EditLineStyle {
+	els.style = &style
+	return els
+}
+
+func (els EditLineStyle) WithActiveStyle(style tcell.Style) EditLineStyle {
+	els.activeStyle = &style
+	return els
+}
+
+func (els EditLineStyle) WithText(text string) EditLineStyle {
+	els.text = text
+	return els
+}
+
+// WithMaxLen limits the number of runes in the edit line, 0 means no limit
+func (els EditLineStyle) WithMaxLen(maxLen int) EditLineStyle {
+	els.maxLen = maxLen
+	return els
+}
+
+func (els EditLineStyle) WithRectangle(rect twin.Rectangle) EditLineStyle {
+	els.rect = rect
+	return els
+}
+
+// WithOnChange sets the function which is called every time the text is changed
+func (els EditLineStyle) WithOnChange(f func(el *EditLine)) EditLineStyle {
+	els.onChange = f
+	return els
+}
+
+// WithOnEnter sets the function called when Enter is pressed. If not set, the Enter
+// is passed to the owner.
+func (els EditLineStyle) WithOnEnter(f func(el *EditLine)) EditLineStyle {
+	els.onEnter = f
+	return els
+}
+
+func NewEditLine(owner twin.Component, els EditLineStyle) (*EditLine, error) {
+	if els.editline == "" {
+		els.editline = "editline"
+	}
+	el := &EditLine{els: els}
+	el.text = []rune(els.text)
+	el.cursor = len(el.text)
+	err := el.Box.Init(owner, el)
+	if err != nil {
+		return nil, err
+	}
+	el.SetOpaque(true)
+	initStyles(&el.Box, els.editline, els.style, els.activeStyle)
+	el.Box.SetBounds(els.rect)
+	return el, nil
+}
+
+func (el *EditLine) CanBeFocused() bool { return true }
+
+// Text returns the current text of the edit line
+func (el *EditLine) Text() string {
+	el.lock.Lock()
+	defer el.lock.Unlock()
+	return string(el.text)
+}
+
+// SetText replaces the text and moves the cursor to its end
+func (el *EditLine) SetText(text string) {
+	el.lock.Lock()
+	el.text = []rune(text)
+	if el.els.maxLen > 0 && len(el.text) > el.els.maxLen {
+		el.text = el.text[:el.els.maxLen]
+	}
+	el.cursor = len(el.text)
+	el.selecting = false
+	el.lock.Unlock()
+	el.changed()
+}
+
+// FormValue returns the text, so the EditLine can be used as FormInput
+func (el *EditLine) FormValue() any {
+	return el.Text()
+}
+
+// SetFormValue sets the text from a string value
+func (el *EditLine) SetFormValue(v any) error {
+	s, ok := v.(string)
+	if !ok {
+		return errFormValueType(v, s)
+	}
+	el.SetText(s)
+	return nil
+}
+
+func (el *EditLine) OnKeyPressed(ke *tcell.EventKey) bool {
+	switch twin.ClipboardKey(ke) {
+	case twin.ClipboardCopy:
+		if sel := el.Selection(); sel != "" {
+			twin.Clipboard.Copy(sel)
+		}
+		return true
+	case twin.ClipboardCut:
+		if sel := el.Selection(); sel != "" {
+			twin.Clipboard.Copy(sel)
+			el.lock.Lock()
+			el.deleteSelection()
+			el.lock.Unlock()
+			el.changed()
+		}
+		return true
+	case twin.ClipboardPaste:
+		twin.Clipboard.Paste()
+		return true
+	}
+
+	el.lock.Lock()
+	modified := false
+	shift := ke.Modifiers()&tcell.ModShift != 0
+	switch ke.Key() {
+	case tcell.KeyLeft, tcell.KeyRight, tcell.KeyHome, tcell.KeyEnd:
+		if shift && !el.selecting {
+			el.selecting, el.anchor = true, el.cursor
+		} else if !shift {
+			el.selecting = false
+		}
+	}
+	switch ke.Key() {
+	case tcell.KeyRune:
+		modified = el.deleteSelection()
+		if el.els.maxLen > 0 && len(el.text) >= el.els.maxLen {
+			break
+		}
+		el.text = append(el.text[:el.cursor], append([]rune{ke.Rune()}, el.text[el.cursor:]...)...)
+		el.cursor++
+		modified = true
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if el.deleteSelection() {
+			modified = true
+		} else if el.cursor > 0 {
+			el.text = append(el.text[:el.cursor-1], el.text[el.cursor:]...)
+			el.cursor--
+			modified = true
+		}
+	case tcell.KeyDelete:
+		if el.deleteSelection() {
+			modified = true
+		} else if el.cursor < len(el.text) {
+			el.text = append(el.text[:el.cursor], el.text[el.cursor+1:]...)
+			modified = true
+		}
+	case tcell.KeyLeft:
+		el.cursor = max(0, el.cursor-1)
+	case tcell.KeyRight:
+		el.cursor = min(len(el.text), el.cursor+1)
+	case tcell.KeyHome, tcell.KeyCtrlA:
+		el.cursor = 0
+	case tcell.KeyEnd, tcell.KeyCtrlE:
+		el.cursor = len(el.text)
+	case tcell.KeyCtrlU:
+		el.text = el.text[el.cursor:]
+		el.cursor = 0
+		el.selecting = false
+		modified = true
+	case tcell.KeyEnter:
+		el.lock.Unlock()
+		if el.els.onEnter != nil {
+			el.els.onEnter(el)
+			return true
+		}
+		return false
+	default:
+		el.lock.Unlock()
+		return false
+	}
+	el.lock.Unlock()
+	if modified {
+		el.changed()
+	} else {
+		twin.Redraw(twin.This(el))
+	}
+	return true
+}
+
+// Selection returns the selected text
+func (el *EditLine) Selection() string {
+	el.lock.Lock()
+	defer el.lock.Unlock()
+	from, to := el.selection()
+	return string(el.text[from:to])
+}
+
+// selection returns the selected runes range, it is empty if nothing is selected
+func (el *EditLine) selection() (int, int) {
+	if !el.selecting {
+		return el.cursor, el.cursor
+	}
+	return min(el.anchor, el.cursor), max(el.anchor, el.cursor)
+}
+
+// deleteSelection removes the selected text, it returns false if nothing was selected
+func (el *EditLine) deleteSelection() bool {
+	from, to := el.selection()
+	el.selecting = false
+	if from == to {
+		return false
+	}
+	el.text = append(el.text[:from], el.text[to:]...)
+	el.cursor = from
+	return true
+}
+
+// OnPaste inserts the pasted text at the cursor position, replacing the selection. The line breaks and tabs are replaced
+// by the spaces, and the text is cut if the max length is reached.
+func (el *EditLine) OnPaste(text string) bool {
+	text = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(text)
+	el.lock.Lock()
+	el.deleteSelection()
+	runes := []rune(text)
+	if el.els.maxLen > 0 {
+		runes = runes[:min(len(runes), max(0, el.els.maxLen-len(el.text)))]
+	}
+	el.text = append(el.text[:el.cursor], append(runes, el.text[el.cursor:]...)...)
+	el.cursor += len(runes)
+	el.lock.Unlock()
+	el.changed()
+	return true
+}
+
+func (el *EditLine) OnMousePressed(p twin.Point) bool {
+	el.lock.Lock()
+	x := 0
+	i := el.offset
+	for ; i < len(el.text); i++ {
+		w := runewidth.RuneWidth(el.text[i])
+		if x+w > p.X {
+			break
+		}
+		x += w
+	}
+	el.cursor = i
+	el.selecting = false
+	el.lock.Unlock()
+	twin.Redraw(twin.This(el))
+	return true
+}
+
+func (el *EditLine) OnDraw(cc *twin.CanvasContext) {
+	r := el.Bounds().Normalized()
+	active := twin.IsActive(el)
+	style := twin.EffectiveStyle(el)
+	cc.FilledRectangle(r, style)
+
+	el.lock.Lock()
+	defer el.lock.Unlock()
+	el.adjustOffset(r.Width)
+	x := 0
+	from, to := el.selection()
+	for i := el.offset; i < len(el.text) && x < r.Width; i++ {
+		s := style
+		selected := i >= from && i < to
+		if selected {
+			s = s.Reverse(true)
+		}
+		if active && i == el.cursor {
+			s = s.Reverse(!selected)
+		}
+		cc.Print(twin.Point{X: x, Y: 0}, string(el.text[i]), s)
+		x += runewidth.RuneWidth(el.text[i])
+	}
+	if active && el.cursor == len(el.text) && x < r.Width {
+		cc.Print(twin.Point{X: x, Y: 0}, " ", style.Reverse(true))
+	}
+}
+
+// adjustOffset moves the first visible rune so the cursor is always visible in the width
+func (el *EditLine) adjustOffset(width int) {
+	if width <= 0 {
+		return
+	}
+	el.offset = min(el.offset, el.cursor)
+	// the cell for the cursor at the end of the text
+	w := 1
+	if el.cursor < len(el.text) {
+		w = runewidth.RuneWidth(el.text[el.cursor])
+	}
+	for i := el.cursor - 1; i >= el.offset; i-- {
+		w += runewidth.RuneWidth(el.text[i])
+	}
+	for w > width && el.offset < el.cursor {
+		w -= runewidth.RuneWidth(el.text[el.offset])
+		el.offset++
+	}
+}
+
+func (el *EditLine) changed() {
+	if el.els.onChange != nil {
+		el.els.onChange(el)
+	}
+	twin.Redraw(twin.This(el))
 }
@@ -0,0 +1,570 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Form is the container which arranges the labeled input fields in two columns: labels
+// on the left and the inputs on the right. The inline error messages are shown under
+// the input which value is not valid. Submit and Cancel buttons are placed under the fields.
+//
+// The focus traverses the form fields in the order they were added, then the buttons. Enter
+// moves the focus to the next field.
+type Form struct {
+	twin.Box
+	fs      FormStyle
+	lock    sync.Mutex
+	fields  []*formField
+	submit  *Button
+	cancel  *Button
+	height  int
+	pending bool
+	// decode is called for the values before onSubmit, it may convert them. It returns the
+	// name of the field, which value can't be decoded, with the error.
+	decode   func(values map[string]any) (string, error)
+	ctx      context.Context
+	cancelFn context.CancelFunc
+}
+
+// FormInput is the component which can be used as an input in the Form
+type FormInput interface {
+	twin.Component
+	// FormValue returns the current value of the input
+	FormValue() any
+	// SetFormValue assigns the value to the input, it returns an error if the
+	// value type is not supported by the input
+	SetFormValue(v any) error
+	// SetBounds is used by the form to place the input
+	SetBounds(r twin.Rectangle)
+}
+
+// Validator checks the value synchronously. It returns the error which message is
+// displayed under the field, if the value is not valid
+type Validator func(v any) error
+
+// AsyncValidator checks the value in a separate go-routine. It is useful for the long-running
+// checks like network requests. The ctx is closed when the form is closed.
+type AsyncValidator func(ctx context.Context, v any) error
+
+// FormField describes the form field. It should be constructed via NewFormField()
+type FormField struct {
+	name            string
+	label           string
+	input           FormInput
+	validators      []Validator
+	asyncValidators []AsyncValidator
+}
+
+type formField struct {
+	FormField
+	lbl     *Label
+	errLbl  *Label
+	initial any
+	err     error
+	pending bool
+	gen     int
+}
+
+type FormStyle struct {
+	form       string
+	style      *tcell.Style
+	submitText string
+	cancelText string
+	rect       twin.Rectangle
+	onSubmit   func(f *Form, values map[string]any)
+	onCancel   func(f *Form)
+}
+
+func NewFormField(name string, input FormInput) FormField {
+	return FormField{name: name, label: name, input: input}
+}
+
+func (ff FormField) WithLabel(label string) FormField {
+	ff.label = label
+	return ff
+}
+
+func (ff FormField) WithValidator(v Validator) FormField {
+	ff.validators = append(ff.validators[:len(ff.validators):len(ff.validators)], v)
+	return ff
+}
+
+func (ff FormField) WithAsyncValidator(v AsyncValidator) FormField {
+	ff.asyncValidators = append(ff.asyncValidators[:len(ff.asyncValidators):len(ff.asyncValidators)], v)
+	return ff
+}
+
+func (fs FormStyle) WithForm(form string) FormStyle {
+	fs.form = form
+	return fs
+}
+
+func (fs FormStyle) WithStyle(style tcell.Style) FormStyle {
+	fs.style = &style
+	return fs
+}
+
+// WithSubmitText sets the Submit button text, the button is not created if the text is empty
+func (fs FormStyle) WithSubmitText(text string) FormStyle {
+	fs.submitText = text
+	return fs
+}
+
+// WithCancelText sets the Cancel button text, the button is not created if the text is empty
+func (fs FormStyle) WithCancelText(text string) FormStyle {
+	fs.cancelText = text
+	return fs
+}
+
+func (fs FormStyle) WithRectangle(rect twin.Rectangle) FormStyle {
+	fs.rect = rect
+	return fs
+}
+
+// WithOnSubmit sets the function which is called with the form values when all the fields are
+// valid and the form is submitted. The function is called from the twin go-routine, the async
+// validators results are delivered to it as well.
+func (fs FormStyle) WithOnSubmit(f func(f *Form, values map[string]any)) FormStyle {
+	fs.onSubmit = f
+	return fs
+}
+
+func (fs FormStyle) WithOnCancel(f func(f *Form)) FormStyle {
+	fs.onCancel = f
+	return fs
+}
+
+func NewForm(owner twin.Component, fs FormStyle) (*Form, error) {
+	if fs.form == "" {
+		fs.form = "form"
+	}
+	if fs.submitText == "" && fs.cancelText == "" {
+		fs.submitText, fs.cancelText = "[ Submit ]", "[ Cancel ]"
+	}
+	f := &Form{fs: fs}
+	f.ctx, f.cancelFn = context.WithCancel(context.Background())
+	err := f.Box.Init(owner, f)
+	if err != nil {
+		return nil, err
+	}
+	f.SetOpaque(true)
+	initStyles(&f.Box, fs.form, fs.style, nil)
+	if fs.submitText != "" {
+		f.submit, err = NewButton(f, ButtonStyle{}.WithText(fs.submitText).WithOnEnter(func(b *Button) { f.Submit() }))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if fs.cancelText != "" {
+		f.cancel, err = NewButton(f, ButtonStyle{}.WithText(fs.cancelText).WithOnEnter(func(b *Button) { f.Cancel() }))
+		if err != nil {
+			return nil, err
+		}
+	}
+	f.Box.SetBounds(fs.rect)
+	f.relayout()
+	f.updateTabOrder()
+	return f, nil
+}
+
+// AddField adds the new field to the form. The field input must be owned by the form
+// or by one of its descendants.
+func (f *Form) AddField(ff FormField) error {
+	if ff.name == "" || ff.input == nil {
+		return fmt.Errorf("the field name and input must be specified: %w", errors.ErrInvalid)
+	}
+	o := twin.Owner(ff.input)
+	for o != nil && o != twin.This(f) {
+		o = twin.Owner(o)
+	}
+	if o == nil {
+		return fmt.Errorf("the input %s of the field %q is not owned by the form: %w", ff.input, ff.name, errors.ErrInvalid)
+	}
+	lbl, err := NewLabel(f, LabelStyle{}.WithLabel(f.fs.form+"Label").WithPureText(ff.label))
+	if err != nil {
+		return err
+	}
+	errLbl, err := NewLabel(f, LabelStyle{}.WithLabel(f.fs.form+"Error"))
+	if err != nil {
+		return err
+	}
+	errLbl.SetVisible(false)
+
+	f.lock.Lock()
+	for _, fld := range f.fields {
+		if fld.name == ff.name {
+			f.lock.Unlock()
+			twin.Close(lbl)
+			twin.Close(errLbl)
+			return fmt.Errorf("the field %q already exists: %w", ff.name, errors.ErrExist)
+		}
+	}
+	f.fields = append(f.fields, &formField{FormField: ff, lbl: lbl, errLbl: errLbl, initial: ff.input.FormValue()})
+	f.lock.Unlock()
+	f.relayout()
+	f.updateTabOrder()
+	return nil
+}
+
+// Input returns the input for the field name, or nil if there is no such field
+func (f *Form) Input(name string) FormInput {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if ff := f.field(name); ff != nil {
+		return ff.input
+	}
+	return nil
+}
+
+// Values returns the current values of all the form fields
+func (f *Form) Values() map[string]any {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	res := make(map[string]any, len(f.fields))
+	for _, ff := range f.fields {
+		res[ff.name] = ff.input.FormValue()
+	}
+	return res
+}
+
+// ValuesTo assigns the form values to the struct fields with the same names. dst must be
+// a pointer to a struct. The fields which have no correspondent struct fields are skipped.
+func (f *Form) ValuesTo(dst any) error {
+	return assignValues(f.Values(), dst)
+}
+
+// IsDirty returns whether any field value was changed since the field was added or
+// since the last ResetDirty() call
+func (f *Form) IsDirty() bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, ff := range f.fields {
+		if !reflect.DeepEqual(ff.initial, ff.input.FormValue()) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFieldDirty returns whether the field name value was changed
+func (f *Form) IsFieldDirty(name string) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	ff := f.field(name)
+	return ff != nil && !reflect.DeepEqual(ff.initial, ff.input.FormValue())
+}
+
+// ResetDirty makes the current values as the initial ones
+func (f *Form) ResetDirty() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, ff := range f.fields {
+		ff.initial = ff.input.FormValue()
+	}
+}
+
+// Validate runs the synchronous validators for all the fields and starts the async ones.
+// It returns false if any of the fields is not valid or its async validation is not completed.
+func (f *Form) Validate() bool {
+	f.lock.Lock()
+	fields := f.fields
+	f.lock.Unlock()
+	ok := true
+	for _, ff := range fields {
+		ok = f.validate(ff) && ok
+	}
+	return ok
+}
+
+// Submit validates the form and calls the onSubmit function if all the values are valid.
+// If some async validations are in progress, the onSubmit will be called as soon as they are
+// completed successfully.
+func (f *Form) Submit() {
+	f.lock.Lock()
+	fields := f.fields
+	f.lock.Unlock()
+	for _, ff := range fields {
+		f.validate(ff)
+	}
+	f.lock.Lock()
+	f.pending = true
+	f.trySubmit()
+}
+
+// Cancel calls the onCancel function
+func (f *Form) Cancel() {
+	f.lock.Lock()
+	f.pending = false
+	f.lock.Unlock()
+	if f.fs.onCancel != nil {
+		f.fs.onCancel(f)
+	}
+}
+
+// Height returns the height the form needs to show all its fields and buttons
+func (f *Form) Height() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.height
+}
+
+func (f *Form) CanBeFocused() bool { return true }
+
+func (f *Form) SetBounds(r twin.Rectangle) {
+	f.Box.SetBounds(r)
+	f.relayout()
+}
+
+func (f *Form) OnDraw(cc *twin.CanvasContext) {
+	cc.FilledRectangle(f.Bounds().Normalized(), twin.EffectiveStyle(f))
+}
+
+func (f *Form) OnKeyPressed(ke *tcell.EventKey) bool {
+	switch ke.Key() {
+	case tcell.KeyTab, tcell.KeyBacktab, tcell.KeyDown, tcell.KeyUp:
+		f.validateInput(twin.Focused())
+	case tcell.KeyEnter:
+		f.validateInput(twin.Focused())
+		twin.FocusNext()
+		return true
+	}
+	return f.Box.OnKeyPressed(ke)
+}
+
+func (f *Form) OnClosed() {
+	f.cancelFn()
+}
+
+// updateTabOrder makes the focus traverse the fields in the order they were added
+// and the buttons after them
+func (f *Form) updateTabOrder() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	order := make([]twin.Component, 0, len(f.fields)+2)
+	for _, ff := range f.fields {
+		order = append(order, ff.input)
+	}
+	if f.submit != nil {
+		order = append(order, f.submit)
+	}
+	if f.cancel != nil {
+		order = append(order, f.cancel)
+	}
+	f.SetTabOrder(order)
+}
+
+// validateInput validates the field, which input is the comp or contains it
+func (f *Form) validateInput(comp twin.Component) {
+	f.lock.Lock()
+	fields := f.fields
+	f.lock.Unlock()
+	for ; comp != nil && comp != twin.This(f); comp = twin.Owner(comp) {
+		for _, ff := range fields {
+			if twin.This(ff.input) == comp {
+				f.validate(ff)
+				return
+			}
+		}
+	}
+}
+
+// validate runs the synchronous validators of ff and starts the async ones, if the value passes
+// them. The validators are called without the lock, so they may use the form, for example to
+// compare the value with another field. It returns whether the value is valid and its async
+// validation is completed.
+func (f *Form) validate(ff *formField) bool {
+	f.lock.Lock()
+	ff.gen++
+	gen := ff.gen
+	validators, asyncValidators := ff.validators, ff.asyncValidators
+	f.lock.Unlock()
+
+	v := ff.input.FormValue()
+	for _, vf := range validators {
+		if err := vf(v); err != nil {
+			f.setError(ff, gen, err)
+			return false
+		}
+	}
+	if len(asyncValidators) == 0 {
+		return f.setError(ff, gen, nil)
+	}
+	f.lock.Lock()
+	if ff.gen != gen {
+		f.lock.Unlock()
+		return false
+	}
+	ff.pending = true
+	f.lock.Unlock()
+	go func() {
+		var err error
+		for _, av := range asyncValidators {
+			if err = av(f.ctx, v); err != nil {
+				break
+			}
+		}
+		// the error label is changed in the twin go-routine
+		twin.Invoke(func() {
+			if f.setError(ff, gen, err) {
+				f.lock.Lock()
+				f.trySubmit()
+			}
+		})
+	}()
+	return false
+}
+
+// trySubmit must be called under the lock, it releases the lock
+func (f *Form) trySubmit() {
+	if !f.pending {
+		f.lock.Unlock()
+		return
+	}
+	var failed twin.Component
+	for _, ff := range f.fields {
+		if ff.pending {
+			f.lock.Unlock()
+			return
+		}
+		if ff.err != nil && failed == nil {
+			failed = ff.input
+		}
+	}
+	f.pending = false
+	f.lock.Unlock()
+	if failed != nil {
+		twin.SetActive(failed)
+		return
+	}
+	values := f.Values()
+	if f.decode != nil {
+		if name, err := f.decode(values); err != nil {
+			f.showError(name, err)
+			return
+		}
+	}
+	if f.fs.onSubmit != nil {
+		f.fs.onSubmit(f, values)
+	}
+}
+
+// showError shows the err under the field name and focuses its input
+func (f *Form) showError(name string, err error) {
+	f.lock.Lock()
+	ff := f.field(name)
+	if ff == nil {
+		f.lock.Unlock()
+		return
+	}
+	gen := ff.gen
+	f.lock.Unlock()
+	f.setError(ff, gen, err)
+	twin.SetActive(ff.input)
+}
+
+// setError records the validation result err of ff and shows it under the field. The result
+// is dropped, if ff was validated again after the validation gen was started. It returns
+// whether the result is recorded.
+func (f *Form) setError(ff *formField, gen int, err error) bool {
+	f.lock.Lock()
+	if ff.gen != gen {
+		f.lock.Unlock()
+		return false
+	}
+	ff.pending = false
+	changed := ff.err != err
+	ff.err = err
+	f.lock.Unlock()
+	if changed {
+		if err != nil {
+			ff.errLbl.SetText(err.Error())
+		}
+		ff.errLbl.SetVisible(err != nil)
+		f.relayout()
+	}
+	return true
+}
+
+func (f *Form) field(name string) *formField {
+	for _, ff := range f.fields {
+		if ff.name == name {
+			return ff
+		}
+	}
+	return nil
+}
+
+func (f *Form) relayout() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.relayoutLocked()
+}
+
+// relayoutLocked places the labels, inputs and the buttons in two columns
+func (f *Form) relayoutLocked() {
+	b := f.Bounds()
+	lw := 0
+	for _, ff := range f.fields {
+		lw = max(lw, runewidth.StringWidth(ff.label))
+	}
+	lw = min(lw+1, b.Width/2)
+	iw := max(0, b.Width-lw)
+	y := 0
+	for _, ff := range f.fields {
+		h := max(1, ff.input.Bounds().Height)
+		ff.lbl.SetBounds(twin.Rectangle{X: 0, Y: y, Width: lw, Height: 1})
+		ff.input.SetBounds(twin.Rectangle{X: lw, Y: y, Width: iw, Height: h})
+		y += h
+		if ff.err != nil {
+			ff.errLbl.SetBounds(twin.Rectangle{X: lw, Y: y, Width: iw, Height: 1})
+			y++
+		}
+	}
+	if f.submit != nil || f.cancel != nil {
+		y++
+		x := lw
+		for _, btn := range []*Button{f.submit, f.cancel} {
+			if btn == nil {
+				continue
+			}
+			w := runewidth.StringWidth(btn.bs.text)
+			btn.SetBounds(twin.Rectangle{X: x, Y: y, Width: w, Height: 1})
+			x += w + 1
+		}
+		y++
+	}
+	f.height = y
+}
+
+func errFormValueType(v, expected any) error {
+	return fmt.Errorf("the value %v of type %T cannot be assigned, %T is expected: %w", v, v, expected, errors.ErrInvalid)
+}
+
+// assignValues sets the struct fields, which dst points to, by the values of the map
+func assignValues(values map[string]any, dst any) error {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("the destination must be a pointer to a struct, but %T: %w", dst, errors.ErrInvalid)
+	}
+	rv = rv.Elem()
+	for name, v := range values {
+		fv := rv.FieldByNameFunc(func(fn string) bool { return strings.EqualFold(fn, name) })
+		if !fv.IsValid() || !fv.CanSet() || v == nil {
+			continue
+		}
+		vv := reflect.ValueOf(v)
+		if !vv.Type().ConvertibleTo(fv.Type()) {
+			return fmt.Errorf("the field %q value of %T cannot be assigned to %s: %w", name, v, fv.Type(), errors.ErrInvalid)
+		}
+		fv.Set(vv.Convert(fv.Type()))
+	}
+	return nil
+}
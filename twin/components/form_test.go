@@ -0,0 +1,174 @@
+package components
+
+import (
+	"context"
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestForm(t *testing.T, fs FormStyle) *Form {
+	f, err := NewForm(twin.Root(), fs.WithRectangle(twin.Rectangle{Width: 40, Height: 10}))
+	assert.Nil(t, err)
+	t.Cleanup(func() { twin.Close(f) })
+	return f
+}
+
+func addEditField(t *testing.T, f *Form, name string, ff func(ff FormField) FormField) *EditLine {
+	el, err := NewEditLine(f, EditLineStyle{}.WithRectangle(twin.Rectangle{Width: 10, Height: 1}))
+	assert.Nil(t, err)
+	assert.Nil(t, f.AddField(ff(NewFormField(name, el))))
+	return el
+}
+
+func notEmpty(v any) error {
+	if v == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	return nil
+}
+
+func TestForm_AddField(t *testing.T) {
+	onTwin(func() {
+		f := newTestForm(t, FormStyle{})
+		el := addEditField(t, f, "name", func(ff FormField) FormField { return ff })
+		assert.ErrorIs(t, f.AddField(NewFormField("name", el)), errors.ErrExist)
+		assert.ErrorIs(t, f.AddField(NewFormField("", el)), errors.ErrInvalid)
+		other, _ := NewEditLine(twin.Root(), EditLineStyle{})
+		defer twin.Close(other)
+		assert.ErrorIs(t, f.AddField(NewFormField("other", other)), errors.ErrInvalid)
+		assert.Equal(t, FormInput(el), f.Input("name"))
+		assert.Nil(t, f.Input("other"))
+		// the field and the buttons under it
+		assert.Equal(t, 3, f.Height())
+	})
+}
+
+func TestForm_Validate(t *testing.T) {
+	onTwin(func() {
+		f := newTestForm(t, FormStyle{})
+		el := addEditField(t, f, "name", func(ff FormField) FormField { return ff.WithValidator(notEmpty) })
+		assert.False(t, f.Validate())
+		ff := f.field("name")
+		assert.True(t, ff.errLbl.IsVisible())
+		assert.Equal(t, "must not be empty", ff.errLbl.Text())
+		assert.Equal(t, 4, f.Height())
+
+		el.SetText("John")
+		assert.True(t, f.Validate())
+		assert.False(t, ff.errLbl.IsVisible())
+		assert.Equal(t, 3, f.Height())
+	})
+}
+
+func TestForm_CrossFieldValidator(t *testing.T) {
+	onTwin(func() {
+		f := newTestForm(t, FormStyle{})
+		pwd := addEditField(t, f, "password", func(ff FormField) FormField { return ff })
+		confirm := addEditField(t, f, "confirm", func(ff FormField) FormField {
+			// the validator reads the form, so it must be called without the form lock
+			return ff.WithValidator(func(v any) error {
+				if v != f.Values()["password"] {
+					return fmt.Errorf("the passwords don't match")
+				}
+				return nil
+			})
+		})
+		pwd.SetText("secret")
+		confirm.SetText("secreT")
+		assert.False(t, f.Validate())
+		assert.Equal(t, "the passwords don't match", f.field("confirm").errLbl.Text())
+		confirm.SetText("secret")
+		assert.True(t, f.Validate())
+	})
+}
+
+func TestForm_Submit(t *testing.T) {
+	var submitted []map[string]any
+	var f *Form
+	onTwin(func() {
+		f = newTestForm(t, FormStyle{}.WithOnSubmit(func(f *Form, values map[string]any) {
+			submitted = append(submitted, values)
+		}))
+		addEditField(t, f, "name", func(ff FormField) FormField { return ff.WithValidator(notEmpty) })
+		f.Submit()
+		assert.Empty(t, submitted)
+
+		assert.Nil(t, f.Input("name").SetFormValue("John"))
+		assert.True(t, f.IsDirty())
+		assert.True(t, f.IsFieldDirty("name"))
+		f.Submit()
+		assert.Equal(t, []map[string]any{{"name": "John"}}, submitted)
+
+		var dst struct{ Name string }
+		assert.Nil(t, f.ValuesTo(&dst))
+		assert.Equal(t, "John", dst.Name)
+		f.ResetDirty()
+		assert.False(t, f.IsDirty())
+	})
+}
+
+func TestForm_AsyncValidator(t *testing.T) {
+	release := make(chan error)
+	var submitted int
+	var f *Form
+	onTwin(func() {
+		f = newTestForm(t, FormStyle{}.WithOnSubmit(func(f *Form, values map[string]any) { submitted++ }))
+		addEditField(t, f, "name", func(ff FormField) FormField {
+			return ff.WithAsyncValidator(func(ctx context.Context, v any) error { return <-release })
+		})
+		f.Submit()
+	})
+	release <- fmt.Errorf("the name is taken")
+	assert.Eventually(t, func() bool {
+		var shown bool
+		onTwin(func() { shown = f.field("name").errLbl.IsVisible() })
+		return shown
+	}, time.Second, time.Millisecond)
+	onTwin(func() {
+		assert.Equal(t, 0, submitted)
+		assert.Equal(t, "the name is taken", f.field("name").errLbl.Text())
+		f.Submit()
+	})
+	release <- nil
+	assert.Eventually(t, func() bool {
+		var n int
+		onTwin(func() { n = submitted })
+		return n == 1
+	}, time.Second, time.Millisecond)
+	onTwin(func() { assert.False(t, f.field("name").errLbl.IsVisible()) })
+}
+
+// compositeInput is the input, which value is edited by its child
+type compositeInput struct {
+	twin.Box
+	el *EditLine
+}
+
+func (ci *compositeInput) CanBeFocused() bool { return true }
+
+func (ci *compositeInput) FormValue() any { return ci.el.FormValue() }
+
+func (ci *compositeInput) SetFormValue(v any) error { return ci.el.SetFormValue(v) }
+
+func TestForm_ValidateNested(t *testing.T) {
+	var f *Form
+	var ci *compositeInput
+	onTwin(func() {
+		f = newTestForm(t, FormStyle{})
+		ci = &compositeInput{}
+		assert.Nil(t, ci.Init(f, ci))
+		ci.el, _ = NewEditLine(ci, EditLineStyle{}.WithRectangle(twin.Rectangle{Width: 10, Height: 1}))
+		assert.Nil(t, f.AddField(NewFormField("name", ci).WithValidator(notEmpty)))
+		twin.Focus(ci.el)
+	})
+	onTwin(func() {
+		assert.Equal(t, twin.Component(ci.el), twin.Focused())
+		f.OnKeyPressed(tcell.NewEventKey(tcell.KeyTab, 0, tcell.ModNone))
+		assert.True(t, f.field("name").errLbl.IsVisible())
+	})
+}
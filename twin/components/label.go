@@ -3,8 +3,6 @@ package components
 import (
 	"github.com/dspasibenko/twin-go/twin"
 	"github.com/gdamore/tcell/v2"
-	"github.com/mattn/go-runewidth"
-	"strings"
 	"sync"
 	"sync/atomic"
 )
@@ -17,15 +15,27 @@ type Label struct {
 }
 
 type labelLines struct {
-	lines   []string
-	offsets []twin.Point
+	lines []TextLine
+	ver   int64
 }
 
 type LabelStyle struct {
+	label      string
 	allignment *TextAlignment
 	style      *tcell.Style
 	pureText   string
+	markup     bool
 	rect       twin.Rectangle
+	wrap       WrapMode
+	ellipsis   string
+	indent     bool
+	vAlignment VerticalAlignment
+	selectable bool
+}
+
+func (ls LabelStyle) WithLabel(label string) LabelStyle {
+	ls.label = label
+	return ls
 }
 
 func (ls LabelStyle) WithAlignment(allignment TextAlignment) LabelStyle {
@@ -43,41 +53,91 @@ func (ls LabelStyle) WithPureText(pureText string) LabelStyle {
 	return ls
 }
 
-func (ls LabelStyle) WithRectangle(r twin.Rectangle) LabelStyle {
-	ls.rect = r
+// WithMarkup sets the label text with the markup tags (see twin.ParseMarkup)
+func (ls LabelStyle) WithMarkup(markup string) LabelStyle {
+	ls.pureText = markup
+	ls.markup = true
 	return ls
 }
 
-func (ls LabelStyle) Style() tcell.Style {
-	if ls.style != nil {
-		return *ls.style
-	}
-	lbs := GetThemeValue[LabelTheme]("label")
-	return lbs.Style
+// WithWrap sets how the lines longer than the label width are wrapped, the default is WrapNone
+func (ls LabelStyle) WithWrap(wrap WrapMode) LabelStyle {
+	ls.wrap = wrap
+	return ls
+}
+
+// WithEllipsis sets the string, which marks the cut text, for example "…"
+func (ls LabelStyle) WithEllipsis(ellipsis string) LabelStyle {
+	ls.ellipsis = ellipsis
+	return ls
+}
+
+// WithPreserveIndent keeps the leading spaces of the lines, instead of trimming them
+func (ls LabelStyle) WithPreserveIndent(indent bool) LabelStyle {
+	ls.indent = indent
+	return ls
+}
+
+func (ls LabelStyle) WithVerticalAlignment(va VerticalAlignment) LabelStyle {
+	ls.vAlignment = va
+	return ls
+}
+
+// WithSelectable allows the label to be focused, the focused label text is selected and may
+// be copied to the clipboard (see twin.ClipboardKey)
+func (ls LabelStyle) WithSelectable(selectable bool) LabelStyle {
+	ls.selectable = selectable
+	return ls
+}
+
+func (ls LabelStyle) WithRectangle(r twin.Rectangle) LabelStyle {
+	ls.rect = r
+	return ls
 }
 
 func (ls LabelStyle) Allignment() TextAlignment {
 	if ls.allignment != nil {
 		return *ls.allignment
 	}
-	lbs := GetThemeValue[LabelTheme]("label")
+	lbs := GetThemeValue[LabelTheme](ls.label)
 	return lbs.Alignment
 }
 
 func NewLabel(owner twin.Component, ls LabelStyle) (*Label, error) {
+	if ls.label == "" {
+		ls.label = "label"
+	}
 	l := &Label{}
 	l.ls = ls
 	err := l.Init(owner, l)
 	if err != nil {
 		return nil, err
 	}
+	l.SetOpaque(true)
+	initStyles(&l.Box, ls.label, ls.style, nil)
 	l.Box.SetBounds(ls.rect)
 	l.SetText(ls.pureText)
 	return l, nil
 }
 
 func (l *Label) CanBeFocused() bool {
-	return false
+	return l.ls.selectable
+}
+
+// OnKeyPressed copies the text of the selectable label to the clipboard
+func (l *Label) OnKeyPressed(ke *tcell.EventKey) bool {
+	if l.ls.selectable && twin.ClipboardKey(ke) == twin.ClipboardCopy {
+		twin.Clipboard.Copy(l.Text())
+		return true
+	}
+	return l.Box.OnKeyPressed(ke)
+}
+
+// Text returns the label text without the markup tags
+func (l *Label) Text() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.text().String()
 }
 
 func (l *Label) SetBounds(r twin.Rectangle) {
@@ -86,41 +146,71 @@ func (l *Label) SetBounds(r twin.Rectangle) {
 }
 
 func (l *Label) SetText(text string) {
+	l.lock.Lock()
+	l.ls.markup = false
+	l.lock.Unlock()
 	l.setText(text, l.Bounds())
+	twin.Redraw(twin.This(l))
+}
+
+// SetMarkup sets the text with the markup tags (see twin.ParseMarkup)
+func (l *Label) SetMarkup(markup string) {
+	l.lock.Lock()
+	l.ls.markup = true
+	l.lock.Unlock()
+	l.setText(markup, l.Bounds())
+	twin.Redraw(twin.This(l))
 }
 
 func (l *Label) OnDraw(cc *twin.CanvasContext) {
 	b := l.Bounds().Normalized()
-	stl := l.ls.Style()
+	stl := twin.EffectiveStyle(l)
 	cc.FilledRectangle(b, stl)
 	ll := l.ll.Load().(labelLines)
-	for i, line := range ll.lines {
-		if i > b.Height {
-			break
-		}
-		cc.Print(ll.offsets[i], line, stl)
+	if ll.ver != themeVersion.Load() {
+		l.setText(l.ls.pureText, l.Bounds())
+		ll = l.ll.Load().(labelLines)
 	}
+	if l.ls.selectable && twin.IsActive(l) {
+		// the whole text of the focused label is selected
+		stl = stl.Reverse(true)
+	}
+	for _, line := range ll.lines {
+		cc.PrintText(line.Offset, line.Text, stl)
+	}
+}
+
+// MeasureHeight returns the number of lines the label text takes with the width
+func (l *Label) MeasureHeight(width int) int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	tl := l.layout(twin.Rectangle{Width: width})
+	return tl.Measure(l.text())
 }
 
 func (l *Label) setText(text string, b twin.Rectangle) {
 	l.lock.Lock()
 	defer l.lock.Unlock()
-	ll := labelLines{}
-	allignment := l.ls.Allignment()
-	lines := strings.Split(text, "\n")
-	for i, line := range lines {
-		ln := strings.TrimSpace(line)
-		ln = runewidth.Truncate(ln, b.Width, "")
-		w := runewidth.StringWidth(ln)
-		ll.lines = append(ll.lines, ln)
-		switch allignment {
-		case AllignLeft:
-			ll.offsets = append(ll.offsets, twin.Point{X: 0, Y: i})
-		case AllignRight:
-			ll.offsets = append(ll.offsets, twin.Point{X: b.Width - w, Y: i})
-		case AllignCenter:
-			ll.offsets = append(ll.offsets, twin.Point{X: max(0, (b.Width-w)/2), Y: i})
-		}
+	l.ls.pureText = text
+	tl := l.layout(b)
+	l.ll.Store(labelLines{lines: tl.Layout(l.text()), ver: themeVersion.Load()})
+}
+
+func (l *Label) text() twin.Text {
+	if l.ls.markup {
+		return twin.ParseMarkup(l.ls.pureText)
+	}
+	return twin.PlainText(l.ls.pureText)
+}
+
+func (l *Label) layout(b twin.Rectangle) TextLayout {
+	return TextLayout{
+		Width:          b.Width,
+		Height:         b.Height,
+		Wrap:           l.ls.wrap,
+		Ellipsis:       l.ls.ellipsis,
+		PreserveIndent: l.ls.indent,
+		Alignment:      l.ls.Allignment(),
+		VAlignment:     l.ls.vAlignment,
 	}
-	l.ll.Store(ll)
 }
@@ -11,13 +11,25 @@ type ListBox struct {
 	ScrollableBox
 	lbs      ListBoxStyle
 	selected int
+	dv       *DataView
+	// unsubscribe removes the DataView listener
+	unsubscribe func()
+	// formatRow makes the text of the DataView row, by default the first cell is shown
+	formatRow func(row []string) twin.Text
+	// header is shown above the rows, if it is not empty
+	header      twin.Text
+	headerStyle *tcell.Style
 }
 
+// rowPlaceholder is shown for the rows, which are not loaded yet
+const rowPlaceholder = "…"
+
 type ListBoxStyle struct {
 	ScrollableBoxStyle
 	listbox   string
 	selStyle  *tcell.Style
 	selActive *tcell.Style
+	markup    bool
 }
 
 func (lbs ListBoxStyle) WithListbox(lb string) ListBoxStyle {
@@ -25,6 +37,12 @@ func (lbs ListBoxStyle) WithListbox(lb string) ListBoxStyle {
 	return lbs
 }
 
+// WithMarkup makes the list box to parse the items markup tags (see twin.ParseMarkup)
+func (lbs ListBoxStyle) WithMarkup(markup bool) ListBoxStyle {
+	lbs.markup = markup
+	return lbs
+}
+
 func (lbs ListBoxStyle) WithSelStyle(ss tcell.Style) ListBoxStyle {
 	lbs.selStyle = &ss
 	return lbs
@@ -44,7 +62,7 @@ func (lbs ListBoxStyle) WithSelActiveStyle(sas tcell.Style) ListBoxStyle {
 }
 
 func (lbs ListBoxStyle) SelActiveStyle() tcell.Style {
-	if lbs.selStyle != nil {
+	if lbs.selActive != nil {
 		return *lbs.selActive
 	}
 	lbt := GetThemeValue[ListBoxTheme](lbs.listbox)
@@ -64,27 +82,37 @@ func (lb *ListBox) Init(owner, this twin.Component, lbs ListBoxStyle) error {
 func (lb *ListBox) OnDraw(cc *twin.CanvasContext) {
 	lb.ScrollableBox.OnDraw(cc)
 	b := lb.listBounds()
+	if len(lb.header) > 0 {
+		hs := twin.EffectiveStyle(lb).Bold(true)
+		if lb.headerStyle != nil {
+			hs = *lb.headerStyle
+		}
+		cc.PrintTextL(twin.Point{X: b.X, Y: b.Y - 1}, lb.header, b.Width, hs)
+	}
 	idx := lb.VirtualOffset().Y
-	vs := lb.VirtualSize()
-	for y := b.Y; y <= b.Height; y++ {
-		if idx >= vs.Height {
+	n := lb.count()
+	for y := b.Y; y < b.Y+b.Height; y++ {
+		if idx >= n {
 			break
 		}
 		var s tcell.Style
-		str := lb.Line(idx)
+		txt, loaded := lb.rowText(idx)
 		if lb.selected == idx {
 			if twin.IsActive(lb) {
 				s = lb.lbs.SelActiveStyle()
 			} else {
 				s = lb.lbs.SelStyle()
 			}
-			if len(str) < b.Width {
-				str += strings.Repeat(" ", b.Width-len(str))
+			if w := txt.Width(); w < b.Width {
+				txt = append(txt, twin.Span{Text: strings.Repeat(" ", b.Width-w)})
 			}
 		} else {
-			s = lb.lbs.Style()
+			s = twin.EffectiveStyle(lb)
+		}
+		if !loaded {
+			s = s.Dim(true)
 		}
-		cc.PrintL(twin.Point{b.X, y}, str, b.Width, s)
+		cc.PrintTextL(twin.Point{X: b.X, Y: y}, txt, b.Width, s)
 		idx++
 	}
 }
@@ -94,8 +122,7 @@ func (lb *ListBox) OnMousePressed(p twin.Point) bool {
 	if b.Contains(p) {
 		idx := p.Y - b.Y
 		idx += lb.VirtualOffset().Y
-		vs := lb.VirtualSize()
-		if idx < vs.Height && idx != lb.selected {
+		if idx < lb.count() && idx != lb.selected {
 			lb.selected = idx
 			twin.Redraw(twin.This(lb))
 		}
@@ -105,7 +132,11 @@ func (lb *ListBox) OnMousePressed(p twin.Point) bool {
 }
 
 func (lb *ListBox) OnKeyPressed(ke *tcell.EventKey) bool {
-	vs := lb.VirtualSize()
+	if twin.ClipboardKey(ke) == twin.ClipboardCopy {
+		lb.copySelected()
+		return true
+	}
+	n := lb.count()
 	b := lb.listBounds()
 	idx := lb.selected
 	switch ke.Key() {
@@ -120,10 +151,10 @@ func (lb *ListBox) OnKeyPressed(ke *tcell.EventKey) bool {
 	case tcell.KeyHome:
 		idx = 0
 	case tcell.KeyEnd:
-		idx = vs.Height - 1
+		idx = n - 1
 	}
 	if idx != lb.selected {
-		idx2 := max(0, min(idx, vs.Height-1))
+		idx2 := max(0, min(idx, n-1))
 		if lb.selected != idx2 {
 			lb.selected = idx2
 			vo := lb.VirtualOffset()
@@ -143,9 +174,130 @@ func (lb *ListBox) OnKeyPressed(ke *tcell.EventKey) bool {
 }
 
 func (lb *ListBox) Line(idx int) string {
+	if lb.dv != nil {
+		if row, ok := lb.dv.Row(idx); ok && len(row) > 0 {
+			return row[0]
+		}
+		return ""
+	}
 	return fmt.Sprintf("%d", idx)
 }
 
+// rowText returns the text of the row idx, and false, if the row is not loaded yet
+func (lb *ListBox) rowText(idx int) (twin.Text, bool) {
+	if lb.dv != nil && lb.formatRow != nil {
+		row, ok := lb.dv.Row(idx)
+		if !ok {
+			return twin.PlainText(rowPlaceholder), false
+		}
+		return lb.formatRow(row), true
+	}
+	if lb.dv != nil {
+		if _, ok := lb.dv.Row(idx); !ok {
+			return twin.PlainText(rowPlaceholder), false
+		}
+	}
+	if lb.lbs.markup {
+		return twin.ParseMarkup(lb.Line(idx)), true
+	}
+	return twin.PlainText(lb.Line(idx)), true
+}
+
+// copySelected copies the selected row to the clipboard, the DataView row is copied as the tab
+// separated cells
+func (lb *ListBox) copySelected() {
+	if lb.dv == nil {
+		twin.Clipboard.Copy(lb.Line(lb.selected))
+		return
+	}
+	if row, ok := lb.dv.Row(lb.selected); ok {
+		twin.Clipboard.CopyRows([][]string{row})
+	}
+}
+
+// Selected returns the index of the selected row
+func (lb *ListBox) Selected() int {
+	return lb.selected
+}
+
+// SetDataView makes the list box show the rows of the dv. Only the rows shown are requested
+// from the data source, and the list box is redrawn when they are loaded. When the rows are
+// inserted or removed above the shown ones, the list is scrolled, so the same rows stay on
+// the screen, unless it is scrolled to the top.
+func (lb *ListBox) SetDataView(dv *DataView) {
+	if lb.unsubscribe != nil {
+		lb.unsubscribe()
+		lb.unsubscribe = nil
+	}
+	lb.dv = dv
+	if dv != nil {
+		lb.unsubscribe = dv.AddListener(func(dc DataChange) {
+			twin.Invoke(func() { lb.onDataChange(dc) })
+		})
+	}
+	lb.onDataChange(DataChange{Type: DataReset})
+}
+
+func (lb *ListBox) onDataChange(dc DataChange) {
+	if lb.dv == nil {
+		return
+	}
+	vo := lb.VirtualOffset()
+	switch dc.Type {
+	case DataInserted:
+		if vo.Y > 0 && dc.Offset <= vo.Y {
+			vo.Y += dc.Count
+		}
+		if lb.selected >= dc.Offset {
+			lb.selected += dc.Count
+		}
+	case DataRemoved:
+		if dc.Offset < vo.Y {
+			vo.Y -= min(dc.Count, vo.Y-dc.Offset)
+		}
+		if lb.selected >= dc.Offset+dc.Count {
+			lb.selected -= dc.Count
+		} else if lb.selected >= dc.Offset {
+			lb.selected = dc.Offset
+		}
+	}
+	n := lb.dv.Len()
+	lb.selected = max(0, min(lb.selected, n-1))
+	vs := lb.VirtualSize()
+	if h := n + lb.headerLines(); vs.Height != h {
+		vs.Height = h
+		lb.SetVirtualSize(vs)
+	}
+	if vo != lb.VirtualOffset() {
+		lb.SetVirtualOffset(vo)
+	}
+	twin.Redraw(twin.This(lb))
+}
+
+func (lb *ListBox) OnClosed() {
+	if lb.unsubscribe != nil {
+		lb.unsubscribe()
+	}
+	lb.ScrollableBox.OnClosed()
+}
+
+// count returns the number of the rows
+func (lb *ListBox) count() int {
+	if lb.dv != nil {
+		return lb.dv.Len()
+	}
+	return max(0, lb.VirtualSize().Height-lb.headerLines())
+}
+
+// headerLines returns the number of the lines the header takes, the virtual height includes
+// them, so the last row can be scrolled to
+func (lb *ListBox) headerLines() int {
+	if len(lb.header) > 0 {
+		return 1
+	}
+	return 0
+}
+
 func (lb *ListBox) listBounds() twin.Rectangle {
 	b := lb.Bounds().Normalized()
 	if lb.HasBorder() {
@@ -159,5 +311,7 @@ func (lb *ListBox) listBounds() twin.Rectangle {
 			b.Height--
 		}
 	}
+	b.Y += lb.headerLines()
+	b.Height = max(0, b.Height-lb.headerLines())
 	return b
 }
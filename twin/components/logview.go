@@ -0,0 +1,507 @@
+package components
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/container"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/pkg/golibs/logging"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/uniseg"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LogView shows the last lines of a log. The lines may be added from any go-routine by Append(),
+// Write() or ReadFrom(). LogView is io.Writer, so it may be the logging output as well (see
+// logging.SetOutput). In the follow mode, the view is scrolled to the last line when a line is
+// added, the mode is paused when the view is scrolled up, and it is resumed when the view is
+// scrolled to the end. The lines are colored by their levels (see LogViewTheme).
+type LogView struct {
+	ScrollableBox
+	lvs  LogViewStyle
+	lock sync.Mutex
+	// the fields below are protected by the lock
+	lines container.RingBuffer[logLine]
+	// partial is the end of the written data, which is not a full line yet
+	partial []byte
+	follow  bool
+	search  *regexp.Regexp
+	// searchFollow is the follow mode before the search, it is restored when the search is off
+	searchFollow bool
+	// match is the index of the line with the current match, or -1
+	match int
+	// width is the width of the longest line, it is recalculated by update(), if widthStale
+	width      int
+	widthStale bool
+	// dropped is the number of the first lines dropped since the last update
+	dropped int
+	// updating is true while the update is posted
+	updating atomic.Bool
+}
+
+type LogViewStyle struct {
+	ScrollableBoxStyle
+	logview  string
+	maxLines int
+}
+
+type logLine struct {
+	text  string
+	width int
+	level logging.Level
+	// hasLevel is false if the line has no level
+	hasLevel bool
+}
+
+// defaultLogLines is the default number of the lines kept by LogView
+const defaultLogLines = 10000
+
+var levelRegexp = regexp.MustCompile(`\b(ERROR|ERR|FATAL|PANIC|WARN|WARNING|INFO|DEBUG|TRACE)\b`)
+
+func (lvs LogViewStyle) WithLogview(logview string) LogViewStyle {
+	lvs.logview = logview
+	return lvs
+}
+
+// WithMaxLines sets the number of the last lines kept, the older lines are dropped
+func (lvs LogViewStyle) WithMaxLines(maxLines int) LogViewStyle {
+	lvs.maxLines = maxLines
+	return lvs
+}
+
+func (lvs LogViewStyle) theme() LogViewTheme {
+	return GetThemeValue[LogViewTheme](lvs.logview)
+}
+
+// NewLogView creates the log view in the follow mode
+func NewLogView(owner twin.Component, lvs LogViewStyle) (*LogView, error) {
+	if lvs.logview == "" {
+		lvs.logview = "logview"
+	}
+	if lvs.maxLines <= 0 {
+		lvs.maxLines = defaultLogLines
+	}
+	lv := &LogView{lvs: lvs, follow: true, match: -1}
+	lv.lines = container.NewRingBuffer[logLine](uint(lvs.maxLines))
+	if err := lv.ScrollableBox.Init(owner, lv, lvs.ScrollableBoxStyle); err != nil {
+		return nil, err
+	}
+	return lv, nil
+}
+
+// Append adds the text to the end of the log, the text may contain several lines
+func (lv *LogView) Append(text string) {
+	lv.lock.Lock()
+	for _, s := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		lv.appendLine(s)
+	}
+	lv.lock.Unlock()
+	lv.postUpdate()
+}
+
+// Write adds the lines to the log, the last line is added when its end is written
+func (lv *LogView) Write(p []byte) (int, error) {
+	lv.lock.Lock()
+	lv.partial = append(lv.partial, p...)
+	added := false
+	for {
+		i := bytes.IndexByte(lv.partial, '\n')
+		if i < 0 {
+			break
+		}
+		lv.appendLine(string(lv.partial[:i]))
+		lv.partial = lv.partial[i+1:]
+		added = true
+	}
+	if len(lv.partial) == 0 {
+		lv.partial = nil
+	}
+	lv.lock.Unlock()
+	if added {
+		lv.postUpdate()
+	}
+	return len(p), nil
+}
+
+// ReadFrom adds the lines read from r till its end or an error. It blocks, so it is usually
+// called in a separate go-routine.
+func (lv *LogView) ReadFrom(r io.Reader) (int64, error) {
+	var n int64
+	buf := make([]byte, 4096)
+	for {
+		m, err := r.Read(buf)
+		if m > 0 {
+			n += int64(m)
+			_, _ = lv.Write(buf[:m])
+		}
+		if err == io.EOF {
+			lv.flush()
+			return n, nil
+		}
+		if err != nil {
+			lv.flush()
+			return n, err
+		}
+	}
+}
+
+// flush adds the not finished line
+func (lv *LogView) flush() {
+	lv.lock.Lock()
+	if len(lv.partial) == 0 {
+		lv.lock.Unlock()
+		return
+	}
+	lv.appendLine(string(lv.partial))
+	lv.partial = nil
+	lv.lock.Unlock()
+	lv.postUpdate()
+}
+
+// Clear removes all the lines
+func (lv *LogView) Clear() {
+	lv.lock.Lock()
+	lv.lines.Clear()
+	lv.partial = nil
+	lv.match = -1
+	lv.width, lv.widthStale = 0, false
+	lv.dropped = 0
+	lv.lock.Unlock()
+	twin.Invoke(func() {
+		lv.SetVirtualOffset(twin.Point{})
+		lv.update()
+	})
+}
+
+// Len returns the number of the lines
+func (lv *LogView) Len() int {
+	lv.lock.Lock()
+	defer lv.lock.Unlock()
+	return lv.lines.Len()
+}
+
+// SetFollow turns the follow mode on or off, in the follow mode the last line is shown
+func (lv *LogView) SetFollow(follow bool) {
+	lv.lock.Lock()
+	lv.follow = follow
+	lv.lock.Unlock()
+	lv.postUpdate()
+}
+
+// IsFollowing returns whether the view is in the follow mode
+func (lv *LogView) IsFollowing() bool {
+	lv.lock.Lock()
+	defer lv.lock.Unlock()
+	return lv.follow
+}
+
+// Search sets the regular expression to be found and highlighted, and moves to its first
+// match from the top shown line. The empty pattern turns the search off and restores the follow
+// mode, which was before the search. It returns whether the match is found.
+func (lv *LogView) Search(pattern string) (bool, error) {
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			return false, fmt.Errorf("could not compile the search pattern %q: %s: %w", pattern, err, errors.ErrInvalid)
+		}
+	}
+	lv.lock.Lock()
+	if lv.search == nil {
+		lv.searchFollow = lv.follow
+	}
+	searched := lv.search != nil
+	lv.search = re
+	lv.match = -1
+	if re == nil && searched {
+		lv.follow = lv.searchFollow
+	}
+	lv.lock.Unlock()
+	if re == nil {
+		lv.postUpdate()
+		return false, nil
+	}
+	twin.Redraw(twin.This(lv))
+	return lv.find(lv.VirtualOffset().Y, 1), nil
+}
+
+// SearchNext moves to the next match, it returns false if there is no one
+func (lv *LogView) SearchNext() bool {
+	return lv.findNext(1)
+}
+
+// SearchPrev moves to the previous match, it returns false if there is no one
+func (lv *LogView) SearchPrev() bool {
+	return lv.findNext(-1)
+}
+
+func (lv *LogView) findNext(dir int) bool {
+	lv.lock.Lock()
+	from := lv.match
+	lv.lock.Unlock()
+	if from < 0 {
+		from = lv.VirtualOffset().Y
+	} else {
+		from += dir
+	}
+	return lv.find(from, dir)
+}
+
+// find looks for the search match from the line from in the direction dir, and shows it
+func (lv *LogView) find(from, dir int) bool {
+	lv.lock.Lock()
+	if lv.search == nil {
+		lv.lock.Unlock()
+		return false
+	}
+	idx, col := -1, 0
+	for i := from; i >= 0 && i < lv.lines.Len(); i += dir {
+		if loc := lv.search.FindStringIndex(lv.lines.At(i).text); loc != nil {
+			idx, col = i, twin.StringWidth(lv.lines.At(i).text[:loc[0]])
+			break
+		}
+	}
+	if idx < 0 {
+		lv.lock.Unlock()
+		return false
+	}
+	lv.match = idx
+	lv.follow = false
+	lv.lock.Unlock()
+
+	visibleSize := lv.ChildrenCanvasBounds().Size()
+	offset := lv.VirtualOffset()
+	if idx < offset.Y || idx >= offset.Y+visibleSize.Height {
+		offset.Y = idx - visibleSize.Height/2
+	}
+	if col < offset.X || col >= offset.X+visibleSize.Width {
+		offset.X = col - visibleSize.Width/4
+	}
+	cur := lv.VirtualOffset()
+	lv.scroll(twin.Point{X: offset.X - cur.X, Y: offset.Y - cur.Y})
+	twin.Redraw(twin.This(lv))
+	return true
+}
+
+// appendLine adds the line, if the buffer is full, the first line is dropped. lv.lock must be held.
+func (lv *LogView) appendLine(s string) {
+	s = strings.TrimSuffix(s, "\r")
+	if lv.lines.Len() == lv.lines.Cap() {
+		if lv.lines.At(0).width >= lv.width {
+			lv.widthStale = true
+		}
+		lv.lines.Skip(1)
+		if lv.match >= 0 {
+			lv.match--
+		}
+		lv.dropped++
+	}
+	ll := logLine{text: s, width: twin.StringWidth(s)}
+	if m := levelRegexp.FindString(s); m != "" {
+		ll.hasLevel = true
+		switch m {
+		case "ERROR", "ERR", "FATAL", "PANIC":
+			ll.level = logging.ERROR
+		case "WARN", "WARNING":
+			ll.level = logging.WARN
+		case "INFO":
+			ll.level = logging.INFO
+		case "DEBUG":
+			ll.level = logging.DEBUG
+		case "TRACE":
+			ll.level = logging.TRACE
+		}
+	}
+	_ = lv.lines.Write(ll)
+	lv.width = max(lv.width, ll.width)
+}
+
+// postUpdate posts the update to the twin go-routine, if it is not posted yet. twin.Invoke
+// never drops the function, so updating is cleared, when the update runs.
+func (lv *LogView) postUpdate() {
+	if lv.updating.CompareAndSwap(false, true) {
+		twin.Invoke(func() {
+			lv.updating.Store(false)
+			lv.update()
+		})
+	}
+}
+
+// update sets the virtual size by the lines, and scrolls to the end in the follow mode. If the
+// view is not following, it is scrolled up by the lines dropped, so the same lines stay on the screen.
+func (lv *LogView) update() {
+	lv.lock.Lock()
+	if lv.widthStale {
+		// the longest line could be dropped
+		lv.width, lv.widthStale = 0, false
+		for i := 0; i < lv.lines.Len(); i++ {
+			lv.width = max(lv.width, lv.lines.At(i).width)
+		}
+	}
+	vs := twin.Size{Width: lv.width, Height: lv.lines.Len()}
+	follow, dropped := lv.follow, lv.dropped
+	lv.dropped = 0
+	lv.lock.Unlock()
+	lv.SetVirtualSize(vs)
+	offset := lv.VirtualOffset()
+	if follow {
+		offset.Y = max(0, vs.Height-lv.ChildrenCanvasBounds().Height)
+	} else {
+		offset.Y = max(0, offset.Y-dropped)
+	}
+	if offset != lv.VirtualOffset() {
+		lv.SetVirtualOffset(offset)
+	}
+	twin.Redraw(twin.This(lv))
+}
+
+// userScrolled pauses the follow mode if the view is scrolled up, and resumes it, if the view
+// is scrolled to the end
+func (lv *LogView) userScrolled() {
+	offset := lv.anim.targetOr(lv.VirtualOffset())
+	atEnd := offset.Y >= lv.VirtualSize().Height-lv.ChildrenCanvasBounds().Height
+	lv.lock.Lock()
+	lv.follow = atEnd
+	lv.lock.Unlock()
+}
+
+func (lv *LogView) OnDraw(cc *twin.CanvasContext) {
+	if lv.IsFollowing() {
+		// the height could be changed since the last update
+		offset := lv.VirtualOffset()
+		if y := max(0, lv.VirtualSize().Height-lv.ChildrenCanvasBounds().Height); offset.Y != y {
+			offset.Y = y
+			lv.SetVirtualOffset(offset)
+		}
+	}
+	lv.ScrollableBox.OnDraw(cc)
+	b, cb := lv.Bounds(), lv.ChildrenCanvasBounds()
+	r := twin.Rectangle{X: cb.X - b.X, Y: cb.Y - b.Y, Width: cb.Width, Height: cb.Height}
+	base := twin.EffectiveStyle(lv)
+	lt := lv.lvs.theme()
+	offset := lv.VirtualOffset()
+
+	lv.lock.Lock()
+	defer lv.lock.Unlock()
+	for y := 0; y < r.Height && offset.Y+y < lv.lines.Len(); y++ {
+		ll := lv.lines.At(offset.Y + y)
+		style := base
+		if c := lt.color(ll); c != tcell.ColorDefault {
+			style = style.Foreground(c)
+		}
+		txt := skipCells(lv.highlight(ll.text, lt.Match), offset.X)
+		cc.PrintTextL(twin.Point{X: r.X, Y: r.Y + y}, txt, r.Width, style)
+	}
+}
+
+// highlight returns the text with the search matches in the match style. lv.lock must be held.
+func (lv *LogView) highlight(s string, match tcell.Style) twin.Text {
+	if lv.search == nil {
+		return twin.PlainText(s)
+	}
+	fg, bg, attrs := match.Decompose()
+	ms := twin.SpanStyle{Fg: fg, Bg: bg, Attrs: attrs}
+	var res twin.Text
+	last := 0
+	for _, loc := range lv.search.FindAllStringIndex(s, -1) {
+		if loc[0] == loc[1] {
+			continue
+		}
+		if loc[0] > last {
+			res = append(res, twin.Span{Text: s[last:loc[0]]})
+		}
+		res = append(res, twin.Span{Text: s[loc[0]:loc[1]], Style: ms})
+		last = loc[1]
+	}
+	if last < len(s) {
+		res = append(res, twin.Span{Text: s[last:]})
+	}
+	return res
+}
+
+// color returns the text color for the line level
+func (lt LogViewTheme) color(ll logLine) tcell.Color {
+	if !ll.hasLevel {
+		return tcell.ColorDefault
+	}
+	switch ll.level {
+	case logging.ERROR:
+		return lt.Error
+	case logging.WARN:
+		return lt.Warn
+	case logging.DEBUG:
+		return lt.Debug
+	case logging.TRACE:
+		return lt.Trace
+	}
+	return tcell.ColorDefault
+}
+
+// skipCells returns the text without its first n cells, the wide cluster, which is cut, is
+// replaced by the spaces
+func skipCells(t twin.Text, n int) twin.Text {
+	for len(t) > 0 && n > 0 {
+		s := t[0].Text
+		state := -1
+		for s != "" && n > 0 {
+			var cl string
+			cl, s, _, state = uniseg.FirstGraphemeClusterInString(s, state)
+			w := twin.ClusterWidth(cl)
+			if w > n {
+				s = strings.Repeat(" ", w-n) + s
+			}
+			n -= min(w, n)
+		}
+		if s == "" {
+			t = t[1:]
+			continue
+		}
+		t = append(twin.Text{{Text: s, Style: t[0].Style, Link: t[0].Link}}, t[1:]...)
+	}
+	return t
+}
+
+func (lv *LogView) OnKeyPressed(ke *tcell.EventKey) bool {
+	switch {
+	case ke.Key() == tcell.KeyF3 && ke.Modifiers()&tcell.ModShift != 0, ke.Key() == tcell.KeyRune && ke.Rune() == 'N':
+		lv.SearchPrev()
+		return true
+	case ke.Key() == tcell.KeyF3, ke.Key() == tcell.KeyRune && ke.Rune() == 'n':
+		lv.SearchNext()
+		return true
+	case ke.Key() == tcell.KeyHome && ke.Modifiers()&tcell.ModCtrl != 0:
+		lv.lock.Lock()
+		lv.follow = false
+		lv.lock.Unlock()
+		lv.SetVirtualOffset(twin.Point{})
+		twin.Redraw(twin.This(lv))
+		return true
+	case ke.Key() == tcell.KeyEnd && ke.Modifiers()&tcell.ModCtrl != 0:
+		lv.SetFollow(true)
+		return true
+	}
+	res := lv.ScrollableBox.OnKeyPressed(ke)
+	if res {
+		lv.userScrolled()
+	}
+	return res
+}
+
+func (lv *LogView) OnMouseWheel(p twin.Point, wheel twin.MouseWheel) bool {
+	res := lv.ScrollableBox.OnMouseWheel(p, wheel)
+	lv.userScrolled()
+	return res
+}
+
+func (lv *LogView) OnMouseDrag(p twin.Point, state twin.MouseDragState) bool {
+	res := lv.ScrollableBox.OnMouseDrag(p, state)
+	if res {
+		lv.userScrolled()
+	}
+	return res
+}
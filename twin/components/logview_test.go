@@ -0,0 +1,234 @@
+package components
+
+import (
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/pkg/golibs/logging"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLogView(t *testing.T, maxLines int) *LogView {
+	lv, err := NewLogView(twin.Root(), LogViewStyle{}.WithMaxLines(maxLines))
+	assert.Nil(t, err)
+	t.Cleanup(func() { twin.Close(lv) })
+	lv.SetBounds(twin.Rectangle{Width: 20, Height: 5})
+	return lv
+}
+
+func (lv *LogView) texts() []string {
+	lv.lock.Lock()
+	defer lv.lock.Unlock()
+	var res []string
+	for i := 0; i < lv.lines.Len(); i++ {
+		res = append(res, lv.lines.At(i).text)
+	}
+	return res
+}
+
+func TestLogView_Append(t *testing.T) {
+	onTwin(func() {
+		lv := newTestLogView(t, 0)
+		lv.Append("a\nb\r\n")
+		assert.Equal(t, []string{"a", "b"}, lv.texts())
+
+		lv.Write([]byte("c"))
+		assert.Equal(t, 2, lv.Len())
+		lv.Write([]byte("d\ne"))
+		assert.Equal(t, []string{"a", "b", "cd"}, lv.texts())
+
+		n, err := lv.ReadFrom(strings.NewReader("f\ng"))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(3), n)
+		assert.Equal(t, []string{"a", "b", "cd", "ef", "g"}, lv.texts())
+
+		lv.Clear()
+		assert.Equal(t, 0, lv.Len())
+	})
+}
+
+func TestLogView_MaxLines(t *testing.T) {
+	onTwin(func() {
+		lv := newTestLogView(t, 3)
+		lv.Append("the longest line\n1\n22\n")
+		lv.update()
+		assert.Equal(t, twin.Size{Width: 16, Height: 3}, lv.VirtualSize())
+
+		lv.SetFollow(false)
+		lv.SetVirtualOffset(twin.Point{Y: 2})
+		lv.Append("333\n4444")
+		assert.Equal(t, []string{"22", "333", "4444"}, lv.texts())
+		lv.update()
+		assert.Equal(t, twin.Size{Width: 4, Height: 3}, lv.VirtualSize())
+		// the same line stays on the top
+		assert.Equal(t, twin.Point{Y: 0}, lv.VirtualOffset())
+	})
+}
+
+func TestLogView_Follow(t *testing.T) {
+	onTwin(func() {
+		lv := newTestLogView(t, 0)
+		for i := 0; i < 20; i++ {
+			lv.Append(fmt.Sprintf("line %d", i))
+		}
+		lv.update()
+		h := lv.ChildrenCanvasBounds().Height
+		assert.True(t, lv.IsFollowing())
+		assert.Equal(t, 20-h, lv.VirtualOffset().Y)
+
+		// scrolling up pauses the follow mode
+		assert.True(t, lv.OnKeyPressed(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)))
+		assert.False(t, lv.IsFollowing())
+		lv.Append("line 20")
+		lv.update()
+		assert.Equal(t, 19-h, lv.VirtualOffset().Y)
+
+		// scrolling to the end resumes it
+		assert.True(t, lv.OnKeyPressed(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)))
+		assert.True(t, lv.OnKeyPressed(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)))
+		assert.True(t, lv.IsFollowing())
+
+		lv.OnKeyPressed(tcell.NewEventKey(tcell.KeyHome, 0, tcell.ModCtrl))
+		assert.False(t, lv.IsFollowing())
+		assert.Equal(t, twin.Point{}, lv.VirtualOffset())
+		lv.OnKeyPressed(tcell.NewEventKey(tcell.KeyEnd, 0, tcell.ModCtrl))
+		assert.True(t, lv.IsFollowing())
+	})
+}
+
+func TestLogView_FollowOnDrag(t *testing.T) {
+	var lv *LogView
+	onTwin(func() {
+		lv = newTestLogView(t, 0)
+		for i := 0; i < 20; i++ {
+			lv.Append(fmt.Sprintf("line %d", i))
+		}
+		lv.update()
+		bars := lv.scrollBars()
+		assert.True(t, bars.hasV)
+		lv.SetFollow(false)
+		lv.SetVirtualOffset(twin.Point{Y: bars.v.MaxOffset - 3})
+
+		// the forward arrow is held, only the repeated scrolling reaches the end
+		p := bars.vPos.Add(0, bars.v.Size-1)
+		assert.True(t, lv.ScrollableBox.OnMouseDrag(p, twin.MouseDragStarted))
+		assert.False(t, lv.IsFollowing())
+	})
+	assert.Eventually(t, lv.IsFollowing, time.Second, 10*time.Millisecond)
+	onTwin(func() {
+		assert.True(t, lv.OnMouseDrag(twin.Point{}, twin.MouseDragEnded))
+	})
+}
+
+func TestLogView_Search(t *testing.T) {
+	onTwin(func() {
+		lv := newTestLogView(t, 0)
+		for i := 0; i < 30; i++ {
+			s := fmt.Sprintf("line %d", i)
+			if i == 3 || i == 17 {
+				s += " foo"
+			}
+			lv.Append(s)
+		}
+		lv.update()
+		lv.SetVirtualOffset(twin.Point{})
+
+		_, err := lv.Search("(")
+		assert.ErrorIs(t, err, errors.ErrInvalid)
+
+		found, err := lv.Search("fo+")
+		assert.Nil(t, err)
+		assert.True(t, found)
+		assert.False(t, lv.IsFollowing())
+		assert.Equal(t, 3, lv.match)
+		assert.True(t, lv.SearchNext())
+		assert.Equal(t, 17, lv.match)
+		assert.True(t, lv.VirtualOffset().Y <= 17 && 17 < lv.VirtualOffset().Y+lv.ChildrenCanvasBounds().Height)
+		assert.False(t, lv.SearchNext())
+		assert.Equal(t, 17, lv.match)
+		assert.True(t, lv.SearchPrev())
+		assert.Equal(t, 3, lv.match)
+		assert.False(t, lv.SearchPrev())
+
+		found, err = lv.Search("")
+		assert.Nil(t, err)
+		assert.False(t, found)
+		assert.True(t, lv.IsFollowing())
+		assert.False(t, lv.SearchNext())
+
+		// the follow mode paused by the user is not resumed
+		lv.SetFollow(false)
+		lv.Search("foo")
+		lv.Search("")
+		assert.False(t, lv.IsFollowing())
+	})
+}
+
+func TestLogView_Highlight(t *testing.T) {
+	onTwin(func() {
+		lv := newTestLogView(t, 0)
+		lv.Search("o")
+		ms := tcell.StyleDefault.Bold(true)
+		lv.lock.Lock()
+		txt := lv.highlight("foo bar", ms)
+		lv.lock.Unlock()
+		hs := twin.SpanStyle{Fg: tcell.ColorDefault, Bg: tcell.ColorDefault, Attrs: tcell.AttrBold}
+		assert.Equal(t, twin.Text{{Text: "f"}, {Text: "o", Style: hs}, {Text: "o", Style: hs}, {Text: " bar"}}, txt)
+	})
+}
+
+func TestLogView_Levels(t *testing.T) {
+	onTwin(func() {
+		tests := []struct {
+			line     string
+			hasLevel bool
+			level    logging.Level
+		}{
+			{"2024-01-01 ERROR something failed", true, logging.ERROR},
+			{"[ERR] x", true, logging.ERROR},
+			{"FATAL: x", true, logging.ERROR},
+			{"PANIC x", true, logging.ERROR},
+			{"WARN x", true, logging.WARN},
+			{"a WARNING", true, logging.WARN},
+			{"INFO x", true, logging.INFO},
+			{"DEBUG x", true, logging.DEBUG},
+			{"TRACE x", true, logging.TRACE},
+			{"INFORMATION x", false, 0},
+			{"error in lower case", false, 0},
+			{"", false, 0},
+		}
+		lv := newTestLogView(t, 0)
+		for _, tc := range tests {
+			lv.Append(tc.line)
+			lv.lock.Lock()
+			ll := lv.lines.At(lv.lines.Len() - 1)
+			lv.lock.Unlock()
+			assert.Equal(t, tc.hasLevel, ll.hasLevel, tc.line)
+			assert.Equal(t, tc.level, ll.level, tc.line)
+		}
+	})
+}
+
+func TestSkipCells(t *testing.T) {
+	tests := []struct {
+		t    twin.Text
+		n    int
+		want twin.Text
+	}{
+		{twin.PlainText("abc"), 0, twin.PlainText("abc")},
+		{twin.PlainText("abc"), 1, twin.PlainText("bc")},
+		{twin.PlainText("abc"), 3, twin.Text{}},
+		{twin.PlainText("abc"), 5, twin.Text{}},
+		{twin.Text{{Text: "ab"}, {Text: "cd", Style: twin.SpanStyle{Attrs: tcell.AttrBold}}}, 3,
+			twin.Text{{Text: "d", Style: twin.SpanStyle{Attrs: tcell.AttrBold}}}},
+		{twin.PlainText("世界"), 1, twin.PlainText(" 界")},
+		{twin.PlainText("世界"), 2, twin.PlainText("界")},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, skipCells(tc.t, tc.n), "%v %d", tc.t, tc.n)
+	}
+}
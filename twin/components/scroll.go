@@ -0,0 +1,98 @@
+package components
+
+import (
+	"github.com/dspasibenko/twin-go/twin"
+	"sync"
+	"time"
+)
+
+const (
+	// wheelBurstInterval is the maximum time between the wheel events of one burst
+	wheelBurstInterval = 60 * time.Millisecond
+	// wheelBurstStep is the number of the burst events, which increase the acceleration by 1
+	wheelBurstStep = 3
+	// maxWheelAcceleration is the maximum multiplier of the wheel step
+	maxWheelAcceleration = 5
+)
+
+// wheelAcceleration counts the fast wheel events in one direction, and makes the scrolling
+// faster for them
+type wheelAcceleration struct {
+	last  time.Time
+	wheel twin.MouseWheel
+	burst int
+}
+
+// lines returns the number of lines to scroll for the wheel event at now
+func (wa *wheelAcceleration) lines(wheel twin.MouseWheel, step int, now time.Time) int {
+	if wheel == wa.wheel && now.Sub(wa.last) < wheelBurstInterval {
+		wa.burst++
+	} else {
+		wa.burst = 0
+	}
+	wa.wheel, wa.last = wheel, now
+	return step * min(maxWheelAcceleration, 1+wa.burst/wheelBurstStep)
+}
+
+// scrollAnimation moves the virtual offset to the target on the twin frames (see twin.Animate),
+// every frame passes the third of the remaining distance, so the scrolling slows down at the end
+type scrollAnimation struct {
+	lock   sync.Mutex
+	target twin.Point
+	// cancel is not nil while the animation runs
+	cancel func()
+}
+
+// targetOr returns the animation target, or cur, if the animation doesn't run
+func (sa *scrollAnimation) targetOr(cur twin.Point) twin.Point {
+	sa.lock.Lock()
+	defer sa.lock.Unlock()
+	if sa.cancel == nil {
+		return cur
+	}
+	return sa.target
+}
+
+// moveTo starts the animation to the target or changes the target of the running one. get
+// returns the current offset, and set changes it.
+func (sa *scrollAnimation) moveTo(target twin.Point, get func() twin.Point, set func(p twin.Point)) {
+	sa.lock.Lock()
+	defer sa.lock.Unlock()
+	sa.target = target
+	if sa.cancel != nil {
+		return
+	}
+	sa.cancel = twin.Animate(func(now time.Time) bool {
+		sa.lock.Lock()
+		defer sa.lock.Unlock()
+		cur := get()
+		if sa.cancel == nil || cur == sa.target {
+			sa.cancel = nil
+			return false
+		}
+		set(twin.Point{X: approach(cur.X, sa.target.X), Y: approach(cur.Y, sa.target.Y)})
+		return true
+	})
+}
+
+// stop stops the running animation
+func (sa *scrollAnimation) stop() {
+	sa.lock.Lock()
+	defer sa.lock.Unlock()
+	if sa.cancel != nil {
+		sa.cancel()
+		sa.cancel = nil
+	}
+}
+
+// approach returns the next value on the way from cur to target
+func approach(cur, target int) int {
+	d := (target - cur) / 3
+	if d == 0 && cur != target {
+		d = 1
+		if target < cur {
+			d = -1
+		}
+	}
+	return cur + d
+}
@@ -1,10 +1,11 @@
 package components
 
 import (
-	"fmt"
 	"github.com/dspasibenko/twin-go/twin"
 	"github.com/gdamore/tcell/v2"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type ScrollableBox struct {
@@ -12,13 +13,61 @@ type ScrollableBox struct {
 	vOffset atomic.Value // twin.Point
 	vSize   atomic.Value // twin.Size
 	sbs     ScrollableBoxStyle
+	lock    sync.Mutex
+	drag    sbDrag
+	// unsubscribe stops the children tracking for the auto virtual size
+	unsubscribe func()
+	wheel       wheelAcceleration
+	anim        scrollAnimation
 }
 
+// sbDrag is the mouse drag on a scroll bar
+type sbDrag struct {
+	active   bool
+	vertical bool
+	part     twin.ScrollBarPart
+	// pos is the bar cell under the mouse
+	pos int
+	// grab is the position of the mouse on the thumb, when it is dragged
+	grab int
+	// cancel stops the animation, which repeats the scrolling while the arrow or the track is
+	// pressed
+	cancel func()
+	// next is the time of the next repeated scrolling
+	next time.Time
+}
+
+// userScroller is implemented by the components embedding ScrollableBox, which track the
+// scrolling made by the user. It is called after the repeated scroll bar scrolling.
+type userScroller interface {
+	userScrolled()
+}
+
+// scrollBars is the layout of the ScrollableBox scroll bars
+type scrollBars struct {
+	hasV, hasH  bool
+	vPos, hPos  twin.Point
+	v, h        twin.ScrollBar
+	virtSize    twin.Size
+	visibleSize twin.Size
+}
+
+const (
+	// scrollRepeatDelay is the delay before the scrolling is repeated while the mouse is held
+	scrollRepeatDelay = 400 * time.Millisecond
+	// scrollRepeatInterval is the interval of the repeated scrolling
+	scrollRepeatInterval = 50 * time.Millisecond
+)
+
 type ScrollableBoxStyle struct {
 	win         string
 	style       *tcell.Style
 	activeStyle *tcell.Style
 	flags       *WindowFlags
+	title       string
+	autoSize    bool
+	wheelStep   int
+	smooth      bool
 }
 
 func (sbs ScrollableBoxStyle) WithWin(win string) ScrollableBoxStyle {
@@ -31,32 +80,48 @@ func (sbs ScrollableBoxStyle) WithStyle(style tcell.Style) ScrollableBoxStyle {
 	return sbs
 }
 
-func (sbs ScrollableBoxStyle) Style() tcell.Style {
-	if sbs.style != nil {
-		return *sbs.style
-	}
-	ws := GetThemeValue[WindowTheme](sbs.win)
-	return ws.NotActive
-}
-
 func (sbs ScrollableBoxStyle) WithActiveStyle(activeStyle tcell.Style) ScrollableBoxStyle {
 	sbs.activeStyle = &activeStyle
 	return sbs
 }
 
-func (sbs ScrollableBoxStyle) ActiveStyle() tcell.Style {
-	if sbs.flags != nil {
-		return *sbs.activeStyle
-	}
-	ws := GetThemeValue[WindowTheme](sbs.win)
-	return ws.Active
-}
-
 func (sbs ScrollableBoxStyle) WithFlags(flags WindowFlags) ScrollableBoxStyle {
 	sbs.flags = &flags
 	return sbs
 }
 
+// WithTitle sets the title shown on the top border. When the box gets the focus, the title
+// becomes the terminal window title as well.
+func (sbs ScrollableBoxStyle) WithTitle(title string) ScrollableBoxStyle {
+	sbs.title = title
+	return sbs
+}
+
+// WithAutoVirtualSize makes the virtual size be the union of the children bounds. It is
+// recalculated whenever a child is added, moved, resized, hidden or closed, so the
+// SetVirtualSize() value is overwritten.
+func (sbs ScrollableBoxStyle) WithAutoVirtualSize(auto bool) ScrollableBoxStyle {
+	sbs.autoSize = auto
+	return sbs
+}
+
+// WithWheelStep sets the number of lines (columns) scrolled by one wheel event, the fast
+// wheel bursts scroll by the multiple steps. The default is 1.
+func (sbs ScrollableBoxStyle) WithWheelStep(step int) ScrollableBoxStyle {
+	sbs.wheelStep = step
+	return sbs
+}
+
+// WithSmoothScroll makes the wheel scrolling animated
+func (sbs ScrollableBoxStyle) WithSmoothScroll(smooth bool) ScrollableBoxStyle {
+	sbs.smooth = smooth
+	return sbs
+}
+
+func (sbs ScrollableBoxStyle) WheelStep() int {
+	return max(1, sbs.wheelStep)
+}
+
 func (sbs ScrollableBoxStyle) Flags() WindowFlags {
 	if sbs.flags != nil {
 		return *sbs.flags
@@ -76,12 +141,68 @@ func (sb *ScrollableBox) Init(owner, this twin.Component, sbs ScrollableBoxStyle
 	sb.sbs = sbs
 	sb.vSize.Store(twin.Size{})
 	sb.vOffset.Store(twin.Point{})
-	return sb.Box.Init(owner, this)
+	if err := sb.Box.Init(owner, this); err != nil {
+		return err
+	}
+	sb.SetOpaque(true)
+	initStyles(&sb.Box, sbs.win, sbs.style, sbs.activeStyle)
+	if sbs.autoSize {
+		sb.unsubscribe = twin.Subscribe(this, func(ev twin.ComponentEvent) {
+			if ev.Owner() == nil || twin.This(ev.Owner()) != this {
+				return
+			}
+			switch ev.Type {
+			case twin.ComponentAdded, twin.ComponentClosed, twin.ComponentBoundsChanged, twin.ComponentVisibilityChanged:
+				sb.updateVirtualSize()
+			}
+		})
+	}
+	return nil
+}
+
+// updateVirtualSize sets the virtual size to cover all the visible children
+func (sb *ScrollableBox) updateVirtualSize() {
+	var vs twin.Size
+	for _, chld := range twin.Children(sb) {
+		if !chld.IsVisible() {
+			continue
+		}
+		r := chld.Bounds()
+		vs.Width = max(vs.Width, r.X+r.Width)
+		vs.Height = max(vs.Height, r.Y+r.Height)
+	}
+	if vs != sb.VirtualSize() {
+		sb.SetVirtualSize(vs)
+		twin.Redraw(twin.This(sb))
+	}
+}
+
+// ScrollIntoView scrolls the box, so its descendant comp is visible. If comp doesn't fit
+// the box, its top left corner is shown.
+func (sb *ScrollableBox) ScrollIntoView(comp twin.Component) {
+	r := twin.RelativeBounds(comp, twin.This(sb))
+	bars := sb.scrollBars()
+	visibleSize := bars.visibleSize
+	cur := twin.Point{X: bars.h.Offset, Y: bars.v.Offset}
+	offset := cur
+	if r.X+r.Width > offset.X+visibleSize.Width {
+		offset.X = r.X + r.Width - visibleSize.Width
+	}
+	if r.X < offset.X {
+		offset.X = r.X
+	}
+	if r.Y+r.Height > offset.Y+visibleSize.Height {
+		offset.Y = r.Y + r.Height - visibleSize.Height
+	}
+	if r.Y < offset.Y {
+		offset.Y = r.Y
+	}
+	sb.scroll(twin.Point{X: offset.X - cur.X, Y: offset.Y - cur.Y})
 }
 
 func (sb *ScrollableBox) OnDraw(cc *twin.CanvasContext) {
 	b := sb.Bounds().Normalized()
-	stl := sb.sbs.Style()
+	stl := twin.EffectiveStyle(sb)
 	flags := sb.sbs.Flags()
 	cc.FilledRectangle(b, stl)
 	if flags&WindowFlagHasBorderBM != 0 {
@@ -92,36 +213,73 @@ func (sb *ScrollableBox) OnDraw(cc *twin.CanvasContext) {
 			crs = sb.sbs.windowStyle().NaRectStyle
 		}
 		cc.Rectangle(b, crs, stl)
+		if sb.sbs.title != "" && b.Width > 4 {
+			cc.PrintL(twin.Point{X: 2, Y: 0}, " "+sb.sbs.title+" ", b.Width-4, stl)
+		}
+	}
+	bars := sb.scrollBars()
+	if bars.hasV {
+		cc.DrawVScrollBar(bars.vPos, bars.v.Size, bars.virtSize.Height, bars.visibleSize.Height, bars.v.Offset, stl)
+	}
+	if bars.hasH {
+		cc.DrawHScrollBar(bars.hPos, bars.h.Size, bars.virtSize.Width, bars.visibleSize.Width, bars.h.Offset, stl)
 	}
+}
+
+// scrollBars calculates the scroll bars layout, it is used for drawing and for the mouse handling
+func (sb *ScrollableBox) scrollBars() scrollBars {
+	b := sb.Bounds().Normalized()
 	sbSize := b.Size()
 	sbPoint := twin.Point{X: 0, Y: 0}
-	hasV := sb.HasVBar()
-	hasH := sb.HasHBar()
+	res := scrollBars{hasV: sb.HasVBar(), hasH: sb.HasHBar()}
 
-	if flags&WindowFlagHasBorderBM != 0 {
+	if sb.sbs.Flags()&WindowFlagHasBorderBM != 0 {
 		sbSize = sbSize.Add(-2, -2)
 		sbPoint = twin.Point{X: 1, Y: 1}
 	} else {
-		if hasV {
+		if res.hasV {
 			sbSize = sbSize.Add(-1, 0)
 		}
-		if hasH {
+		if res.hasH {
 			sbSize = sbSize.Add(0, -1)
 		}
 	}
-	virtSize := sb.VirtualSize()
-	visibleSize := sb.ChildrenCanvasBounds().Size()
-	virtSize.Width = max(visibleSize.Width, virtSize.Width)
-	virtSize.Height = max(visibleSize.Height, virtSize.Height)
+	res.visibleSize = sb.ChildrenCanvasBounds().Size()
+	res.virtSize = sb.VirtualSize()
+	res.virtSize.Width = max(res.visibleSize.Width, res.virtSize.Width)
+	res.virtSize.Height = max(res.visibleSize.Height, res.virtSize.Height)
 	virtOffset := sb.VirtualOffset()
-	virtOffset.X = max(0, min(virtOffset.X, virtSize.Width-visibleSize.Width))
-	virtOffset.Y = max(0, min(virtOffset.Y, virtSize.Height-visibleSize.Height))
-	if hasV {
-		cc.DrawVScrollBar(twin.Point{X: b.Width - 1, Y: sbPoint.Y}, sbSize.Height, virtSize.Height, visibleSize.Height, virtOffset.Y, stl)
+	res.vPos = twin.Point{X: b.Width - 1, Y: sbPoint.Y}
+	res.v = twin.NewScrollBar(sbSize.Height, res.virtSize.Height, res.visibleSize.Height, virtOffset.Y)
+	res.hPos = twin.Point{X: sbPoint.X, Y: b.Height - 1}
+	res.h = twin.NewScrollBar(sbSize.Width, res.virtSize.Width, res.visibleSize.Width, virtOffset.X)
+	return res
+}
+
+// barAt returns the cell index of the scroll bar under p
+func (bars scrollBars) barAt(p twin.Point) (vertical bool, i int, ok bool) {
+	if bars.hasV && p.X == bars.vPos.X && bars.v.Part(p.Y-bars.vPos.Y) != twin.ScrollBarNone {
+		return true, p.Y - bars.vPos.Y, true
 	}
-	if hasH {
-		cc.DrawHScrollBar(twin.Point{X: sbPoint.X, Y: b.Height - 1}, sbSize.Width, virtSize.Width, visibleSize.Width, virtOffset.X, stl)
+	if bars.hasH && p.Y == bars.hPos.Y && bars.h.Part(p.X-bars.hPos.X) != twin.ScrollBarNone {
+		return false, p.X - bars.hPos.X, true
+	}
+	return false, 0, false
+}
+
+func (bars scrollBars) bar(vertical bool) twin.ScrollBar {
+	if vertical {
+		return bars.v
 	}
+	return bars.h
+}
+
+// index returns the cell index of the p projection to the vertical or the horizontal bar
+func (bars scrollBars) index(p twin.Point, vertical bool) int {
+	if vertical {
+		return p.Y - bars.vPos.Y
+	}
+	return p.X - bars.hPos.X
 }
 
 func (sb *ScrollableBox) ChildrenCanvasBounds() twin.Rectangle {
@@ -142,65 +300,129 @@ func (sb *ScrollableBox) ChildrenCanvasBounds() twin.Rectangle {
 	return b
 }
 
+// OnMousePressed consumes the clicks on the scroll bars, they are handled by OnMouseDrag
 func (sb *ScrollableBox) OnMousePressed(p twin.Point) bool {
-	b := sb.Bounds().Normalized()
-	flags := sb.sbs.Flags()
-	sbSize := b.Size()
-	sbPoint := twin.Point{X: 0, Y: 0}
-	hasV := sb.HasVBar()
-	hasH := sb.HasHBar()
+	_, _, ok := sb.scrollBars().barAt(p)
+	return ok
+}
 
-	if flags&WindowFlagHasBorderBM != 0 {
-		sbSize = sbSize.Add(-2, -2)
-		sbPoint = twin.Point{X: 1, Y: 1}
-	} else {
-		if hasV {
-			sbSize = sbSize.Add(-1, 0)
+// OnMouseDrag scrolls by a line when an arrow is pressed, by a page when the track is pressed,
+// and proportionally when the thumb is dragged. The arrows and the track repeat the scrolling
+// while the button is held.
+func (sb *ScrollableBox) OnMouseDrag(p twin.Point, state twin.MouseDragState) bool {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+	bars := sb.scrollBars()
+	switch state {
+	case twin.MouseDragStarted:
+		vertical, i, ok := bars.barAt(p)
+		if !ok {
+			return false
 		}
-		if hasH {
-			sbSize = sbSize.Add(0, -1)
+		bar := bars.bar(vertical)
+		sb.drag = sbDrag{active: true, vertical: vertical, part: bar.Part(i), pos: i}
+		if sb.drag.part == twin.ScrollBarThumb {
+			sb.drag.grab = i - bar.ThumbStart()
+			return true
 		}
-	}
-
-	virtSize := sb.VirtualSize()
-	visibleSize := sb.ChildrenCanvasBounds().Size()
-	virtSize.Width = max(visibleSize.Width, virtSize.Width)
-	virtSize.Height = max(visibleSize.Height, virtSize.Height)
-	virtOffset := sb.VirtualOffset()
-	virtOffset.X = max(0, min(virtOffset.X, virtSize.Width-visibleSize.Width))
-	virtOffset.Y = max(0, min(virtOffset.Y, virtSize.Height-visibleSize.Height))
-
-	fmt.Println(hasV, p, b, sbSize)
-	if hasV && p.X == b.Width-1 && p.Y < sbPoint.Y+sbSize.Height && p.Y >= sbPoint.Y {
-		maxY := virtSize.Height - visibleSize.Height
-		if p.Y == sbPoint.Y {
-			virtOffset.Y = virtOffset.Y - 1
-		} else if p.Y == sbPoint.Y+sbSize.Height-1 {
-			virtOffset.Y = virtOffset.Y + 1
-		} else if sbSize.Height > 2 {
-			virtOffset.Y = int(float64(p.Y-1) / float64(sbSize.Height-2) * float64(maxY))
+		sb.stepDrag()
+		sb.drag.next = time.Now().Add(scrollRepeatDelay)
+		sb.drag.cancel = twin.Animate(sb.repeatDrag)
+		return true
+	case twin.MouseDragMoved:
+		if !sb.drag.active {
+			return false
+		}
+		sb.drag.pos = bars.index(p, sb.drag.vertical)
+		if sb.drag.part != twin.ScrollBarThumb {
+			return true
+		}
+		offset := sb.VirtualOffset()
+		if sb.drag.vertical {
+			offset.Y = bars.v.OffsetAt(sb.drag.pos - sb.drag.grab)
+		} else {
+			offset.X = bars.h.OffsetAt(sb.drag.pos - sb.drag.grab)
+		}
+		if offset != sb.VirtualOffset() {
+			sb.SetVirtualOffset(offset)
+			twin.Redraw(twin.This(sb))
 		}
-		virtOffset.Y = max(0, min(virtOffset.Y, maxY))
-		sb.SetVirtualOffset(virtOffset)
-		twin.Redraw(twin.This(sb))
+		return true
+	case twin.MouseDragEnded:
+		sb.stopDrag()
 		return true
 	}
+	return false
+}
 
-	if hasH && p.Y == b.Height-1 && p.X < sbPoint.X+sbSize.Width && p.X >= sbPoint.X {
-		maxX := virtSize.Width - visibleSize.Width
-		if p.X == sbPoint.X {
-			virtOffset.X = virtOffset.X - 1
-		} else if p.X == sbPoint.X+sbSize.Width-1 {
-			virtOffset.X = virtOffset.X + 1
-		} else if sbSize.Width > 2 {
-			virtOffset.X = int(float64(p.X-1) / float64(sbSize.Width-2) * float64(maxX))
+// stepDrag scrolls once for the pressed arrow or track, the track is scrolled till the thumb
+// reaches the mouse pointer. sb.lock must be held.
+func (sb *ScrollableBox) stepDrag() {
+	bars := sb.scrollBars()
+	bar := bars.bar(sb.drag.vertical)
+	d := 0
+	page := bars.visibleSize.Width
+	if sb.drag.vertical {
+		page = bars.visibleSize.Height
+	}
+	switch sb.drag.part {
+	case twin.ScrollBarBackArrow:
+		d = -1
+	case twin.ScrollBarForwardArrow:
+		d = 1
+	case twin.ScrollBarBackTrack:
+		if sb.drag.pos < bar.ThumbStart() {
+			d = -page
 		}
-		virtOffset.X = max(0, min(virtOffset.X, maxX))
-		sb.SetVirtualOffset(virtOffset)
-		twin.Redraw(twin.This(sb))
+	case twin.ScrollBarForwardTrack:
+		if sb.drag.pos >= bar.ThumbStart()+bar.ThumbLen {
+			d = page
+		}
+	}
+	if sb.drag.vertical {
+		sb.scroll(twin.Point{Y: d})
+	} else {
+		sb.scroll(twin.Point{X: d})
+	}
+}
+
+// repeatDrag is the animation, which repeats stepDrag while the arrow or the track is pressed
+func (sb *ScrollableBox) repeatDrag(now time.Time) bool {
+	sb.lock.Lock()
+	if !sb.drag.active || sb.drag.cancel == nil {
+		sb.lock.Unlock()
+		return false
+	}
+	if now.Before(sb.drag.next) {
+		sb.lock.Unlock()
 		return true
 	}
-	return false
+	sb.drag.next = now.Add(scrollRepeatInterval)
+	sb.stepDrag()
+	sb.lock.Unlock()
+	if us, ok := twin.This(sb).(userScroller); ok {
+		us.userScrolled()
+	}
+	return true
+}
+
+// stopDrag stops the drag in progress, sb.lock must be held
+func (sb *ScrollableBox) stopDrag() {
+	if sb.drag.cancel != nil {
+		sb.drag.cancel()
+	}
+	sb.drag = sbDrag{}
+}
+
+func (sb *ScrollableBox) OnClosed() {
+	sb.lock.Lock()
+	sb.stopDrag()
+	sb.lock.Unlock()
+	if sb.unsubscribe != nil {
+		sb.unsubscribe()
+	}
+	sb.anim.stop()
+	sb.Box.OnClosed()
 }
 
 func (sb *ScrollableBox) OnKeyPressed(ke *tcell.EventKey) bool {
@@ -227,17 +449,40 @@ func (sb *ScrollableBox) OnKeyPressed(ke *tcell.EventKey) bool {
 }
 
 func (sb *ScrollableBox) OnMouseWheel(p twin.Point, wheel twin.MouseWheel) bool {
+	n := sb.wheel.lines(wheel, sb.sbs.WheelStep(), time.Now())
+	var d twin.Point
 	switch wheel {
 	case twin.MouseWheelUp:
-		return sb.scroll(twin.Point{X: 0, Y: -1})
+		d.Y = -n
 	case twin.MouseWheelDown:
-		return sb.scroll(twin.Point{X: 0, Y: 1})
+		d.Y = n
 	case twin.MouseWheelLeft:
-		return sb.scroll(twin.Point{X: -1, Y: 0})
+		d.X = -n
 	case twin.MouseWheelRight:
-		return sb.scroll(twin.Point{X: 1, Y: 0})
+		d.X = n
+	default:
+		return false
 	}
-	return false
+	if sb.sbs.smooth {
+		return sb.scrollSmooth(d)
+	}
+	return sb.scroll(d)
+}
+
+// scrollSmooth scrolls by d with the animation, the consecutive calls move the animation target
+func (sb *ScrollableBox) scrollSmooth(d twin.Point) bool {
+	bars := sb.scrollBars()
+	target := sb.anim.targetOr(twin.Point{X: bars.h.Offset, Y: bars.v.Offset})
+	target.X = max(0, min(bars.h.MaxOffset, target.X+d.X))
+	target.Y = max(0, min(bars.v.MaxOffset, target.Y+d.Y))
+	sb.anim.moveTo(target, func() twin.Point {
+		bars := sb.scrollBars()
+		return twin.Point{X: bars.h.Offset, Y: bars.v.Offset}
+	}, func(p twin.Point) {
+		sb.vOffset.Store(p)
+		twin.Redraw(twin.This(sb))
+	})
+	return true
 }
 
 func (sb *ScrollableBox) scroll(p twin.Point) bool {
@@ -275,13 +520,22 @@ func (sb *ScrollableBox) VirtualOffset() twin.Point {
 	return sb.vOffset.Load().(twin.Point)
 }
 
+// SetVirtualOffset sets the virtual offset, the smooth scrolling in progress is stopped
 func (sb *ScrollableBox) SetVirtualOffset(offset twin.Point) {
+	sb.anim.stop()
 	sb.vOffset.Store(offset)
 }
 
 func (sb *ScrollableBox) CanBeFocused() bool { return true }
 
-func (sb *ScrollableBox) Style() tcell.Style { return sb.sbs.Style() }
+// OnFocus makes the box title the terminal window title, when the box is focused
+func (sb *ScrollableBox) OnFocus(focused bool) {
+	if focused && sb.sbs.title != "" {
+		twin.SetTitle(sb.sbs.title)
+	}
+}
+
+func (sb *ScrollableBox) Style() tcell.Style { return twin.EffectiveStyle(sb) }
 
 func (sb *ScrollableBox) HasBorder() bool {
 	return sb.sbs.Flags()&WindowFlagHasBorderBM != 0
@@ -0,0 +1,355 @@
+package components
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/twin"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// structTag is the parsed `twin:"label=...,min=...,max=...,choices=a|b|c"` struct field tag.
+// The "-" tag value makes the field skipped.
+type structTag struct {
+	label   string
+	min     string
+	max     string
+	choices []string
+	skip    bool
+}
+
+// structBinding binds the form field with the struct field
+type structBinding struct {
+	name  string
+	index []int
+	tp    reflect.Type
+	tag   structTag
+	minV  *reflect.Value
+	maxV  *reflect.Value
+	// enum is true for the integer field with choices, which are the names of the values 0, 1, 2...
+	enum bool
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// NewStructForm creates the Form for the struct ptr points to. Every exported field of the
+// struct becomes the form field with an input chosen by the field type: EditLine for strings,
+// numbers, time.Duration and time.Time (RFC 3339), CheckBox for bool and ChoiceBox for the fields
+// with choices. Nested structs fields are added with the nested struct name prefix. The field may
+// be tuned by the `twin` tag, for example:
+//
+//	type Config struct {
+//		Host    string        `twin:"label=Host name,min=1"`
+//		Port    int           `twin:"min=1,max=65535"`
+//		Mode    string        `twin:"choices=fast|safe"`
+//		Level   int           `twin:"choices=low|medium|high"`
+//		Timeout time.Duration `twin:"min=1s"`
+//		Secret  string        `twin:"-"`
+//	}
+//
+// The choices of a string field are its values, and the choices of an integer field are the
+// names of the values 0, 1, 2 and so on. min and max define the value range for numbers,
+// durations and times, and the length range for strings.
+// The validated values are written back to the struct when the form is submitted, before the
+// fs onSubmit is called.
+func NewStructForm(owner twin.Component, ptr any, fs FormStyle) (*Form, error) {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("the pointer to a struct is expected, but %T: %w", ptr, errors.ErrInvalid)
+	}
+	f, err := NewForm(owner, fs)
+	if err != nil {
+		return nil, err
+	}
+	var binds []*structBinding
+	if err := addStructFields(f, rv.Elem(), nil, "", "", &binds); err != nil {
+		twin.Close(f)
+		return nil, err
+	}
+	f.decode = func(values map[string]any) (string, error) {
+		// all the fields are decoded first, so the struct is not changed, if one of them fails
+		decoded := make([]reflect.Value, len(binds))
+		for i, sb := range binds {
+			v, err := sb.value(values[sb.name])
+			if err != nil {
+				return sb.name, err
+			}
+			decoded[i] = v
+		}
+		for i, sb := range binds {
+			rv.Elem().FieldByIndex(sb.index).Set(decoded[i])
+			values[sb.name] = decoded[i].Interface()
+		}
+		return "", nil
+	}
+	return f, nil
+}
+
+func addStructFields(f *Form, v reflect.Value, index []int, name, label string, binds *[]*structBinding) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		st, err := parseStructTag(sf.Tag.Get("twin"))
+		if err != nil {
+			return fmt.Errorf("the field %s tag: %w", sf.Name, err)
+		}
+		if st.skip {
+			continue
+		}
+		sb := &structBinding{name: name + sf.Name, index: append(index[:len(index):len(index)], i), tp: sf.Type, tag: st}
+		if st.label == "" {
+			st.label = label + sf.Name
+		}
+		fv := v.Field(i)
+		if sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+			if !hasExportedFields(sf.Type) {
+				return fmt.Errorf("the field %s type %s has no exported fields: %w", sb.name, sf.Type, errors.ErrInvalid)
+			}
+			if err := addStructFields(f, fv, sb.index, sb.name+".", st.label+".", binds); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := sb.parseRange(); err != nil {
+			return err
+		}
+		var input FormInput
+		switch {
+		case sf.Type.Kind() == reflect.Bool:
+			input, err = NewCheckBox(f, CheckBoxStyle{}.WithChecked(fv.Bool()))
+		case len(st.choices) > 0:
+			var sel int
+			if sel, err = sb.choice(fv); err != nil {
+				return err
+			}
+			input, err = NewChoiceBox(f, ChoiceBoxStyle{}.WithChoices(st.choices...).WithSelected(sel))
+		case isEditable(sf.Type):
+			input, err = NewEditLine(f, EditLineStyle{}.WithText(formatValue(fv)))
+		default:
+			return fmt.Errorf("the field %s type %s is not supported: %w", sb.name, sf.Type, errors.ErrInvalid)
+		}
+		if err != nil {
+			return err
+		}
+		ff := NewFormField(sb.name, input).WithLabel(st.label).WithValidator(func(v any) error {
+			_, err := sb.value(v)
+			return err
+		})
+		if err := f.AddField(ff); err != nil {
+			return err
+		}
+		*binds = append(*binds, sb)
+	}
+	return nil
+}
+
+func isEditable(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// choice returns the index of the choice for the field value fv
+func (sb *structBinding) choice(fv reflect.Value) (int, error) {
+	switch {
+	case sb.tp.Kind() == reflect.String:
+		return max(0, indexOf(sb.tag.choices, fv.String())), nil
+	case sb.tp != durationType && sb.tp.Kind() >= reflect.Int && sb.tp.Kind() <= reflect.Int64:
+		sb.enum = true
+		return int(max(0, min(fv.Int(), int64(len(sb.tag.choices)-1)))), nil
+	case sb.tp.Kind() >= reflect.Uint && sb.tp.Kind() <= reflect.Uint64:
+		sb.enum = true
+		return int(min(fv.Uint(), uint64(len(sb.tag.choices)-1))), nil
+	}
+	return 0, fmt.Errorf("the field %s type %s can't have choices: %w", sb.name, sb.tp, errors.ErrInvalid)
+}
+
+func indexOf(ss []string, s string) int {
+	for i, s1 := range ss {
+		if s1 == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// formatValue returns the field value text for EditLine
+func formatValue(fv reflect.Value) string {
+	if fv.Type() == timeType {
+		return fv.Interface().(time.Time).Format(time.RFC3339)
+	}
+	return fmt.Sprint(fv.Interface())
+}
+
+func (sb *structBinding) parseRange() error {
+	for _, p := range []struct {
+		s   string
+		dst **reflect.Value
+	}{{sb.tag.min, &sb.minV}, {sb.tag.max, &sb.maxV}} {
+		if p.s == "" {
+			continue
+		}
+		tp := sb.tp
+		if tp.Kind() == reflect.String {
+			tp = reflect.TypeOf(0)
+		}
+		v, err := parseValue(p.s, tp)
+		if err != nil {
+			return fmt.Errorf("the field %s range %q: %s: %w", sb.name, p.s, err, errors.ErrInvalid)
+		}
+		*p.dst = &v
+	}
+	return nil
+}
+
+// value converts the form value v to the struct field value and checks its range
+func (sb *structBinding) value(v any) (reflect.Value, error) {
+	var rv reflect.Value
+	if s, ok := v.(string); ok && sb.enum {
+		idx := indexOf(sb.tag.choices, s)
+		if idx < 0 {
+			return rv, fmt.Errorf("%q is not one of %v", s, sb.tag.choices)
+		}
+		rv = reflect.New(sb.tp).Elem()
+		if sb.tp.Kind() >= reflect.Uint && sb.tp.Kind() <= reflect.Uint64 {
+			rv.SetUint(uint64(idx))
+		} else {
+			rv.SetInt(int64(idx))
+		}
+	} else if ok {
+		var err error
+		if rv, err = parseValue(s, sb.tp); err != nil {
+			return rv, err
+		}
+	} else {
+		rv = reflect.ValueOf(v)
+		if !rv.IsValid() || !rv.Type().ConvertibleTo(sb.tp) {
+			return rv, errFormValueType(v, reflect.Zero(sb.tp).Interface())
+		}
+		rv = rv.Convert(sb.tp)
+	}
+	if sb.minV != nil && compareValues(rv, *sb.minV) < 0 {
+		return rv, fmt.Errorf("must be at least %s", sb.tag.min)
+	}
+	if sb.maxV != nil && compareValues(rv, *sb.maxV) > 0 {
+		return rv, fmt.Errorf("must be at most %s", sb.tag.max)
+	}
+	return rv, nil
+}
+
+// parseValue parses s to the value of the type tp
+func parseValue(s string, tp reflect.Type) (reflect.Value, error) {
+	rv := reflect.New(tp).Elem()
+	s = strings.TrimSpace(s)
+	switch {
+	case tp == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return rv, fmt.Errorf("invalid duration %q", s)
+		}
+		rv.SetInt(int64(d))
+	case tp == timeType:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return rv, fmt.Errorf("invalid time %q, RFC 3339 is expected", s)
+		}
+		rv.Set(reflect.ValueOf(t))
+	case tp.Kind() == reflect.String:
+		rv.SetString(s)
+	case tp.Kind() >= reflect.Int && tp.Kind() <= reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, tp.Bits())
+		if err != nil {
+			return rv, fmt.Errorf("invalid integer %q", s)
+		}
+		rv.SetInt(i)
+	case tp.Kind() >= reflect.Uint && tp.Kind() <= reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, tp.Bits())
+		if err != nil {
+			return rv, fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		rv.SetUint(u)
+	case tp.Kind() == reflect.Float32 || tp.Kind() == reflect.Float64:
+		fl, err := strconv.ParseFloat(s, tp.Bits())
+		if err != nil {
+			return rv, fmt.Errorf("invalid number %q", s)
+		}
+		rv.SetFloat(fl)
+	default:
+		return rv, fmt.Errorf("the type %s is not supported: %w", tp, errors.ErrInvalid)
+	}
+	return rv, nil
+}
+
+// compareValues compares the value v with the range limit lim. The strings are compared by
+// their length
+func compareValues(v, lim reflect.Value) int {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Compare(lim.Interface().(time.Time))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return cmp.Compare(int64(len([]rune(v.String()))), lim.Int())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(v.Int(), lim.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(v.Uint(), lim.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(v.Float(), lim.Float())
+	}
+	return 0
+}
+
+func parseStructTag(tag string) (structTag, error) {
+	var st structTag
+	if tag == "-" {
+		st.skip = true
+		return st, nil
+	}
+	for _, kv := range strings.Split(tag, ",") {
+		if strings.TrimSpace(kv) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return st, fmt.Errorf("the tag part %q must be key=value: %w", kv, errors.ErrInvalid)
+		}
+		switch strings.TrimSpace(k) {
+		case "label":
+			st.label = v
+		case "min":
+			st.min = strings.TrimSpace(v)
+		case "max":
+			st.max = strings.TrimSpace(v)
+		case "choices":
+			st.choices = strings.Split(v, "|")
+		default:
+			return st, fmt.Errorf("unknown tag key %q: %w", k, errors.ErrInvalid)
+		}
+	}
+	return st, nil
+}
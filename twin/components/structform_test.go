@@ -0,0 +1,159 @@
+package components
+
+import (
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testLevel int
+
+type testConfig struct {
+	Host    string    `twin:"label=Host name,min=1"`
+	Port    int       `twin:"min=1,max=65535"`
+	Mode    string    `twin:"choices=fast|safe"`
+	Level   testLevel `twin:"choices=low|medium|high"`
+	Debug   bool
+	Timeout time.Duration `twin:"min=1s"`
+	Start   time.Time     `twin:"min=2020-01-01T00:00:00Z"`
+	Secret  string        `twin:"-"`
+	DB      struct {
+		User string
+	}
+	hidden int
+}
+
+func TestNewStructForm(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	cfg := &testConfig{Host: "localhost", Port: 80, Mode: "safe", Level: 1, Timeout: time.Minute, Start: start}
+	cfg.DB.User = "admin"
+	var submitted map[string]any
+	onTwin(func() {
+		f, err := NewStructForm(twin.Root(), cfg, FormStyle{}.WithOnSubmit(func(f *Form, values map[string]any) {
+			submitted = values
+		}))
+		assert.Nil(t, err)
+		defer twin.Close(f)
+
+		assert.Equal(t, map[string]any{
+			"Host": "localhost", "Port": "80", "Mode": "safe", "Level": "medium", "Debug": false,
+			"Timeout": "1m0s", "Start": "2024-05-01T10:00:00Z", "DB.User": "admin",
+		}, f.Values())
+		assert.Equal(t, "Host name", f.field("Host").label)
+		assert.Equal(t, "DB.User", f.field("DB.User").label)
+		assert.True(t, f.Validate())
+
+		assert.Nil(t, f.Input("Port").SetFormValue("8080"))
+		assert.Nil(t, f.Input("Level").SetFormValue("high"))
+		assert.Nil(t, f.Input("Debug").SetFormValue(true))
+		assert.Nil(t, f.Input("Start").SetFormValue("2025-01-02T03:04:05Z"))
+		assert.Nil(t, f.Input("DB.User").SetFormValue("root"))
+		f.Submit()
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, testLevel(2), cfg.Level)
+		assert.True(t, cfg.Debug)
+		assert.Equal(t, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), cfg.Start)
+		assert.Equal(t, "root", cfg.DB.User)
+		assert.Equal(t, testLevel(2), submitted["Level"])
+		assert.Equal(t, 8080, submitted["Port"])
+	})
+}
+
+func TestNewStructForm_Validate(t *testing.T) {
+	cfg := &testConfig{Host: "localhost", Port: 80, Mode: "fast", Timeout: time.Minute, Start: time.Now()}
+	onTwin(func() {
+		f, err := NewStructForm(twin.Root(), cfg, FormStyle{})
+		assert.Nil(t, err)
+		defer twin.Close(f)
+
+		for _, tc := range []struct {
+			name, value, err string
+		}{
+			{"Host", "", "must be at least 1"},
+			{"Port", "0", "must be at least 1"},
+			{"Port", "65536", "must be at most 65535"},
+			{"Port", "http", `invalid integer "http"`},
+			{"Timeout", "10ms", "must be at least 1s"},
+			{"Timeout", "1", `invalid duration "1"`},
+			{"Start", "2019-12-31T23:59:59Z", "must be at least 2020-01-01T00:00:00Z"},
+			{"Start", "yesterday", `invalid time "yesterday", RFC 3339 is expected`},
+		} {
+			in := f.Input(tc.name)
+			prev := in.FormValue()
+			assert.Nil(t, in.SetFormValue(tc.value))
+			assert.False(t, f.Validate(), tc.value)
+			assert.Equal(t, tc.err, f.field(tc.name).errLbl.Text())
+			assert.Nil(t, in.SetFormValue(prev))
+			assert.True(t, f.Validate())
+		}
+	})
+}
+
+func TestNewStructForm_Uint(t *testing.T) {
+	cfg := &struct {
+		N uint64 `twin:"min=9223372036854775808"`
+	}{N: math.MaxUint64}
+	onTwin(func() {
+		f, err := NewStructForm(twin.Root(), cfg, FormStyle{})
+		assert.Nil(t, err)
+		defer twin.Close(f)
+		// the values above MaxInt64 are compared without the overflow
+		assert.True(t, f.Validate())
+		assert.Nil(t, f.Input("N").SetFormValue("1"))
+		assert.False(t, f.Validate())
+	})
+}
+
+func TestNewStructForm_DecodeError(t *testing.T) {
+	cfg := &struct {
+		Host string
+		Port int
+	}{Host: "a", Port: 1}
+	var submitted bool
+	onTwin(func() {
+		f, err := NewStructForm(twin.Root(), cfg, FormStyle{}.WithOnSubmit(func(f *Form, values map[string]any) {
+			submitted = true
+		}))
+		assert.Nil(t, err)
+		defer twin.Close(f)
+		// the value is changed after the validation
+		f.field("Port").validators = nil
+		assert.Nil(t, f.Input("Host").SetFormValue("b"))
+		assert.Nil(t, f.Input("Port").SetFormValue("x"))
+		f.Submit()
+		assert.False(t, submitted)
+		// the struct is not changed partially
+		assert.Equal(t, "a", cfg.Host)
+		assert.True(t, f.field("Port").errLbl.IsVisible())
+		assert.Equal(t, `invalid integer "x"`, f.field("Port").errLbl.Text())
+	})
+}
+
+func TestNewStructForm_Errors(t *testing.T) {
+	onTwin(func() {
+		for _, ptr := range []any{
+			struct{ A int }{},
+			&struct {
+				A float64 `twin:"choices=a|b"`
+			}{},
+			&struct {
+				A time.Duration `twin:"choices=a|b"`
+			}{},
+			&struct{ M sync.Mutex }{},
+			&struct{ A []int }{},
+			&struct {
+				A int `twin:"size=1"`
+			}{},
+			&struct {
+				A int `twin:"min=a"`
+			}{},
+		} {
+			_, err := NewStructForm(twin.Root(), ptr, FormStyle{})
+			assert.ErrorIs(t, err, errors.ErrInvalid, "%T", ptr)
+		}
+	})
+}
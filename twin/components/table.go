@@ -0,0 +1,83 @@
+package components
+
+import (
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"strings"
+)
+
+// Table is the list box, which shows the DataView rows as the columns with the header
+type Table struct {
+	ListBox
+	ts TableStyle
+}
+
+// TableColumn describes the table column
+type TableColumn struct {
+	Title     string
+	Width     int
+	Alignment TextAlignment
+}
+
+type TableStyle struct {
+	ListBoxStyle
+	columns     []TableColumn
+	headerStyle *tcell.Style
+}
+
+// tableSeparator separates the table columns
+const tableSeparator = string(tcell.RuneVLine)
+
+func (ts TableStyle) WithColumns(columns ...TableColumn) TableStyle {
+	ts.columns = append([]TableColumn(nil), columns...)
+	return ts
+}
+
+// WithHeaderStyle sets the style of the columns titles, the default is the bold table style
+func (ts TableStyle) WithHeaderStyle(style tcell.Style) TableStyle {
+	ts.headerStyle = &style
+	return ts
+}
+
+// NewTable creates the table, the rows are set by SetDataView()
+func NewTable(owner twin.Component, ts TableStyle) (*Table, error) {
+	t := &Table{ts: ts}
+	if err := t.ListBox.Init(owner, t, ts.ListBoxStyle); err != nil {
+		return nil, err
+	}
+	t.formatRow = t.format
+	t.headerStyle = ts.headerStyle
+	titles := make([]string, len(ts.columns))
+	for i, col := range ts.columns {
+		titles[i] = col.Title
+	}
+	t.header = t.format(titles)
+	t.SetVirtualSize(twin.Size{Height: t.headerLines()})
+	return t, nil
+}
+
+// format makes the row text, the cells are aligned in the columns
+func (t *Table) format(cells []string) twin.Text {
+	var res twin.Text
+	for i, col := range t.ts.columns {
+		if i > 0 {
+			res = append(res, twin.Span{Text: tableSeparator})
+		}
+		var cell twin.Text
+		if i < len(cells) {
+			cell = twin.PlainText(cells[i]).Truncate(col.Width)
+		}
+		pad := col.Width - cell.Width()
+		left := 0
+		switch col.Alignment {
+		case AllignRight:
+			left = pad
+		case AllignCenter:
+			left = pad / 2
+		}
+		res = append(res, twin.Span{Text: strings.Repeat(" ", left)})
+		res = append(res, cell...)
+		res = append(res, twin.Span{Text: strings.Repeat(" ", pad-left)})
+	}
+	return res
+}
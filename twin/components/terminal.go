@@ -0,0 +1,185 @@
+package components
+
+import (
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Terminal runs a command (a shell, ssh etc.) in the pty and shows its output parsed by
+// twin.VTerm. The keys and the pasted text are sent to the command, and the pty is resized
+// with the component. As the terminal takes all the keys, including Tab, the focus is moved
+// from it by the mouse or by the application.
+type Terminal struct {
+	twin.Box
+	ts TerminalStyle
+	vt *twin.VTerm
+	// title is the last terminal title set by the command
+	title string
+	// redrawing is true while the redraw is posted
+	redrawing atomic.Bool
+	// the fields below are protected by the lock
+	lock sync.Mutex
+	pty  *os.File
+	cmd  *exec.Cmd
+}
+
+type TerminalStyle struct {
+	terminal string
+	style    *tcell.Style
+}
+
+// defaultTerminalSize is the pty size till the terminal is drawn
+var defaultTerminalSize = twin.Size{Width: 80, Height: 24}
+
+func (ts TerminalStyle) WithTerminal(terminal string) TerminalStyle {
+	ts.terminal = terminal
+	return ts
+}
+
+// WithStyle sets the style of the cells with the default colors
+func (ts TerminalStyle) WithStyle(style tcell.Style) TerminalStyle {
+	ts.style = &style
+	return ts
+}
+
+// NewTerminal creates the terminal, the command is run by Start()
+func NewTerminal(owner twin.Component, ts TerminalStyle) (*Terminal, error) {
+	if ts.terminal == "" {
+		ts.terminal = "terminal"
+	}
+	t := &Terminal{ts: ts, vt: twin.NewVTerm(defaultTerminalSize)}
+	if err := t.Init(owner, t); err != nil {
+		return nil, err
+	}
+	t.SetOpaque(true)
+	initStyles(&t.Box, ts.terminal, ts.style, nil)
+	return t, nil
+}
+
+// Start runs the cmd in the terminal, the onExit is called from the twin go-routine with the
+// cmd.Wait() result, when the command exits. Only one command may run at a time.
+func (t *Terminal) Start(cmd *exec.Cmd, onExit func(err error)) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.cmd != nil {
+		return fmt.Errorf("the terminal runs %s already: %w", t.cmd.Path, errors.ErrExist)
+	}
+	if sz := t.Bounds().Size(); sz.Width > 0 && sz.Height > 0 {
+		t.vt.Resize(sz)
+	}
+	pty, err := twin.StartPty(cmd, t.vt.Size())
+	if err != nil {
+		return err
+	}
+	t.vt.SetReply(pty)
+	t.pty, t.cmd = pty, cmd
+	go t.read(pty, cmd, onExit)
+	return nil
+}
+
+// Stop kills the command running
+func (t *Terminal) Stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.cmd != nil {
+		_ = t.cmd.Process.Kill()
+	}
+}
+
+// VTerm returns the terminal emulator, which keeps the screen
+func (t *Terminal) VTerm() *twin.VTerm {
+	return t.vt
+}
+
+// read copies the command output to the VTerm till the command exits
+func (t *Terminal) read(pty *os.File, cmd *exec.Cmd, onExit func(err error)) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := pty.Read(buf)
+		if n > 0 {
+			_, _ = t.vt.Write(buf[:n])
+			t.postRedraw()
+		}
+		if err != nil {
+			break
+		}
+	}
+	err := cmd.Wait()
+	t.lock.Lock()
+	t.vt.SetReply(nil)
+	pty.Close()
+	t.pty, t.cmd = nil, nil
+	t.lock.Unlock()
+	if onExit != nil {
+		twin.Invoke(func() { onExit(err) })
+	}
+}
+
+// postRedraw posts the redraw to the twin go-routine, if it is not posted yet
+func (t *Terminal) postRedraw() {
+	if t.redrawing.CompareAndSwap(false, true) {
+		twin.Invoke(func() {
+			t.redrawing.Store(false)
+			twin.Redraw(twin.This(t))
+		})
+	}
+}
+
+// send writes the input to the command
+func (t *Terminal) send(b []byte) bool {
+	t.lock.Lock()
+	pty := t.pty
+	t.lock.Unlock()
+	if pty == nil {
+		return false
+	}
+	_, _ = pty.Write(b)
+	return true
+}
+
+func (t *Terminal) OnDraw(cc *twin.CanvasContext) {
+	sz := t.Bounds().Size()
+	if sz.Width > 0 && sz.Height > 0 && sz != t.vt.Size() {
+		t.vt.Resize(sz)
+		t.lock.Lock()
+		if t.pty != nil {
+			_ = twin.SetPtySize(t.pty, sz)
+		}
+		t.lock.Unlock()
+	}
+	active := twin.IsActive(t)
+	t.vt.Draw(cc, twin.Point{}, twin.EffectiveStyle(t), active)
+	if title := t.vt.Title(); active && title != t.title {
+		t.title = title
+		twin.SetTitle(title)
+	}
+}
+
+func (t *Terminal) OnKeyPressed(ke *tcell.EventKey) bool {
+	if seq := t.vt.KeySequence(ke); seq != nil && t.send(seq) {
+		return true
+	}
+	return t.Box.OnKeyPressed(ke)
+}
+
+func (t *Terminal) OnPaste(text string) bool {
+	text = strings.ReplaceAll(text, "\n", "\r")
+	if t.vt.BracketedPaste() {
+		text = "\x1b[200~" + text + "\x1b[201~"
+	}
+	return t.send([]byte(text))
+}
+
+func (t *Terminal) CanBeFocused() bool { return true }
+
+func (t *Terminal) OnClosed() {
+	t.Stop()
+	t.Box.OnClosed()
+}
@@ -0,0 +1,280 @@
+package components
+
+import (
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/rivo/uniseg"
+	"strings"
+)
+
+// WrapMode defines what to do with the lines, which are longer than the layout width
+type WrapMode int
+
+const (
+	// WrapNone cuts the long lines
+	WrapNone = WrapMode(iota)
+	// WrapWord moves the words, which don't fit the width, to the next line
+	WrapWord
+	// WrapChar moves the chars, which don't fit the width, to the next line
+	WrapChar
+)
+
+type VerticalAlignment int
+
+const (
+	AllignTop = VerticalAlignment(iota)
+	AllignMiddle
+	AllignBottom
+)
+
+// TextLayout places the text lines within the rectangle of Width x Height cells
+type TextLayout struct {
+	Width int
+	// Height limits the number of lines, 0 means no limit
+	Height int
+	Wrap   WrapMode
+	// Ellipsis is added to the line, which is cut, and to the last line if not all lines fit the Height
+	Ellipsis string
+	// PreserveIndent keeps the leading spaces of the lines, the wrapped lines get the same indent.
+	// If false, the lines are trimmed.
+	PreserveIndent bool
+	Alignment      TextAlignment
+	VAlignment     VerticalAlignment
+}
+
+// TextLine is the line of the text and its position in the layout rectangle
+type TextLine struct {
+	Text   twin.Text
+	Offset twin.Point
+}
+
+// glyph is one grapheme cluster of the text
+type glyph struct {
+	str   string
+	width int
+	span  int
+	// brk is true if the line may be broken after the glyph
+	brk   bool
+	space bool
+}
+
+// Layout splits the text t on the lines according to the layout settings
+func (tl TextLayout) Layout(t twin.Text) []TextLine {
+	lines := tl.lines(t)
+	if tl.Height > 0 && len(lines) > tl.Height {
+		lines = lines[:tl.Height]
+		if tl.Ellipsis != "" {
+			last := lines[len(lines)-1]
+			lines[len(lines)-1] = withEllipsis(last.glyphs, last.spans, tl.Width, tl.Ellipsis)
+		}
+	}
+	y := 0
+	if tl.Height > len(lines) {
+		switch tl.VAlignment {
+		case AllignMiddle:
+			y = (tl.Height - len(lines)) / 2
+		case AllignBottom:
+			y = tl.Height - len(lines)
+		}
+	}
+	res := make([]TextLine, 0, len(lines))
+	for i, ln := range lines {
+		w := glyphsWidth(ln.glyphs)
+		x := 0
+		switch tl.Alignment {
+		case AllignRight:
+			x = max(0, tl.Width-w)
+		case AllignCenter:
+			x = max(0, (tl.Width-w)/2)
+		}
+		res = append(res, TextLine{Text: ln.text(), Offset: twin.Point{X: x, Y: y + i}})
+	}
+	return res
+}
+
+// Measure returns the number of lines the text t takes with the layout width, the Height
+// is not taken into account
+func (tl TextLayout) Measure(t twin.Text) int {
+	tl.Height = 0
+	return len(tl.lines(t))
+}
+
+type glyphLine struct {
+	glyphs []glyph
+	spans  twin.Text
+}
+
+func (gl glyphLine) text() twin.Text {
+	var res twin.Text
+	var sb strings.Builder
+	for i, g := range gl.glyphs {
+		sb.WriteString(g.str)
+		if i == len(gl.glyphs)-1 || gl.glyphs[i+1].span != g.span {
+			s := gl.spans[g.span]
+			s.Text = sb.String()
+			res = append(res, s)
+			sb.Reset()
+		}
+	}
+	return res
+}
+
+func (tl TextLayout) lines(t twin.Text) []glyphLine {
+	var res []glyphLine
+	for _, line := range t.Lines() {
+		if !tl.PreserveIndent {
+			line = line.TrimSpace()
+		}
+		glyphs := toGlyphs(line)
+		if tl.PreserveIndent {
+			for len(glyphs) > 0 && glyphs[len(glyphs)-1].space {
+				glyphs = glyphs[:len(glyphs)-1]
+			}
+		}
+		switch {
+		case tl.Width <= 0:
+			res = append(res, glyphLine{glyphs: glyphs, spans: line})
+		case tl.Wrap == WrapNone:
+			if glyphsWidth(glyphs) > tl.Width {
+				res = append(res, withEllipsis(glyphs, line, tl.Width, tl.Ellipsis))
+			} else {
+				res = append(res, glyphLine{glyphs: glyphs, spans: line})
+			}
+		default:
+			for _, gs := range tl.wrap(glyphs) {
+				res = append(res, glyphLine{glyphs: gs, spans: line})
+			}
+		}
+	}
+	return res
+}
+
+// wrap splits the glyphs on the lines no longer than the width
+func (tl TextLayout) wrap(glyphs []glyph) [][]glyph {
+	var indent []glyph
+	if tl.PreserveIndent {
+		for _, g := range glyphs {
+			if !g.space {
+				break
+			}
+			indent = append(indent, g)
+		}
+		if glyphsWidth(indent) >= tl.Width/2 {
+			indent = nil // no room for the text
+		}
+	}
+	var res [][]glyph
+	var cur []glyph
+	w, brk := 0, -1
+	newLine := func(rest []glyph) {
+		if ln := trimGlyphs(cur); len(ln) > 0 {
+			res = append(res, ln)
+		}
+		cur = append(append([]glyph{}, indent...), rest...)
+		w, brk = glyphsWidth(cur), -1
+	}
+	for _, g := range glyphs {
+		if w+g.width > tl.Width {
+			if g.space {
+				// the space at the end of the line is dropped
+				brk = len(cur)
+				continue
+			}
+			if tl.Wrap == WrapWord && brk > len(indent) {
+				rest := append([]glyph{}, cur[brk:]...)
+				cur = cur[:brk]
+				newLine(rest)
+			}
+			if w+g.width > tl.Width && len(cur) > len(indent) {
+				newLine(nil)
+			}
+			if w+g.width > tl.Width {
+				// the glyph is wider than the layout, it takes the line alone and is clipped, when drawn
+				cur = []glyph{g}
+				newLine(nil)
+				continue
+			}
+		}
+		cur = append(cur, g)
+		w += g.width
+		if g.brk {
+			brk = len(cur)
+		}
+	}
+	if ln := trimGlyphs(cur); len(ln) > 0 || len(res) == 0 {
+		res = append(res, ln)
+	}
+	return res
+}
+
+// withEllipsis cuts the glyphs to the width and adds the ellipsis to the end, the width 0
+// means no limit
+func withEllipsis(glyphs []glyph, spans twin.Text, width int, ellipsis string) glyphLine {
+	ew := twin.StringWidth(ellipsis)
+	if width <= 0 {
+		width = glyphsWidth(glyphs) + ew
+	}
+	if ew > width {
+		ellipsis, ew = "", 0
+	}
+	w := 0
+	n := 0
+	for ; n < len(glyphs) && w+glyphs[n].width <= width-ew; n++ {
+		w += glyphs[n].width
+	}
+	gs := trimGlyphs(glyphs[:n:n])
+	if ellipsis != "" {
+		span := 0
+		if len(gs) > 0 {
+			span = gs[len(gs)-1].span
+		}
+		if len(spans) == 0 {
+			spans = twin.Text{{}}
+		}
+		gs = append(gs, glyph{str: ellipsis, width: ew, span: span})
+	}
+	return glyphLine{glyphs: gs, spans: spans}
+}
+
+// toGlyphs splits the text on the grapheme clusters and marks the line break opportunities
+func toGlyphs(t twin.Text) []glyph {
+	plain := t.String()
+	brks := make(map[int]bool)
+	offs, state := 0, -1
+	for rest := plain; rest != ""; {
+		var seg string
+		seg, rest, _, state = uniseg.FirstLineSegmentInString(rest, state)
+		offs += len(seg)
+		brks[offs] = true
+	}
+	var res []glyph
+	offs = 0
+	for i, s := range t {
+		state = -1
+		for rest := s.Text; rest != ""; {
+			var cl string
+			cl, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+			offs += len(cl)
+			w := twin.ClusterWidth(cl)
+			if cl == "\t" {
+				cl, w = "    ", 4
+			}
+			res = append(res, glyph{str: cl, width: w, span: i, brk: brks[offs], space: strings.TrimSpace(cl) == ""})
+		}
+	}
+	return res
+}
+
+func trimGlyphs(gs []glyph) []glyph {
+	for len(gs) > 0 && gs[len(gs)-1].space {
+		gs = gs[:len(gs)-1]
+	}
+	return gs
+}
+
+func glyphsWidth(gs []glyph) int {
+	w := 0
+	for _, g := range gs {
+		w += g.width
+	}
+	return w
+}
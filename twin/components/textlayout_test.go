@@ -0,0 +1,61 @@
+package components
+
+import (
+	"fmt"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTextLayout_Layout(t *testing.T) {
+	tests := []struct {
+		name string
+		tl   TextLayout
+		text string
+		want []string
+	}{
+		{"no width", TextLayout{}, "hello world\n  next ", []string{"0,0:hello world", "0,1:next"}},
+		{"cut", TextLayout{Width: 5}, "hello world", []string{"0,0:hello"}},
+		{"cut ellipsis", TextLayout{Width: 6, Ellipsis: "…"}, "hello world", []string{"0,0:hello…"}},
+		{"wrap word", TextLayout{Width: 10, Wrap: WrapWord}, "hello big world", []string{"0,0:hello big", "0,1:world"}},
+		{"wrap long word", TextLayout{Width: 4, Wrap: WrapWord}, "ab cdefgh", []string{"0,0:ab", "0,1:cdef", "0,2:gh"}},
+		{"wrap char", TextLayout{Width: 4, Wrap: WrapChar}, "abcdefghij", []string{"0,0:abcd", "0,1:efgh", "0,2:ij"}},
+		{"wrap indent", TextLayout{Width: 8, Wrap: WrapWord, PreserveIndent: true}, "  aaa bbb ccc",
+			[]string{"0,0:  aaa", "0,1:  bbb", "0,2:  ccc"}},
+		{"height ellipsis", TextLayout{Width: 5, Height: 2, Wrap: WrapWord, Ellipsis: "…"}, "aaa bbb ccc",
+			[]string{"0,0:aaa", "0,1:bbb…"}},
+		{"height ellipsis no width", TextLayout{Height: 2, Ellipsis: "..."}, "a\nb\nc", []string{"0,0:a", "0,1:b..."}},
+		{"wide glyph", TextLayout{Width: 1, Wrap: WrapChar}, "世界", []string{"0,0:世", "0,1:界"}},
+		{"wide glyph word", TextLayout{Width: 1, Wrap: WrapWord}, "a世b", []string{"0,0:a", "0,1:世", "0,2:b"}},
+		{"align right", TextLayout{Width: 6, Alignment: AllignRight}, "ab", []string{"4,0:ab"}},
+		{"align right no width", TextLayout{Alignment: AllignRight}, "ab", []string{"0,0:ab"}},
+		{"align center", TextLayout{Width: 6, Alignment: AllignCenter}, "ab\nabcd", []string{"2,0:ab", "1,1:abcd"}},
+		{"align bottom", TextLayout{Width: 6, Height: 3, VAlignment: AllignBottom}, "ab", []string{"0,2:ab"}},
+		{"align middle", TextLayout{Width: 6, Height: 3, VAlignment: AllignMiddle}, "ab", []string{"0,1:ab"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var res []string
+			for _, ln := range tc.tl.Layout(twin.PlainText(tc.text)) {
+				res = append(res, fmt.Sprintf("%d,%d:%s", ln.Offset.X, ln.Offset.Y, ln.Text.String()))
+			}
+			assert.Equal(t, tc.want, res)
+		})
+	}
+}
+
+func TestTextLayout_Spans(t *testing.T) {
+	bold := twin.SpanStyle{Attrs: 1}
+	tl := TextLayout{Width: 6, Wrap: WrapWord}
+	lines := tl.Layout(twin.Text{{Text: "ab "}, {Text: "cd ef", Style: bold}})
+	assert.Equal(t, []TextLine{
+		{Text: twin.Text{{Text: "ab "}, {Text: "cd", Style: bold}}},
+		{Text: twin.Text{{Text: "ef", Style: bold}}, Offset: twin.Point{Y: 1}},
+	}, lines)
+}
+
+func TestTextLayout_Measure(t *testing.T) {
+	tl := TextLayout{Width: 4, Height: 1, Wrap: WrapChar}
+	assert.Equal(t, 3, tl.Measure(twin.PlainText("abcdefghij")))
+	assert.Equal(t, 2, tl.Measure(twin.PlainText("ab\n世界")))
+}
@@ -0,0 +1,551 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ThemeFormat defines the theme file format
+type ThemeFormat string
+
+const (
+	ThemeFormatJSON = ThemeFormat("json")
+	ThemeFormatYAML = ThemeFormat("yaml")
+	ThemeFormatTOML = ThemeFormat("toml")
+)
+
+// ThemeBaseKey is the theme file key, which value is the name of the registered theme the
+// file theme inherits from. If the key is not specified, the "default" theme is the base one.
+const ThemeBaseKey = "base"
+
+var (
+	themesLock sync.Mutex
+	themes     = map[string]func() Theme{
+		"default":       GetDefaultTheme,
+		"dark":          GetDarkTheme,
+		"light":         GetLightTheme,
+		"high-contrast": GetHighContrastTheme,
+	}
+
+	styleType     = reflect.TypeOf(tcell.StyleDefault)
+	colorType     = reflect.TypeOf(tcell.ColorDefault)
+	alignmentType = reflect.TypeOf(AllignLeft)
+	rectStyleType = reflect.TypeOf(twin.CanvasRectangleSingle)
+	flagsType     = reflect.TypeOf(WindowFlagHasBorderBM)
+
+	attrNames = map[string]tcell.AttrMask{
+		"bold":          tcell.AttrBold,
+		"blink":         tcell.AttrBlink,
+		"reverse":       tcell.AttrReverse,
+		"underline":     tcell.AttrUnderline,
+		"dim":           tcell.AttrDim,
+		"italic":        tcell.AttrItalic,
+		"strikethrough": tcell.AttrStrikeThrough,
+	}
+	alignmentNames = map[string]TextAlignment{
+		"left":   AllignLeft,
+		"right":  AllignRight,
+		"center": AllignCenter,
+	}
+	rectStyleNames = map[string]twin.CanvasRectangleStyle{
+		"single":  twin.CanvasRectangleSingle,
+		"double":  twin.CanvasRectangleDouble,
+		"rounded": twin.CanvasRectangleRounded,
+		"bold":    twin.CanvasRectangleBold,
+	}
+	flagNames = map[string]WindowFlags{
+		"border":   WindowFlagHasBorderBM,
+		"vscroll":  WindowFlagHasVerticalScrollBM,
+		"hscroll":  WindowFlagHasHorizontalScrollBM,
+		"scrolls":  WindowFlagHasBothScrollsBM,
+		"autohide": WindowFlagAutoHideScrollBM,
+	}
+)
+
+// RegisterTheme registers the theme t by the name, so it can be used as the base theme
+// in the theme files, or can be got by GetTheme()
+func RegisterTheme(name string, t Theme) {
+	t = copyTheme(t)
+	themesLock.Lock()
+	defer themesLock.Unlock()
+	themes[name] = func() Theme { return copyTheme(t) }
+}
+
+// GetTheme returns the registered theme by its name. The built-in themes are "default", "dark",
+// "light" and "high-contrast"
+func GetTheme(name string) (Theme, error) {
+	themesLock.Lock()
+	f, ok := themes[name]
+	themesLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("the theme %q is not registered: %w", name, errors.ErrNotExist)
+	}
+	return f(), nil
+}
+
+// LoadTheme reads the theme from the file. The file format is defined by its extension:
+// .json, .yaml, .yml or .toml
+func LoadTheme(path string) (Theme, error) {
+	var format ThemeFormat
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = ThemeFormatJSON
+	case ".yaml", ".yml":
+		format = ThemeFormatYAML
+	case ".toml":
+		format = ThemeFormatTOML
+	default:
+		return nil, fmt.Errorf("unknown theme file format %q: %w", path, errors.ErrInvalid)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := ParseTheme(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("the theme file %q: %w", path, err)
+	}
+	return t, nil
+}
+
+// ParseTheme parses the theme from data. The theme contains the theme values, which override
+// the base theme values (see ThemeBaseKey). Only the fields, which are specified, are overridden,
+// for example (YAML):
+//
+//	base: dark
+//	button:
+//	  active: "black:yellow:bold"
+//	  alignment: left
+//	win:
+//	  notActive: {fg: white, bg: "#202020"}
+//	  flags: [border, scrolls]
+//	  activeRectStyle: double
+//	rules:
+//	  "button.primary:focused": "white:green:bold"
+//
+// The style may be defined by the string "fg:bg:attrs", where any part may be empty, or by the
+// object with fg, bg and attrs fields. The attributes are separated by '|'. The color is the
+// color name, "#rrggbb", "#rgb" or "rgb(r,g,b)". The RGB colors are shown as the nearest palette
+// colors on the terminals, which don't support the true colors.
+func ParseTheme(data []byte, format ThemeFormat) (Theme, error) {
+	var m map[string]any
+	var err error
+	switch format {
+	case ThemeFormatJSON:
+		err = json.Unmarshal(data, &m)
+	case ThemeFormatYAML:
+		err = yaml.Unmarshal(data, &m)
+	case ThemeFormatTOML:
+		err = toml.Unmarshal(data, &m)
+	default:
+		return nil, fmt.Errorf("unknown theme format %q: %w", format, errors.ErrInvalid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the theme: %w", err)
+	}
+	base := "default"
+	if b, ok := m[ThemeBaseKey]; ok {
+		if base, ok = b.(string); !ok {
+			return nil, fmt.Errorf("the %q must be a string: %w", ThemeBaseKey, errors.ErrInvalid)
+		}
+		delete(m, ThemeBaseKey)
+	}
+	t, err := GetTheme(base)
+	if err != nil {
+		return nil, err
+	}
+	for name, src := range m {
+		v, ok := t[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown theme value %q: %w", name, errors.ErrInvalid)
+		}
+		dst := reflect.New(reflect.TypeOf(v)).Elem()
+		dst.Set(reflect.ValueOf(v))
+		if err := applyThemeValue(dst, src, name); err != nil {
+			return nil, err
+		}
+		t[name] = dst.Interface()
+	}
+	return t, nil
+}
+
+func GetDarkTheme() Theme {
+	t := GetDefaultTheme()
+	win := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorSilver)
+	active := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorWhite)
+	input := tcell.StyleDefault.Background(tcell.ColorDarkSlateGray).Foreground(tcell.ColorWhite)
+	inputActive := tcell.StyleDefault.Background(tcell.ColorGray).Foreground(tcell.ColorWhite)
+	disabled := win.Foreground(tcell.ColorDimGray)
+	inputDisabled := input.Foreground(tcell.ColorGray)
+	t.setWindows(win, active, disabled)
+	t["button"] = ButtonTheme{NotActive: input, Active: inputActive.Bold(true), Disabled: inputDisabled, Alignment: AllignCenter}
+	t["label"] = LabelTheme{Style: win, Disabled: disabled, Alignment: AllignLeft}
+	t["listbox"] = ListBoxTheme{SelStyle: input, SelActive: inputActive}
+	t["editline"] = EditLineTheme{NotActive: input, Active: inputActive, Disabled: inputDisabled}
+	t["checkbox"] = CheckBoxTheme{NotActive: win, Active: active.Bold(true), Disabled: disabled}
+	t["choicebox"] = ChoiceBoxTheme{NotActive: input, Active: inputActive, Disabled: inputDisabled}
+	t["form"] = FormTheme{Style: win}
+	t["formLabel"] = LabelTheme{Style: win, Disabled: disabled, Alignment: AllignLeft}
+	t["formError"] = LabelTheme{Style: win.Foreground(tcell.ColorRed), Alignment: AllignLeft}
+	t["logview"] = LogViewTheme{Error: tcell.ColorRed, Warn: tcell.ColorYellow, Debug: tcell.ColorGray, Trace: tcell.ColorDimGray, Match: inputActive.Bold(true)}
+	t["terminal"] = TerminalTheme{Style: win}
+	return t
+}
+
+func GetLightTheme() Theme {
+	t := GetDefaultTheme()
+	win := tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack)
+	active := tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorNavy)
+	input := tcell.StyleDefault.Background(tcell.ColorSilver).Foreground(tcell.ColorBlack)
+	inputActive := tcell.StyleDefault.Background(tcell.ColorLightBlue).Foreground(tcell.ColorBlack)
+	disabled := win.Foreground(tcell.ColorDarkGray)
+	inputDisabled := input.Foreground(tcell.ColorGray)
+	t.setWindows(win, active, disabled)
+	t["button"] = ButtonTheme{NotActive: input, Active: inputActive, Disabled: inputDisabled, Alignment: AllignCenter}
+	t["label"] = LabelTheme{Style: win, Disabled: disabled, Alignment: AllignLeft}
+	t["listbox"] = ListBoxTheme{SelStyle: input, SelActive: inputActive}
+	t["editline"] = EditLineTheme{NotActive: input, Active: inputActive, Disabled: inputDisabled}
+	t["checkbox"] = CheckBoxTheme{NotActive: win, Active: active, Disabled: disabled}
+	t["choicebox"] = ChoiceBoxTheme{NotActive: input, Active: inputActive, Disabled: inputDisabled}
+	t["form"] = FormTheme{Style: win}
+	t["formLabel"] = LabelTheme{Style: win, Disabled: disabled, Alignment: AllignLeft}
+	t["formError"] = LabelTheme{Style: win.Foreground(tcell.ColorMaroon), Alignment: AllignLeft}
+	t["logview"] = LogViewTheme{Error: tcell.ColorMaroon, Warn: tcell.ColorOlive, Debug: tcell.ColorGray, Trace: tcell.ColorDarkGray, Match: inputActive}
+	t["terminal"] = TerminalTheme{Style: win}
+	return t
+}
+
+func GetHighContrastTheme() Theme {
+	t := GetDefaultTheme()
+	win := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorWhite)
+	active := tcell.StyleDefault.Background(tcell.ColorBlack).Foreground(tcell.ColorYellow).Bold(true)
+	sel := tcell.StyleDefault.Background(tcell.ColorYellow).Foreground(tcell.ColorBlack).Bold(true)
+	disabled := win.Foreground(tcell.ColorGray).StrikeThrough(true)
+	t.setWindows(win, active, win.Foreground(tcell.ColorGray))
+	t["button"] = ButtonTheme{NotActive: win.Reverse(true), Active: sel, Disabled: disabled, Alignment: AllignCenter}
+	t["label"] = LabelTheme{Style: win, Disabled: win.Foreground(tcell.ColorGray), Alignment: AllignLeft}
+	t["listbox"] = ListBoxTheme{SelStyle: win.Reverse(true), SelActive: sel}
+	t["editline"] = EditLineTheme{NotActive: win.Underline(true), Active: sel, Disabled: disabled}
+	t["checkbox"] = CheckBoxTheme{NotActive: win, Active: sel, Disabled: disabled}
+	t["choicebox"] = ChoiceBoxTheme{NotActive: win.Underline(true), Active: sel, Disabled: disabled}
+	t["form"] = FormTheme{Style: win}
+	t["formLabel"] = LabelTheme{Style: win.Bold(true), Disabled: win.Foreground(tcell.ColorGray), Alignment: AllignLeft}
+	t["formError"] = LabelTheme{Style: win.Foreground(tcell.ColorRed).Bold(true), Alignment: AllignLeft}
+	t["logview"] = LogViewTheme{Error: tcell.ColorRed, Warn: tcell.ColorYellow, Debug: tcell.ColorWhite, Trace: tcell.ColorGray, Match: sel}
+	t["terminal"] = TerminalTheme{Style: win}
+	return t
+}
+
+// setWindows sets the styles for all the window themes
+func (t Theme) setWindows(notActive, active, disabled tcell.Style) {
+	for name, v := range t {
+		if wt, ok := v.(WindowTheme); ok {
+			wt.NotActive, wt.Active, wt.Disabled = notActive, active, disabled
+			t[name] = wt
+		}
+	}
+}
+
+// applyThemeValue sets the src, read from the theme file, to dst. The path is used for the errors
+func applyThemeValue(dst reflect.Value, src any, path string) error {
+	var err error
+	switch dst.Type() {
+	case styleType:
+		var s tcell.Style
+		if s, err = parseStyle(dst.Interface().(tcell.Style), src); err == nil {
+			dst.Set(reflect.ValueOf(s))
+		}
+	case colorType:
+		var clr tcell.Color
+		if clr, err = parseColor(src); err == nil {
+			dst.Set(reflect.ValueOf(clr))
+		}
+	case alignmentType:
+		err = setByName(dst, src, alignmentNames)
+	case rectStyleType:
+		err = setByName(dst, src, rectStyleNames)
+	case flagsType:
+		var flags WindowFlags
+		if flags, err = parseFlags(src); err == nil {
+			dst.Set(reflect.ValueOf(flags))
+		}
+	default:
+		if dst.Kind() == reflect.Struct {
+			return applyThemeStruct(dst, src, path)
+		}
+		if dst.Kind() == reflect.Map && dst.Type().Key().Kind() == reflect.String {
+			return applyThemeMap(dst, src, path)
+		}
+		err = setBasic(dst, src)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func applyThemeStruct(dst reflect.Value, src any, path string) error {
+	m, ok := src.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: an object is expected, but %T: %w", path, src, errors.ErrInvalid)
+	}
+	for k, v := range m {
+		fv := dst.FieldByNameFunc(func(fn string) bool { return strings.EqualFold(fn, k) })
+		if !fv.IsValid() || !fv.CanSet() {
+			return fmt.Errorf("%s: unknown field %q: %w", path, k, errors.ErrInvalid)
+		}
+		if err := applyThemeValue(fv, v, path+"."+k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyThemeMap(dst reflect.Value, src any, path string) error {
+	m, ok := src.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s: an object is expected, but %T: %w", path, src, errors.ErrInvalid)
+	}
+	res := reflect.MakeMapWithSize(dst.Type(), dst.Len()+len(m))
+	for _, k := range dst.MapKeys() {
+		res.SetMapIndex(k, dst.MapIndex(k))
+	}
+	for k, v := range m {
+		kv := reflect.ValueOf(k).Convert(dst.Type().Key())
+		ev := reflect.New(dst.Type().Elem()).Elem()
+		if old := res.MapIndex(kv); old.IsValid() {
+			ev.Set(old)
+		}
+		if err := applyThemeValue(ev, v, path+"."+k); err != nil {
+			return err
+		}
+		res.SetMapIndex(kv, ev)
+	}
+	dst.Set(res)
+	return nil
+}
+
+// parseStyle applies src to the style s. src may be "fg:bg:attrs" string or an object with
+// the fg, bg and attrs fields
+func parseStyle(s tcell.Style, src any) (tcell.Style, error) {
+	var fg, bg, attrs any
+	switch v := src.(type) {
+	case string:
+		parts := strings.Split(v, ":")
+		if len(parts) > 3 {
+			return s, fmt.Errorf("the style %q must be in \"fg:bg:attrs\" form: %w", v, errors.ErrInvalid)
+		}
+		parts = append(parts, "", "", "")
+		fg, bg, attrs = parts[0], parts[1], parts[2]
+	case map[string]any:
+		for k, fv := range v {
+			switch strings.ToLower(k) {
+			case "fg", "foreground":
+				fg = fv
+			case "bg", "background":
+				bg = fv
+			case "attrs", "attributes":
+				attrs = fv
+			default:
+				return s, fmt.Errorf("unknown style field %q: %w", k, errors.ErrInvalid)
+			}
+		}
+	default:
+		return s, fmt.Errorf("a string or an object is expected for the style, but %T: %w", src, errors.ErrInvalid)
+	}
+	if fg != nil && fg != "" {
+		clr, err := parseColor(fg)
+		if err != nil {
+			return s, err
+		}
+		s = s.Foreground(clr)
+	}
+	if bg != nil && bg != "" {
+		clr, err := parseColor(bg)
+		if err != nil {
+			return s, err
+		}
+		s = s.Background(clr)
+	}
+	if attrs != nil && attrs != "" {
+		am, err := parseAttrs(attrs)
+		if err != nil {
+			return s, err
+		}
+		s = s.Attributes(am).Underline(am&tcell.AttrUnderline != 0)
+	}
+	return s, nil
+}
+
+func parseColor(src any) (tcell.Color, error) {
+	name, ok := src.(string)
+	if !ok {
+		return tcell.ColorDefault, fmt.Errorf("the color name is expected, but %T: %w", src, errors.ErrInvalid)
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "default" || name == "reset" {
+		return tcell.ColorReset, nil
+	}
+	if strings.HasPrefix(name, "rgb(") && strings.HasSuffix(name, ")") {
+		var r, g, b int32
+		if _, err := fmt.Sscanf(name, "rgb(%d,%d,%d)", &r, &g, &b); err != nil || max(r, g, b) > 255 || min(r, g, b) < 0 {
+			return tcell.ColorDefault, fmt.Errorf("invalid color %q, rgb(0..255,0..255,0..255) is expected: %w", name, errors.ErrInvalid)
+		}
+		return tcell.NewRGBColor(r, g, b), nil
+	}
+	if len(name) == 4 && name[0] == '#' {
+		// the short form #rgb
+		name = string([]byte{'#', name[1], name[1], name[2], name[2], name[3], name[3]})
+	}
+	clr := tcell.GetColor(name)
+	if clr == tcell.ColorDefault {
+		return clr, fmt.Errorf("unknown color %q: %w", name, errors.ErrInvalid)
+	}
+	return clr, nil
+}
+
+func parseAttrs(src any) (tcell.AttrMask, error) {
+	var names []string
+	switch v := src.(type) {
+	case string:
+		names = strings.Split(v, "|")
+	case []any:
+		for _, n := range v {
+			s, ok := n.(string)
+			if !ok {
+				return 0, fmt.Errorf("the attribute name is expected, but %T: %w", n, errors.ErrInvalid)
+			}
+			names = append(names, s)
+		}
+	default:
+		return 0, fmt.Errorf("the attributes must be a string or a list, but %T: %w", src, errors.ErrInvalid)
+	}
+	var res tcell.AttrMask
+	for _, n := range names {
+		n = strings.ToLower(strings.TrimSpace(n))
+		if n == "" || n == "none" {
+			continue
+		}
+		am, ok := attrNames[n]
+		if !ok {
+			return 0, fmt.Errorf("unknown attribute %q: %w", n, errors.ErrInvalid)
+		}
+		res |= am
+	}
+	return res, nil
+}
+
+func parseFlags(src any) (WindowFlags, error) {
+	var names []any
+	switch v := src.(type) {
+	case string:
+		for _, n := range strings.Split(v, "|") {
+			names = append(names, n)
+		}
+	case []any:
+		names = v
+	default:
+		i, err := toInt64(src)
+		return WindowFlags(i), err
+	}
+	var res WindowFlags
+	for _, n := range names {
+		s, ok := n.(string)
+		if !ok {
+			return 0, fmt.Errorf("the flag name is expected, but %T: %w", n, errors.ErrInvalid)
+		}
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" || s == "none" {
+			continue
+		}
+		f, ok := flagNames[s]
+		if !ok {
+			return 0, fmt.Errorf("unknown window flag %q: %w", s, errors.ErrInvalid)
+		}
+		res |= f
+	}
+	return res, nil
+}
+
+func setByName[T any](dst reflect.Value, src any, names map[string]T) error {
+	if s, ok := src.(string); ok {
+		v, ok := names[strings.ToLower(strings.TrimSpace(s))]
+		if !ok {
+			return fmt.Errorf("unknown value %q: %w", s, errors.ErrInvalid)
+		}
+		dst.Set(reflect.ValueOf(v))
+		return nil
+	}
+	return setBasic(dst, src)
+}
+
+// setBasic sets the numbers, strings and bools
+func setBasic(dst reflect.Value, src any) error {
+	switch dst.Kind() {
+	case reflect.String:
+		s, ok := src.(string)
+		if !ok {
+			return fmt.Errorf("a string is expected, but %T: %w", src, errors.ErrInvalid)
+		}
+		dst.SetString(s)
+	case reflect.Bool:
+		b, ok := src.(bool)
+		if !ok {
+			return fmt.Errorf("a bool is expected, but %T: %w", src, errors.ErrInvalid)
+		}
+		dst.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt64(src)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := toInt64(src)
+		if err != nil {
+			return err
+		}
+		dst.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		rv := reflect.ValueOf(src)
+		if !rv.IsValid() || !rv.CanConvert(dst.Type()) || rv.Kind() == reflect.String {
+			return fmt.Errorf("a number is expected, but %T: %w", src, errors.ErrInvalid)
+		}
+		dst.Set(rv.Convert(dst.Type()))
+	default:
+		return fmt.Errorf("the type %s is not supported: %w", dst.Type(), errors.ErrInvalid)
+	}
+	return nil
+}
+
+func toInt64(src any) (int64, error) {
+	switch v := src.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("an integer is expected, but %v: %w", v, errors.ErrInvalid)
+		}
+		return int64(v), nil
+	case string:
+		i, err := strconv.ParseInt(v, 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("an integer is expected, but %q: %w", v, errors.ErrInvalid)
+		}
+		return i, nil
+	}
+	return 0, fmt.Errorf("an integer is expected, but %T: %w", src, errors.ErrInvalid)
+}
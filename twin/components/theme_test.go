@@ -0,0 +1,179 @@
+package components
+
+import (
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/dspasibenko/twin-go/twin"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testThemes = map[ThemeFormat]string{
+	ThemeFormatJSON: `{
+	"base": "dark",
+	"button": {"active": "black:yellow:bold", "alignment": "left"},
+	"win": {"notActive": {"fg": "white", "bg": "#202020"}, "flags": ["border", "scrolls"], "activeRectStyle": "single"},
+	"logview": {"error": "rgb(255,0,0)"},
+	"rules": {"button.primary:focused": "white:green:bold|underline"}
+}`,
+	ThemeFormatYAML: `
+base: dark
+button:
+  active: "black:yellow:bold"
+  alignment: left
+win:
+  notActive: {fg: white, bg: "#202020"}
+  flags: [border, scrolls]
+  activeRectStyle: single
+logview:
+  error: rgb(255,0,0)
+rules:
+  "button.primary:focused": "white:green:bold|underline"
+`,
+	ThemeFormatTOML: `
+base = "dark"
+[button]
+active = "black:yellow:bold"
+alignment = "left"
+[win]
+flags = ["border", "scrolls"]
+activeRectStyle = "single"
+[win.notActive]
+fg = "white"
+bg = "#202020"
+[logview]
+error = "rgb(255,0,0)"
+[rules]
+"button.primary:focused" = "white:green:bold|underline"
+`,
+}
+
+func TestParseTheme(t *testing.T) {
+	dark := GetDarkTheme()
+	button := dark["button"].(ButtonTheme)
+	button.Active = button.Active.Foreground(tcell.ColorBlack).Background(tcell.ColorYellow).Attributes(tcell.AttrBold)
+	button.Alignment = AllignLeft
+	win := dark["win"].(WindowTheme)
+	win.NotActive = win.NotActive.Foreground(tcell.ColorWhite).Background(tcell.NewHexColor(0x202020))
+	win.Flags = WindowFlagHasBorderBM | WindowFlagHasBothScrollsBM
+	win.ActiveRectStyle = twin.CanvasRectangleSingle
+	logview := dark["logview"].(LogViewTheme)
+	logview.Error = tcell.NewRGBColor(255, 0, 0)
+	rules := StyleRules{"button.primary:focused": tcell.StyleDefault.Foreground(tcell.ColorWhite).
+		Background(tcell.ColorGreen).Bold(true).Underline(true)}
+
+	for format, data := range testThemes {
+		th, err := ParseTheme([]byte(data), format)
+		assert.Nil(t, err, format)
+		assert.Equal(t, button, th["button"], format)
+		assert.Equal(t, win, th["win"], format)
+		assert.Equal(t, logview, th["logview"], format)
+		assert.Equal(t, rules, th[ThemeRulesKey], format)
+		// the values, which are not specified, are taken from the base
+		assert.Equal(t, dark["label"], th["label"], format)
+		assert.Nil(t, SetTheme(th))
+	}
+	assert.Nil(t, SetTheme(GetDefaultTheme()))
+}
+
+func TestParseTheme_Errors(t *testing.T) {
+	for _, data := range []string{
+		`{"unknown": {}}`,
+		`{"button": {"size": 1}}`,
+		`{"button": "black"}`,
+		`{"button": {"active": "nocolor"}}`,
+		`{"button": {"active": "black:white:blinking"}}`,
+		`{"button": {"active": "a:b:c:d"}}`,
+		`{"button": {"active": {"color": "black"}}}`,
+		`{"button": {"alignment": "top"}}`,
+		`{"win": {"flags": ["border", "menu"]}}`,
+		`{"logview": {"error": "rgb(256,0,0)"}}`,
+		`{"logview": {"error": 1}}`,
+		`{"base": 1}`,
+	} {
+		_, err := ParseTheme([]byte(data), ThemeFormatJSON)
+		assert.ErrorIs(t, err, errors.ErrInvalid, data)
+	}
+	_, err := ParseTheme([]byte(`{"base": "unknown"}`), ThemeFormatJSON)
+	assert.ErrorIs(t, err, errors.ErrNotExist)
+	_, err = ParseTheme([]byte(`{`), ThemeFormatJSON)
+	assert.NotNil(t, err)
+	_, err = ParseTheme([]byte(`{}`), ThemeFormat("xml"))
+	assert.ErrorIs(t, err, errors.ErrInvalid)
+}
+
+func TestParseColor(t *testing.T) {
+	for src, want := range map[string]tcell.Color{
+		"red":              tcell.ColorRed,
+		" Navy ":           tcell.ColorNavy,
+		"default":          tcell.ColorReset,
+		"#102030":          tcell.NewHexColor(0x102030),
+		"#fa0":             tcell.NewHexColor(0xffaa00),
+		"rgb(1, 2, 3)":     tcell.NewRGBColor(1, 2, 3),
+		"RGB(255,255,255)": tcell.NewRGBColor(255, 255, 255),
+	} {
+		clr, err := parseColor(src)
+		assert.Nil(t, err, src)
+		assert.Equal(t, want, clr, src)
+	}
+	for _, src := range []any{"#12", "rgb(1,2)", "rgb(-1,0,0)", "reddish", 1} {
+		_, err := parseColor(src)
+		assert.ErrorIs(t, err, errors.ErrInvalid, src)
+	}
+}
+
+func TestLoadTheme(t *testing.T) {
+	dir := t.TempDir()
+	for name, format := range map[string]ThemeFormat{"t.json": ThemeFormatJSON, "t.YML": ThemeFormatYAML, "t.toml": ThemeFormatTOML} {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(path, []byte(testThemes[format]), 0o600))
+		th, err := LoadTheme(path)
+		assert.Nil(t, err, name)
+		assert.Equal(t, AllignLeft, th["button"].(ButtonTheme).Alignment, name)
+	}
+	_, err := LoadTheme(filepath.Join(dir, "t.ini"))
+	assert.ErrorIs(t, err, errors.ErrInvalid)
+	_, err = LoadTheme(filepath.Join(dir, "absent.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRegisterTheme(t *testing.T) {
+	th := GetDefaultTheme()
+	th["label"] = LabelTheme{Alignment: AllignRight}
+	RegisterTheme("test", th)
+	th["label"] = LabelTheme{}
+
+	th1, err := GetTheme("test")
+	assert.Nil(t, err)
+	assert.Equal(t, LabelTheme{Alignment: AllignRight}, th1["label"])
+	th1["label"] = LabelTheme{}
+	th2, _ := GetTheme("test")
+	assert.Equal(t, LabelTheme{Alignment: AllignRight}, th2["label"])
+
+	th3, err := ParseTheme([]byte(`{"base": "test", "label": {"alignment": "center"}}`), ThemeFormatJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, LabelTheme{Alignment: AllignCenter}, th3["label"])
+
+	_, err = GetTheme("absent")
+	assert.ErrorIs(t, err, errors.ErrNotExist)
+}
+
+func TestSetTheme(t *testing.T) {
+	defer SetTheme(GetDefaultTheme())
+	assert.ErrorIs(t, SetTheme(Theme{"button": LabelTheme{}}), errors.ErrInvalid)
+	assert.Nil(t, SetTheme(Theme{"button": ButtonTheme{Alignment: AllignRight}}))
+	assert.Equal(t, ButtonTheme{Alignment: AllignRight}, GetThemeValue[ButtonTheme]("button"))
+	// the values not set are default
+	assert.Equal(t, GetDefaultTheme()["label"], GetThemeValue[LabelTheme]("label"))
+	_, err := LookupThemeValue[ButtonTheme]("label")
+	assert.ErrorIs(t, err, errors.ErrInvalid)
+	_, err = LookupThemeValue[ButtonTheme]("absent")
+	assert.ErrorIs(t, err, errors.ErrNotExist)
+
+	// the missing values are taken from the default theme
+	theme.Store(Theme{})
+	assert.Equal(t, GetDefaultTheme()["label"], GetThemeValue[LabelTheme]("label"))
+	assert.Equal(t, ButtonTheme{}, GetThemeValue[ButtonTheme]("absent"))
+}
@@ -1,19 +1,63 @@
 package twin
 
 import (
+	"cmp"
 	"context"
 	"github.com/gdamore/tcell/v2"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type controller struct {
-	s        tcell.Screen
-	root     *rootContainer
-	done     chan struct{}
-	runs     atomic.Bool
-	lock     sync.Mutex
-	dirtySet map[Component]bool
+	// s is initialized once by screen() or SetScreen(), and must be accessed by screen()
+	s           tcell.Screen
+	screenOnce  sync.Once
+	screenReady atomic.Bool
+	root        *rootContainer
+	done        chan struct{}
+	runs        atomic.Bool
+	lock        sync.Mutex
+	// damage contains the screen areas (the physical coordinates), which must be redrawn
+	damage []Rectangle
+	// lastFocused is the focused component the listeners were notified about
+	lastFocused    Component
+	focusListeners []*focusListenerHolder
+	// events contains the component events, which are not delivered yet
+	events             []ComponentEvent
+	componentListeners []*componentListenerHolder
+	// invokes contains the functions passed to Invoke(), which are not called yet
+	invokes []func()
+	// hovered and pressed contain compHolder for the components under the mouse
+	hovered atomic.Value
+	pressed atomic.Value
+	// colors is the number of colors the terminal supports
+	colors            atomic.Int32
+	terminalListeners []*terminalListenerHolder
+	// termUnfocused is true if the terminal window lost the focus
+	termUnfocused atomic.Bool
+	// zSeq is the counter for the BringToFront() and SendToBack() calls order
+	zSeq atomic.Int64
+	// frameInterval is the minimal time between two frames in nanoseconds, see SetMaxFrameRate()
+	frameInterval atomic.Int64
+	// frameScheduled is true if the deferred frame is waited for
+	frameScheduled atomic.Bool
+	// the fields below are used by the run go-routine only
+	lastFrame    time.Time
+	mousePressed bool
+	// dragged is the component receiving the mouse drag, see Component.OnMouseDrag
+	dragged       Component
+	screenResized bool
+	// links contains the screen cells printed as the hyperlinks
+	links map[Point]linkCell
+	// pasted contains the keys of the bracketed paste in progress, it is nil if there is no paste
+	pasted  []*tcell.EventKey
+	resized map[Component]bool
+	// animations are called once per frame, see Animate()
+	animations    []*animationHolder
+	lastAnimation time.Time
 }
 
 type resizeEvent struct {
@@ -21,31 +65,67 @@ type resizeEvent struct {
 	comp Component
 }
 
+// linkCell is the hyperlink printed by comp to a screen cell
+type linkCell struct {
+	comp Component
+	link string
+}
+
+type frameEvent struct {
+	tcell.EventTime
+}
+
+// invokeEvent wakes the loop up to call the functions passed to Invoke()
+type invokeEvent struct {
+	tcell.EventTime
+}
+
 type activateEvent struct {
 	tcell.EventTime
 	comp Component
 }
 
+// defaultFrameRate is the default maximum number of frames drawn per second
+const defaultFrameRate = 60
+
+// maxDamageAreas is the number of the damaged areas, after which they are merged into one
+const maxDamageAreas = 16
+
 var c *controller
 
 func init() {
 	c = new(controller)
-	var err error
-	c.s, err = tcell.NewScreen()
-	if err != nil {
-		panic(err)
-	}
-	if err := c.s.Init(); err != nil {
-		panic(err)
-	}
-	c.s.EnableMouse()
-	//	c.s.EnablePaste()
-	//	c.s.EnableFocus()
-	c.dirtySet = make(map[Component]bool)
 	c.done = make(chan struct{})
+	c.resized = make(map[Component]bool)
+	c.links = make(map[Point]linkCell)
+	c.frameInterval.Store(int64(time.Second / defaultFrameRate))
 	c.root = newRootContainer()
 }
 
+// screen returns the screen, the terminal one is created on the first call, if it is not set by SetScreen()
+func (c *controller) screen() tcell.Screen {
+	c.screenOnce.Do(func() {
+		s, err := tcell.NewScreen()
+		if err != nil {
+			panic(err)
+		}
+		c.initScreen(s)
+	})
+	return c.s
+}
+
+func (c *controller) initScreen(s tcell.Screen) {
+	if err := s.Init(); err != nil {
+		panic(err)
+	}
+	s.EnableMouse()
+	s.EnablePaste()
+	s.EnableFocus()
+	c.colors.Store(int32(s.Colors()))
+	c.s = s
+	c.screenReady.Store(true)
+}
+
 func (c *controller) run() (context.Context, context.CancelFunc) {
 	if !c.runs.CompareAndSwap(false, true) {
 		panic("twin controller can be run once")
@@ -53,69 +133,136 @@ func (c *controller) run() (context.Context, context.CancelFunc) {
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		defer close(c.done)
-		defer c.s.Fini()
+		defer c.screen().Fini()
 		defer cancel()
 		defer c.closeAll()
-		c.s.Clear()
+		c.screen().Clear()
 		go func() {
 			<-ctx.Done() // if someone called cancel() here or there...
-			c.s.PostEvent(tcell.NewEventInterrupt(nil))
+			c.screen().PostEvent(tcell.NewEventInterrupt(nil))
 		}()
+		watchSuspend(ctx)
 		c.onScreenResize()
-		mousePressed := false
 		for {
 			c.onLoop()
-			e := c.s.PollEvent()
-			if e == nil {
-				// we're done
-				break
+			// all the pending events are handled before the next frame is drawn
+			if !c.onEvent(c.screen().PollEvent()) {
+				return
 			}
-			switch ev := e.(type) {
-			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyCtrlC {
+			for c.screen().HasPendingEvent() {
+				if !c.onEvent(c.screen().PollEvent()) {
 					return
 				}
-				c.onKeyPressed(c.root, ev)
-			case *tcell.EventInterrupt:
-				return
-			case *tcell.EventResize:
-				c.onScreenResize()
-			case *resizeEvent:
-				c.onResize(ev.comp)
-			case *activateEvent:
-				c.setActive(ev.comp)
-			case *tcell.EventMouse:
-				btns := ev.Buttons()
-				clicks := btns & 255
-				x, y := ev.Position()
-				if !mousePressed && clicks != 0 {
-					mousePressed = true
-				}
-				if mousePressed && clicks == 0 {
-					c.onMouse(Point{x, y}, func(comp Component, p Point) {
-						comp.OnMousePressed(p)
-					})
-					mousePressed = false
-				}
-				if btns&0xF00 != 0 {
-					// translate the Up and Down to Left/Right if the modifiers are pressed
-					if ev.Modifiers() != 0 {
-						if btns == tcell.WheelUp {
-							btns = tcell.WheelLeft
-						} else if btns == tcell.WheelDown {
-							btns = tcell.WheelRight
-						}
-					}
-					c.onMouse(Point{x, y}, func(comp Component, p Point) {
-						comp.OnMouseWheel(p, MouseWheel(btns))
-					})
-				}
 			}
+			c.onCoalescedEvents()
 		}
 	}()
 	return ctx, cancel
 }
 
+// onEvent handles the event e. It returns false if the twin must be stopped.
+func (c *controller) onEvent(e tcell.Event) bool {
+	switch ev := e.(type) {
+	case nil:
+		return false // we're done
+	case *tcell.EventKey:
+		if ev.Key() == tcell.KeyCtrlC {
+			return false
+		}
+		if c.pasted != nil {
+			c.pasted = append(c.pasted, ev)
+			break
+		}
+		if c.onKeyPressed(c.root, ev) {
+			break
+		}
+		switch ev.Key() {
+		case tcell.KeyCtrlZ:
+			c.suspend()
+		case tcell.KeyEnter:
+			c.activateFocusedLink()
+		}
+	case *tcell.EventPaste:
+		if ev.Start() {
+			c.pasted = []*tcell.EventKey{}
+			break
+		}
+		keys := c.pasted
+		c.pasted = nil
+		c.onPasted(keys)
+	case *tcell.EventInterrupt:
+		return false
+	case *tcell.EventResize:
+		c.screenResized = true
+	case *resizeEvent:
+		c.resized[ev.comp] = true
+	case *tcell.EventFocus:
+		c.onTerminalFocus(ev.Focused)
+	case *suspendEvent:
+		c.suspend()
+	case *execEvent:
+		c.execute(ev.cmd, ev.onDone)
+	case *clipboardPasteEvent:
+		if ev.text != "" {
+			c.onPaste(c.root, ev.text)
+		}
+	case *invokeEvent:
+		c.runInvokes()
+	case *activateEvent:
+		c.setActive(ev.comp)
+	case *focusMoveEvent:
+		c.focusNext(ev.dir)
+	case *tcell.EventMouse:
+		btns := ev.Buttons()
+		clicks := btns & 255
+		x, y := ev.Position()
+		c.trackMouse(Point{x, y}, clicks != 0, c.mousePressed)
+		if !c.mousePressed && clicks != 0 {
+			c.mousePressed = true
+			c.startDrag(Point{x, y})
+		} else if c.mousePressed && clicks != 0 {
+			c.drag(Point{x, y}, MouseDragMoved)
+		}
+		if c.mousePressed && clicks == 0 {
+			c.drag(Point{x, y}, MouseDragEnded)
+			c.dragged = nil
+			if lc, ok := c.links[Point{x, y}]; !ok || !c.activateLink(lc.comp, lc.link) {
+				c.onMouse(Point{x, y}, func(comp Component, p Point) {
+					comp.OnMousePressed(p)
+				})
+			}
+			c.mousePressed = false
+		}
+		if btns&0xF00 != 0 {
+			// Shift+wheel scrolls horizontally
+			if ev.Modifiers()&tcell.ModShift != 0 {
+				if btns == tcell.WheelUp {
+					btns = tcell.WheelLeft
+				} else if btns == tcell.WheelDown {
+					btns = tcell.WheelRight
+				}
+			}
+			c.onMouse(Point{x, y}, func(comp Component, p Point) {
+				comp.OnMouseWheel(p, MouseWheel(btns))
+			})
+		}
+	}
+	return true
+}
+
+// onCoalescedEvents handles the resize events collected by onEvent, so the screen and every
+// component are resized once per the events batch
+func (c *controller) onCoalescedEvents() {
+	if c.screenResized {
+		c.screenResized = false
+		c.onScreenResize()
+	}
+	for comp := range c.resized {
+		delete(c.resized, comp)
+		c.onResize(comp)
+	}
+}
+
 func (c *controller) onKeyPressed(comp Component, ke *tcell.EventKey) bool {
 	_, chld := comp.box().getActiveChild()
 	if chld != nil {
@@ -126,50 +273,179 @@ func (c *controller) onKeyPressed(comp Component, ke *tcell.EventKey) bool {
 	return comp.OnKeyPressed(ke)
 }
 
+// onPasted delivers the pasted text to the focused component and its owners. If no one handles
+// it, the keys are delivered one by one.
+func (c *controller) onPasted(keys []*tcell.EventKey) {
+	if len(keys) == 0 {
+		return
+	}
+	var sb strings.Builder
+	for _, ke := range keys {
+		switch ke.Key() {
+		case tcell.KeyRune:
+			sb.WriteRune(ke.Rune())
+		case tcell.KeyEnter, tcell.KeyLF:
+			sb.WriteByte('\n')
+		case tcell.KeyTab:
+			sb.WriteByte('\t')
+		}
+	}
+	if c.onPaste(c.root, sb.String()) {
+		return
+	}
+	for _, ke := range keys {
+		c.onKeyPressed(c.root, ke)
+	}
+}
+
+func (c *controller) onPaste(comp Component, text string) bool {
+	_, chld := comp.box().getActiveChild()
+	if chld != nil && c.onPaste(chld, text) {
+		return true
+	}
+	return comp.OnPaste(text)
+}
+
+// setLink remembers the hyperlink printed by comp at the screen point p, the empty link
+// removes the point
+func (c *controller) setLink(p Point, comp Component, link string) {
+	if link != "" {
+		c.links[p] = linkCell{comp: comp, link: link}
+	} else if len(c.links) > 0 {
+		delete(c.links, p)
+	}
+}
+
+// activateLink calls OnLinkActivated for comp and then for its owners till one handles the link
+func (c *controller) activateLink(comp Component, link string) bool {
+	for ; comp != nil; comp = comp.box().owner {
+		if comp.OnLinkActivated(link) {
+			return true
+		}
+	}
+	return false
+}
+
+// activateFocusedLink activates the first hyperlink printed by the focused component
+func (c *controller) activateFocusedLink() bool {
+	focused := c.focused()
+	var first *Point
+	var link string
+	for p, lc := range c.links {
+		if lc.comp != focused {
+			continue
+		}
+		if first == nil || p.Y < first.Y || (p.Y == first.Y && p.X < first.X) {
+			first, link = &p, lc.link
+		}
+	}
+	return first != nil && c.activateLink(focused, link)
+}
+
 type mouseF func(comp Component, p Point)
 
 func (c *controller) onMouse(p Point, mf mouseF) {
-	cc := newCanvas(c.root.Bounds().Size())
-	c.onMouseComp(cc, c.root, p, mf)
+	if c.root.Bounds().Contains(p) {
+		c.onMouseComp(newCanvas(c.root.Bounds().Size()), c.root, p, mf)
+	}
 }
 
+// onMouseComp delivers the mouse event to the top-most component under p in the comp subtree. The
+// comp must be visible and be under p. If the component doesn't handle the event, its owner does.
 func (c *controller) onMouseComp(cc *CanvasContext, comp Component, p Point, mf mouseF) bool {
-	if !comp.IsVisible() {
+	cc.pushRelativeRegion(Point{}, comp.Bounds())
+	tl := cc.physicalPointXY(Point{})
+	cc.pop()
+	if !comp.IsEnabled() {
+		// the disabled component and its children don't receive the mouse events, its owner does
 		return false
 	}
-	b := comp.Bounds()
-	tl := cc.physicalPointXY(b.TopLeft())
-	b = b.Move(tl)
-	if !b.Contains(p) {
-		return false
+	cc.pushRelativeRegion(comp.VirtualOffset(), comp.ChildrenCanvasBounds())
+	chld := c.childAt(cc, comp, p)
+	handled := chld != nil && c.onMouseComp(cc, chld, p, mf)
+	cc.pop()
+	if handled {
+		return true
 	}
-	b = comp.ChildrenCanvasBounds()
-	b = b.Move(cc.physicalPointXY(b.TopLeft()))
-	if b.Contains(p) {
-		// p is in the chldrn bounds
-		cc.pushRelativeRegion(comp.VirtualOffset(), b)
-		defer cc.pop()
-		children := comp.box().children()
-		_, active := comp.box().getActiveChild()
-		if active != nil && c.onMouseComp(cc, active, p, mf) {
-			return true
+	mf(comp, Point{X: p.X - tl.X, Y: p.Y - tl.Y})
+	return c.setActive(comp)
+}
+
+// compAt returns the deepest visible component under the physical point p, or nil
+func (c *controller) compAt(cc *CanvasContext, comp Component, p Point) Component {
+	cc.pushRelativeRegion(comp.VirtualOffset(), comp.ChildrenCanvasBounds())
+	defer cc.pop()
+	if chld := c.childAt(cc, comp, p); chld != nil {
+		return c.compAt(cc, chld, p)
+	}
+	return comp
+}
+
+// childAt returns the top-most visible child of comp under the physical point p, or nil. The
+// canvas region must be the comp children one.
+func (c *controller) childAt(cc *CanvasContext, comp Component, p Point) Component {
+	if !cc.physicalRegion().Contains(p) {
+		return nil
+	}
+	chldrn := c.zOrdered(comp)
+	for i := len(chldrn) - 1; i >= 0; i-- {
+		chld := chldrn[i]
+		if !chld.IsVisible() {
+			continue
 		}
-		for i := len(children) - 1; i >= 0; i-- {
-			child := children[i]
-			if child == active {
-				continue
-			}
-			if c.onMouseComp(cc, child, p, mf) {
-				return true
-			}
+		cc.pushRelativeRegion(Point{}, chld.Bounds())
+		hit := cc.physicalRegion().Contains(p)
+		cc.pop()
+		if hit {
+			return chld
 		}
 	}
-	mf(comp, Point{X: p.X - tl.X, Y: p.Y - tl.Y})
-	return c.setActive(comp)
+	return nil
+}
+
+// zOrdered returns the comp children in the drawing order, from the bottom to the top one. The
+// children are ordered by their z-index, then by BringToFront() and SendToBack() calls, and then
+// by the order they were added to the owner.
+func (c *controller) zOrdered(comp Component) []Component {
+	res := slices.Clone(comp.box().children())
+	slices.SortStableFunc(res, func(a, b Component) int {
+		if r := cmp.Compare(a.box().zIndex.Load(), b.box().zIndex.Load()); r != 0 {
+			return r
+		}
+		return cmp.Compare(a.box().zSeq.Load(), b.box().zSeq.Load())
+	})
+	return res
+}
+
+// trackMouse updates the hovered and pressed components
+func (c *controller) trackMouse(p Point, pressed, wasPressed bool) {
+	var under Component
+	if c.root.Bounds().Contains(p) {
+		under = c.compAt(newCanvas(c.root.Bounds().Size()), c.root, p)
+	}
+	c.setMouseComp(&c.hovered, under)
+	if !pressed {
+		c.setMouseComp(&c.pressed, nil)
+	} else if !wasPressed {
+		c.setMouseComp(&c.pressed, under)
+	}
+}
+
+func (c *controller) setMouseComp(v *atomic.Value, comp Component) {
+	old, _ := v.Swap(compHolder{comp: comp}).(compHolder)
+	if old.comp == comp {
+		return
+	}
+	if old.comp != nil && !old.comp.box().isClosed() {
+		c.reDrawNeeded(old.comp, &tcell.EventTime{})
+	}
+	if comp != nil {
+		c.reDrawNeeded(comp, &tcell.EventTime{})
+	}
 }
 
 func (c *controller) setActive(comp Component) bool {
-	if !comp.CanBeFocused() || !comp.IsVisible() {
+	if !comp.CanBeFocused() || !comp.IsVisible() || !comp.IsEnabled() {
 		return false
 	}
 	if comp.box().isActive() {
@@ -177,7 +453,7 @@ func (c *controller) setActive(comp Component) bool {
 	}
 	o := comp.box().owner
 	for o != nil {
-		if !o.IsVisible() || !o.CanBeFocused() {
+		if !o.IsVisible() || !o.CanBeFocused() || !o.IsEnabled() {
 			return false
 		}
 		o = o.box().owner
@@ -194,26 +470,75 @@ func (c *controller) setActive(comp Component) bool {
 
 func (c *controller) onLoop() {
 	c.lock.Lock()
-	dirtySet := c.dirtySet
-	if len(dirtySet) > 0 {
-		c.dirtySet = make(map[Component]bool)
-	}
 	var deleted []Component
 	c.deleteComponent(c.root, &deleted) // handle deleted comps
 	c.lock.Unlock()
 
-	if len(deleted) > 0 {
-		for _, d := range deleted {
-			d.box().closeActually()
-		}
-		dirtySet[c.root] = true
+	for _, d := range deleted {
+		c.invalidateCache(d.box().owner)
+		c.addDamage(c.physicalBounds(d), &tcell.EventTime{})
+		d.box().closeActually()
+	}
+	c.runInvokes()
+	c.checkFocus()
+	c.deliverEvents()
+	if wait := c.animate(); wait > 0 {
+		c.scheduleFrame(wait)
+	}
+
+	c.lock.Lock()
+	damage := c.damage
+	if len(damage) == 0 {
+		c.lock.Unlock()
+		return
 	}
-	if len(dirtySet) == 0 {
+	if wait := time.Duration(c.frameInterval.Load()) - time.Since(c.lastFrame); wait > 0 {
+		// too early, the damage is kept till the next frame
+		c.lock.Unlock()
+		c.scheduleFrame(wait)
 		return
 	}
+	c.damage = nil
+	c.lock.Unlock()
+
+	c.lastFrame = time.Now()
 	cc := newCanvas(c.root.Bounds().Size())
-	c.draw(cc, c.root, false, dirtySet)
-	c.s.Show()
+	c.drawDamage(cc, damage)
+	c.screen().Show()
+}
+
+// invoke adds f to the functions called from the loop
+func (c *controller) invoke(f func()) {
+	c.lock.Lock()
+	wasEmpty := len(c.invokes) == 0
+	c.invokes = append(c.invokes, f)
+	c.lock.Unlock()
+	if wasEmpty {
+		// if the event queue is full, the functions are called by the next onLoop() anyway
+		_ = c.screen().PostEvent(&invokeEvent{})
+	}
+}
+
+// runInvokes calls the functions passed to Invoke() in their order
+func (c *controller) runInvokes() {
+	c.lock.Lock()
+	invokes := c.invokes
+	c.invokes = nil
+	c.lock.Unlock()
+	for _, f := range invokes {
+		f()
+	}
+}
+
+// scheduleFrame wakes up the loop after the wait duration to draw the deferred frame
+func (c *controller) scheduleFrame(wait time.Duration) {
+	if !c.frameScheduled.CompareAndSwap(false, true) {
+		return
+	}
+	time.AfterFunc(wait, func() {
+		c.frameScheduled.Store(false)
+		c.screen().PostEvent(&frameEvent{})
+	})
 }
 
 func (c *controller) deleteComponent(comp Component, deleted *[]Component) bool {
@@ -248,56 +573,222 @@ func (c *controller) closeAll() {
 	}
 }
 
-func (c *controller) draw(cc *CanvasContext, comp Component, force bool, ds map[Component]bool) {
+// drawDamage redraws the components, which intersect the damaged areas. The drawing is clipped
+// by the areas, so the rest of the screen is not touched.
+func (c *controller) drawDamage(cc *CanvasContext, damage []Rectangle) {
+	for _, d := range damage {
+		// the region has the zero virtual offset, so the physical coordinates are not changed
+		cc.pushRelativeRegion(d.TopLeft(), d)
+		c.draw(cc, c.root)
+		cc.pop()
+	}
+}
+
+func (c *controller) draw(cc *CanvasContext, comp Component) {
 	if !comp.IsVisible() {
 		return
 	}
-	if force || ds[comp] {
-		cc.pushRelativeRegion(Point{0, 0}, comp.Bounds())
+	b := comp.box()
+	if !b.cached.Load() {
+		c.drawSubtree(cc, comp)
+		return
+	}
+	cb := b.cache.Load()
+	if cb == nil || cb.Size() != comp.Bounds().Size() {
+		cb = Snapshot(comp)
+		b.cache.Store(cb)
+	}
+	cc.pushRelativeRegion(Point{}, comp.Bounds())
+	prev := cc.comp
+	cc.comp = comp
+	cc.DrawBuffer(Point{}, cb)
+	cc.comp = prev
+	cc.pop()
+}
+
+// drawSubtree draws comp and its children within the canvas region. The children, which are out
+// of the region or are covered by an opaque sibling above them, are skipped.
+func (c *controller) drawSubtree(cc *CanvasContext, comp Component) {
+	cc.pushRelativeRegion(Point{}, comp.Bounds())
+	if !cc.physicalRegion().IsEmpty() {
+		prev := cc.comp
+		cc.comp = comp
 		comp.OnDraw(cc)
-		cc.pop()
-		force = true // redraw all chldrn then automatically
+		cc.comp = prev
 	}
-	var active Component
+	cc.pop()
+
 	cc.pushRelativeRegion(comp.VirtualOffset(), comp.ChildrenCanvasBounds())
-	chldrn := comp.box().children()
-	for _, chld := range chldrn {
-		if chld.box().isActive() {
-			active = chld
-		} else {
-			c.draw(cc, chld, force, ds)
+	defer cc.pop()
+	if cc.physicalRegion().IsEmpty() {
+		return
+	}
+	ordered := c.zOrdered(comp)
+	rects := make([]Rectangle, len(ordered))
+	for i, chld := range ordered {
+		if chld.IsVisible() {
+			cc.pushRelativeRegion(Point{}, chld.Bounds())
+			rects[i] = cc.physicalRegion()
+			cc.pop()
 		}
 	}
-	if active != nil {
-		c.draw(cc, active, force, ds)
+	for i, chld := range ordered {
+		if rects[i].IsEmpty() || c.isOccluded(ordered[i+1:], rects[i+1:], rects[i]) {
+			continue
+		}
+		c.draw(cc, chld)
 	}
-	cc.pop()
 }
 
-func (c *controller) reDrawNeeded(comp Component, event tcell.Event) {
+// isOccluded returns whether the physical rectangle r is fully covered by one of the opaque
+// components comps, their physical rectangles are rects
+func (c *controller) isOccluded(comps []Component, rects []Rectangle, r Rectangle) bool {
+	for i, comp := range comps {
+		if comp.box().opaque.Load() && rects[i].ContainsRect(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// physicalBounds returns the screen area, where comp is drawn, or the empty rectangle, if the
+// component or one of its owners is not visible, or it is not attached to the root
+func (c *controller) physicalBounds(comp Component) Rectangle {
+	cc := c.compCanvas(comp)
+	if cc == nil {
+		return Rectangle{}
+	}
+	return cc.physicalRegion()
+}
+
+// compCanvas returns the canvas with the comp region on top, or nil, if the component or one
+// of its owners is not visible, or it is not attached to the root
+func (c *controller) compCanvas(comp Component) *CanvasContext {
+	var path []Component
+	for o := comp; o != nil; o = o.box().owner {
+		path = append(path, o)
+	}
+	if path[len(path)-1] != c.root {
+		return nil
+	}
+	cc := newCanvas(c.root.Bounds().Size())
+	for i := len(path) - 1; i > 0; i-- {
+		if !path[i].IsVisible() {
+			return nil
+		}
+		cc.pushRelativeRegion(path[i].VirtualOffset(), path[i].ChildrenCanvasBounds())
+	}
+	cc.pushRelativeRegion(Point{}, comp.Bounds())
+	return cc
+}
+
+// startDrag offers the mouse drag started on the physical point p to the component under p
+// and then to its owners, the first one which accepts it receives the rest of the drag
+func (c *controller) startDrag(p Point) {
+	if !c.root.Bounds().Contains(p) {
+		return
+	}
+	comp := c.compAt(newCanvas(c.root.Bounds().Size()), c.root, p)
+	if !IsEnabled(comp) {
+		return
+	}
+	for ; comp != nil; comp = comp.box().owner {
+		c.dragged = comp
+		if c.drag(p, MouseDragStarted) {
+			return
+		}
+	}
+	c.dragged = nil
+}
+
+// drag delivers the drag state to the dragged component, p is the physical point
+func (c *controller) drag(p Point, state MouseDragState) bool {
+	if c.dragged == nil || c.dragged.box().isClosed() {
+		return false
+	}
+	var tl Point
+	if cc := c.compCanvas(c.dragged); cc != nil {
+		tl = cc.physicalPointXY(Point{})
+	} else if state != MouseDragEnded {
+		// the component is hidden, but it must know the drag is over anyway
+		return false
+	}
+	return c.dragged.OnMouseDrag(Point{X: p.X - tl.X, Y: p.Y - tl.Y}, state)
+}
+
+// addDamage adds the physical rectangle r to the areas to be redrawn. The overlapping areas are
+// merged, so every cell is drawn once.
+func (c *controller) addDamage(r Rectangle, event tcell.Event) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	if comp.box().owner != nil {
-		c.dirtySet[comp.box().owner] = true
-	} else {
-		c.dirtySet[comp] = true
+	wasEmpty := len(c.damage) == 0
+	if wasEmpty && c.screenReady.Load() {
+		// kick the loop to wake up, there is no loop without the screen
+		defer c.s.PostEvent(event)
 	}
+	if r.IsEmpty() {
+		return
+	}
+	for merged := true; merged; {
+		merged = false
+		for i, d := range c.damage {
+			if d.ContainsRect(r) {
+				return
+			}
+			if !d.Intersection(r).IsEmpty() {
+				r = r.Union(d)
+				c.damage = append(c.damage[:i], c.damage[i+1:]...)
+				merged = true
+				break
+			}
+		}
+	}
+	c.damage = append(c.damage, r)
+	if len(c.damage) > maxDamageAreas {
+		// too many small areas, it is cheaper to redraw their union
+		u := Rectangle{}
+		for _, d := range c.damage {
+			u = u.Union(d)
+		}
+		c.damage = append(c.damage[:0], u)
+	}
+}
+
+// invalidateCache drops the cached rendering of comp and all its owners
+func (c *controller) invalidateCache(comp Component) {
+	for ; comp != nil; comp = comp.box().owner {
+		comp.box().cache.Store(nil)
+	}
+}
+
+// invalidateAllCaches drops the cached renderings in the comp subtree
+func (c *controller) invalidateAllCaches(comp Component) {
+	comp.box().cache.Store(nil)
+	for _, chld := range comp.box().children() {
+		c.invalidateAllCaches(chld)
+	}
+}
 
-	if len(c.dirtySet) > 1 {
+func (c *controller) reDrawNeeded(comp Component, event tcell.Event) {
+	c.invalidateCache(comp)
+	if !c.screenReady.Load() {
+		// nothing is shown yet, the whole screen is drawn, when it is ready
 		return
 	}
-	c.s.PostEvent(event) // kick the loop to wake up
+	c.addDamage(c.physicalBounds(comp), event)
 }
 
 func (c *controller) onScreenResize() {
-	w, h := c.s.Size()
+	w, h := c.screen().Size()
 	sz := Size{Width: w, Height: h}
 	curSz := c.root.Bounds().Size()
 	if sz == curSz {
 		return
 	}
 	c.root.bounds.Store(Rectangle{X: 0, Y: 0, Width: w, Height: h})
+	clear(c.links) // the whole screen is redrawn
+	c.notify(c.root, ComponentEvent{Type: ComponentBoundsChanged, Bounds: c.root.Bounds()})
 	c.onResize(c.root)
 	c.reDrawNeeded(c.root, &tcell.EventTime{})
 }
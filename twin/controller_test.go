@@ -0,0 +1,443 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// countingTarget counts the cells put to the buffer
+type countingTarget struct {
+	*CellBuffer
+	n int
+}
+
+func (ct *countingTarget) SetContent(x, y int, mainc rune, combc []rune, style tcell.Style) {
+	ct.n++
+	ct.CellBuffer.SetContent(x, y, mainc, combc, style)
+}
+
+func newCountingCanvas() (*CanvasContext, *countingTarget) {
+	sz := c.root.Bounds().Size()
+	ct := &countingTarget{CellBuffer: NewCellBuffer(sz)}
+	cc := newCanvas(sz)
+	cc.t, cc.screen = ct, false
+	return cc, ct
+}
+
+func takeDamage() []Rectangle {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	res := c.damage
+	c.damage = nil
+	return res
+}
+
+func TestRectangle_Intersection(t *testing.T) {
+	r := Rectangle{X: 1, Y: 1, Width: 4, Height: 3}
+	assert.Equal(t, Rectangle{X: 3, Y: 2, Width: 2, Height: 2}, r.Intersection(Rectangle{X: 3, Y: 2, Width: 10, Height: 10}))
+	assert.True(t, r.Intersection(Rectangle{X: 5, Y: 1, Width: 1, Height: 1}).IsEmpty())
+	assert.Equal(t, Rectangle{X: 0, Y: 1, Width: 5, Height: 4}, r.Union(Rectangle{X: 0, Y: 4, Width: 1, Height: 1}))
+	assert.Equal(t, r, r.Union(Rectangle{}))
+	assert.True(t, r.ContainsRect(Rectangle{X: 2, Y: 2, Width: 3, Height: 2}))
+	assert.False(t, r.ContainsRect(Rectangle{X: 2, Y: 2, Width: 4, Height: 2}))
+}
+
+func TestDamage_Partial(t *testing.T) {
+	c.onScreenResize()
+	p := newTestBox(t, Root(), "p", Rectangle{X: 2, Y: 1, Width: 20, Height: 5})
+	left := newTestBox(t, p, "left", Rectangle{X: 0, Y: 0, Width: 10, Height: 5})
+	right := newTestBox(t, p, "right", Rectangle{X: 10, Y: 0, Width: 10, Height: 5})
+	leaf := newTestBox(t, right, "leaf", Rectangle{X: 1, Y: 2, Width: 4, Height: 1})
+	takeDamage()
+
+	leaf.text = "tick"
+	Redraw(leaf)
+	damage := takeDamage()
+	assert.Equal(t, []Rectangle{{X: 13, Y: 3, Width: 4, Height: 1}}, damage)
+
+	cc, ct := newCountingCanvas()
+	c.drawDamage(cc, damage)
+	assert.Equal(t, 0, left.draws)
+	assert.Equal(t, 1, right.draws)
+	assert.Equal(t, 1, leaf.draws)
+	// the damaged cells are filled by the root, p, right and leaf, and the leaf prints its text
+	assert.Equal(t, 4*4+4, ct.n)
+	assert.Equal(t, "tick", ct.String()[3*81+13:3*81+17])
+
+	// the old place of the moved component is redrawn too
+	leaf.SetBounds(Rectangle{X: 1, Y: 3, Width: 4, Height: 1})
+	assert.ElementsMatch(t, []Rectangle{{X: 13, Y: 3, Width: 4, Height: 1}, {X: 13, Y: 4, Width: 4, Height: 1}}, takeDamage())
+}
+
+func TestDamage_Merge(t *testing.T) {
+	takeDamage()
+	c.addDamage(Rectangle{X: 0, Y: 0, Width: 2, Height: 2}, &tcell.EventTime{})
+	c.addDamage(Rectangle{X: 5, Y: 5, Width: 2, Height: 2}, &tcell.EventTime{})
+	c.addDamage(Rectangle{X: 1, Y: 1, Width: 5, Height: 1}, &tcell.EventTime{})
+	c.addDamage(Rectangle{X: 0, Y: 0, Width: 1, Height: 1}, &tcell.EventTime{})
+	assert.Equal(t, []Rectangle{{X: 5, Y: 5, Width: 2, Height: 2}, {X: 0, Y: 0, Width: 6, Height: 2}}, takeDamage())
+}
+
+func TestDamage_Occlusion(t *testing.T) {
+	c.onScreenResize()
+	p := newTestBox(t, Root(), "p", Rectangle{X: 0, Y: 0, Width: 10, Height: 3})
+	under := newTestBox(t, p, "under", Rectangle{X: 1, Y: 1, Width: 3, Height: 1})
+	over := newTestBox(t, p, "over", Rectangle{X: 0, Y: 0, Width: 5, Height: 2})
+	takeDamage()
+
+	cc, _ := newCountingCanvas()
+	c.drawDamage(cc, []Rectangle{{X: 0, Y: 0, Width: 10, Height: 3}})
+	assert.Equal(t, 1, under.draws)
+
+	over.SetOpaque(true)
+	cc, _ = newCountingCanvas()
+	c.drawDamage(cc, takeDamage())
+	assert.Equal(t, 1, under.draws)
+	assert.Equal(t, 2, over.draws)
+	assert.Equal(t, 2, p.draws)
+}
+
+// newDeepTree creates the tree of depth levels, every level has the container and 3 siblings
+// around it. It returns the leaf.
+func newDeepTree(tb testing.TB, depth int) Component {
+	var owner Component = Root()
+	r := Root().Bounds()
+	for i := 0; i < depth; i++ {
+		r = Rectangle{X: 1, Y: 1, Width: r.Width - 2, Height: r.Height - 2}
+		for j := 0; j < 3; j++ {
+			newTestBox(tb, owner, "sibling", Rectangle{X: j * r.Width / 3, Y: r.Height, Width: r.Width / 3, Height: 1})
+		}
+		owner = newTestBox(tb, owner, "container", r)
+	}
+	return newTestBox(tb, owner, "leaf", Rectangle{X: 0, Y: 0, Width: 4, Height: 1})
+}
+
+// BenchmarkRedraw compares the number of the cells drawn for the leaf redrawing, when the whole
+// owner area is redrawn and when only the damaged area is redrawn
+func BenchmarkRedraw(b *testing.B) {
+	c.onScreenResize()
+	leaf := newDeepTree(b, 8)
+	for _, bc := range []struct {
+		name string
+		comp Component
+	}{
+		{"owner", leaf.box().owner},
+		{"damage", leaf},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			damage := []Rectangle{c.physicalBounds(bc.comp)}
+			cc, ct := newCountingCanvas()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.drawDamage(cc, damage)
+			}
+			b.ReportMetric(float64(ct.n)/float64(b.N), "cells/op")
+		})
+	}
+}
+
+func TestZOrder(t *testing.T) {
+	c.onScreenResize()
+	p := newTestBox(t, Root(), "", Rectangle{X: 0, Y: 0, Width: 10, Height: 2})
+	a := newTestBox(t, p, "aaaa", Rectangle{X: 0, Y: 0, Width: 4, Height: 1})
+	b := newTestBox(t, p, "bb", Rectangle{X: 2, Y: 0, Width: 2, Height: 1})
+	takeDamage()
+
+	check := func(exp string, top Component) {
+		t.Helper()
+		cc, ct := newCountingCanvas()
+		c.drawDamage(cc, []Rectangle{p.Bounds()})
+		assert.Equal(t, exp, ct.String()[:4])
+		assert.Equal(t, top, c.compAt(newCanvas(c.root.Bounds().Size()), c.root, Point{X: 3, Y: 0}))
+		var pressed Component
+		c.onMouse(Point{X: 3, Y: 0}, func(comp Component, p Point) {
+			if pressed == nil {
+				pressed = comp
+			}
+		})
+		assert.Equal(t, top, pressed)
+		takeDamage()
+	}
+	check("aabb", b)
+
+	// the damaged component under the sibling doesn't overdraw it
+	a.BringToFront()
+	assert.Equal(t, []Rectangle{{X: 0, Y: 0, Width: 4, Height: 1}}, takeDamage())
+	check("aaaa", a)
+
+	a.SendToBack()
+	check("aabb", b)
+
+	b.SetZIndex(-1)
+	check("aaaa", a)
+	assert.Equal(t, -1, b.ZIndex())
+	b.BringToFront()
+	check("aaaa", a)
+
+	// the disabled component doesn't swallow the click, its owner gets it
+	a.SetEnabled(false)
+	var pressed Component
+	c.onMouse(Point{X: 3, Y: 0}, func(comp Component, p Point) {
+		if pressed == nil {
+			pressed = comp
+		}
+	})
+	assert.Equal(t, Component(p), pressed)
+}
+
+func TestFrameRate(t *testing.T) {
+	t.Cleanup(func() { SetMaxFrameRate(defaultFrameRate) })
+	c.onScreenResize()
+	tb := newTestBox(t, Root(), "", Rectangle{X: 0, Y: 0, Width: 5, Height: 1})
+	takeDamage()
+
+	SetMaxFrameRate(1)
+	c.lastFrame = time.Now()
+	Redraw(tb)
+	c.onLoop()
+	// the frame is deferred, so the damage is kept
+	assert.Equal(t, 0, tb.draws)
+	assert.True(t, c.frameScheduled.Load())
+
+	SetMaxFrameRate(0)
+	c.onLoop()
+	assert.Equal(t, 1, tb.draws)
+	assert.Empty(t, takeDamage())
+}
+
+func TestCoalescedEvents(t *testing.T) {
+	c.onScreenResize()
+	p := newTestBox(t, Root(), "", Rectangle{X: 0, Y: 0, Width: 5, Height: 1})
+	chld := newTestBox(t, p, "", Rectangle{X: 0, Y: 0, Width: 5, Height: 1})
+	for i := 0; i < 10; i++ {
+		assert.True(t, c.onEvent(&resizeEvent{comp: p}))
+	}
+	assert.Equal(t, 0, chld.resizes)
+	c.onCoalescedEvents()
+	assert.Equal(t, 1, chld.resizes)
+
+	assert.False(t, c.onEvent(nil))
+	assert.False(t, c.onEvent(tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl)))
+}
+
+// inputBox is the focusable test component, which records the pasted text and the keys
+type inputBox struct {
+	testBox
+	handlePaste bool
+	pasted      string
+	keys        []tcell.Key
+}
+
+func (ib *inputBox) CanBeFocused() bool { return true }
+
+func (ib *inputBox) OnPaste(text string) bool {
+	if ib.handlePaste {
+		ib.pasted += text
+	}
+	return ib.handlePaste
+}
+
+func (ib *inputBox) OnKeyPressed(ke *tcell.EventKey) bool {
+	ib.keys = append(ib.keys, ke.Key())
+	return true
+}
+
+func TestPaste(t *testing.T) {
+	ib := &inputBox{handlePaste: true}
+	assert.Nil(t, ib.Init(Root(), ib))
+	ib.SetBounds(Rectangle{Width: 5, Height: 1})
+	t.Cleanup(func() {
+		Close(ib)
+		c.onLoop()
+	})
+	assert.True(t, c.setActive(ib))
+
+	paste := func(keys ...*tcell.EventKey) {
+		c.onEvent(tcell.NewEventPaste(true))
+		for _, ke := range keys {
+			c.onEvent(ke)
+		}
+		c.onEvent(tcell.NewEventPaste(false))
+	}
+	keys := []*tcell.EventKey{
+		tcell.NewEventKey(tcell.KeyRune, 'a', 0),
+		tcell.NewEventKey(tcell.KeyEnter, 0, 0),
+		tcell.NewEventKey(tcell.KeyRune, 'b', 0),
+	}
+	paste(keys...)
+	assert.Equal(t, "a\nb", ib.pasted)
+	assert.Empty(t, ib.keys)
+
+	// the paste, which is not handled, is delivered as the keys
+	ib.handlePaste = false
+	paste(keys...)
+	assert.Equal(t, []tcell.Key{tcell.KeyRune, tcell.KeyEnter, tcell.KeyRune}, ib.keys)
+}
+
+type dragBox struct {
+	Box
+	accept bool
+	drags  []MouseDragState
+	points []Point
+}
+
+func (db *dragBox) OnMouseDrag(p Point, state MouseDragState) bool {
+	db.drags = append(db.drags, state)
+	db.points = append(db.points, p)
+	return db.accept
+}
+
+func TestMouseDrag(t *testing.T) {
+	owner := &dragBox{accept: true}
+	assert.Nil(t, owner.Init(Root(), owner))
+	owner.SetBounds(Rectangle{X: 2, Y: 1, Width: 10, Height: 5})
+	chld := &dragBox{}
+	assert.Nil(t, chld.Init(owner, chld))
+	chld.SetBounds(Rectangle{X: 1, Y: 1, Width: 3, Height: 2})
+	t.Cleanup(func() {
+		Close(owner)
+		c.onLoop()
+	})
+
+	mouse := func(x, y int, btns tcell.ButtonMask) {
+		c.onEvent(tcell.NewEventMouse(x, y, btns, 0))
+	}
+	// the child refuses the drag, so the owner receives it, even outside of its bounds
+	mouse(4, 2, tcell.Button1)
+	mouse(5, 2, tcell.Button1)
+	mouse(20, 10, tcell.Button1)
+	mouse(20, 10, tcell.ButtonNone)
+	assert.Equal(t, []MouseDragState{MouseDragStarted}, chld.drags)
+	assert.Equal(t, []MouseDragState{MouseDragStarted, MouseDragMoved, MouseDragMoved, MouseDragEnded}, owner.drags)
+	assert.Equal(t, []Point{{2, 1}, {3, 1}, {18, 9}, {18, 9}}, owner.points)
+	assert.Equal(t, Point{1, 0}, chld.points[0])
+
+	// the motion without the button pressed is not a drag
+	mouse(5, 2, tcell.ButtonNone)
+	assert.Len(t, owner.drags, 4)
+}
+
+type scrollerBox struct {
+	Box
+	offset Point
+	shown  []Rectangle
+}
+
+func (sb *scrollerBox) CanBeFocused() bool { return true }
+
+func (sb *scrollerBox) VirtualOffset() Point { return sb.offset }
+
+func (sb *scrollerBox) ScrollIntoView(comp Component) {
+	r := RelativeBounds(comp, sb)
+	sb.shown = append(sb.shown, r)
+	sb.offset = r.TopLeft()
+}
+
+func TestScrollIntoView(t *testing.T) {
+	sb := &scrollerBox{}
+	assert.Nil(t, sb.Init(Root(), sb))
+	sb.SetBounds(Rectangle{Width: 10, Height: 5})
+	t.Cleanup(func() {
+		Close(sb)
+		c.onLoop()
+	})
+	inner := &scrollerBox{offset: Point{X: 1, Y: 2}}
+	assert.Nil(t, inner.Init(sb, inner))
+	inner.SetBounds(Rectangle{X: 3, Y: 20, Width: 10, Height: 10})
+
+	var added []Component
+	unsubscribe := Subscribe(sb, func(ev ComponentEvent) {
+		if ev.Type == ComponentAdded {
+			assert.Equal(t, Component(inner), ev.Owner())
+			added = append(added, ev.Comp)
+		}
+	})
+	defer unsubscribe()
+	ib1 := &inputBox{}
+	assert.Nil(t, ib1.Init(inner, ib1))
+	ib1.SetBounds(Rectangle{X: 2, Y: 4, Width: 5, Height: 1})
+	ib2 := &inputBox{}
+	assert.Nil(t, ib2.Init(inner, ib2))
+	ib2.SetBounds(Rectangle{X: 2, Y: 8, Width: 5, Height: 1})
+	c.deliverEvents()
+	assert.Equal(t, []Component{ib1, ib2}, added)
+
+	// the inner box is asked first, then the outer one gets the position considering the inner
+	// box offset
+	assert.True(t, c.setActive(ib1))
+	assert.True(t, c.focusNext(1))
+	assert.Equal(t, []Rectangle{{X: 2, Y: 8, Width: 5, Height: 1}}, inner.shown)
+	assert.Equal(t, []Rectangle{{X: 3, Y: 20, Width: 5, Height: 1}}, sb.shown)
+}
+
+func TestAnimate(t *testing.T) {
+	SetMaxFrameRate(1)
+	t.Cleanup(func() { SetMaxFrameRate(defaultFrameRate) })
+	var calls int
+	Animate(func(now time.Time) bool {
+		calls++
+		return calls < 3
+	})
+	c.lastAnimation = time.Time{}
+	c.onLoop()
+	// the next animation frame is not due yet
+	c.onLoop()
+	assert.Equal(t, 1, calls)
+	for i := 0; i < 5; i++ {
+		c.lastAnimation = time.Time{}
+		c.onLoop()
+	}
+	assert.Equal(t, 3, calls)
+	assert.Empty(t, c.animations)
+
+	cancel := Animate(func(now time.Time) bool {
+		calls++
+		return true
+	})
+	cancel()
+	c.lastAnimation = time.Time{}
+	c.onLoop()
+	assert.Equal(t, 3, calls)
+}
+
+type wheelBox struct {
+	Box
+	wheels []MouseWheel
+}
+
+func (wb *wheelBox) OnMouseWheel(p Point, wheel MouseWheel) bool {
+	wb.wheels = append(wb.wheels, wheel)
+	return true
+}
+
+func TestMouseWheel_Shift(t *testing.T) {
+	wb := &wheelBox{}
+	assert.Nil(t, wb.Init(Root(), wb))
+	wb.SetBounds(Rectangle{Width: 5, Height: 5})
+	t.Cleanup(func() {
+		Close(wb)
+		c.onLoop()
+	})
+	c.onEvent(tcell.NewEventMouse(1, 1, tcell.WheelUp, tcell.ModShift))
+	c.onEvent(tcell.NewEventMouse(1, 1, tcell.WheelDown, tcell.ModShift))
+	// the other modifiers don't change the direction
+	c.onEvent(tcell.NewEventMouse(1, 1, tcell.WheelDown, tcell.ModCtrl))
+	assert.Equal(t, []MouseWheel{MouseWheelLeft, MouseWheelRight, MouseWheelDown}, wb.wheels)
+}
+
+func TestInvoke(t *testing.T) {
+	var called []int
+	// the tests don't poll the events, so the queue is full and the most events are dropped
+	for i := 0; i < 1000; i++ {
+		Invoke(func() { called = append(called, i) })
+	}
+	c.onEvent(&invokeEvent{})
+	assert.Len(t, called, 1000)
+	assert.Equal(t, 999, called[999])
+
+	Invoke(func() { called = nil })
+	c.onLoop()
+	assert.Nil(t, called)
+}
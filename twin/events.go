@@ -0,0 +1,132 @@
+package twin
+
+// ComponentEventType defines the type of the tree-wide component event
+type ComponentEventType int
+
+const (
+	// ComponentFocused is sent when the component becomes active or loses the activity
+	ComponentFocused = ComponentEventType(iota)
+	// ComponentClosed is sent after the component is closed and removed from the tree
+	ComponentClosed
+	// ComponentBoundsChanged is sent when the component bounds are changed
+	ComponentBoundsChanged
+	// ComponentVisibilityChanged is sent when the component visibility is changed
+	ComponentVisibilityChanged
+	// ComponentEnabledChanged is sent when the component is enabled or disabled
+	ComponentEnabledChanged
+	// ComponentAdded is sent when the component is added to its owner
+	ComponentAdded
+)
+
+// ComponentEvent describes a change of a component in the tree
+type ComponentEvent struct {
+	Type ComponentEventType
+	Comp Component
+	// Focused contains the new activity state for ComponentFocused
+	Focused bool
+	// Visible contains the new visibility for ComponentVisibilityChanged
+	Visible bool
+	// Enabled contains the new enabled state for ComponentEnabledChanged
+	Enabled bool
+	// Bounds contains the new bounds for ComponentBoundsChanged
+	Bounds Rectangle
+	// path contains the component owners at the moment of the event
+	path []Component
+}
+
+// ComponentListener receives the tree-wide component events
+type ComponentListener func(ev ComponentEvent)
+
+type componentListenerHolder struct {
+	root Component
+	cl   ComponentListener
+}
+
+func (ct ComponentEventType) String() string {
+	switch ct {
+	case ComponentFocused:
+		return "focused"
+	case ComponentClosed:
+		return "closed"
+	case ComponentBoundsChanged:
+		return "bounds changed"
+	case ComponentVisibilityChanged:
+		return "visibility changed"
+	case ComponentEnabledChanged:
+		return "enabled changed"
+	case ComponentAdded:
+		return "added"
+	}
+	return "unknown"
+}
+
+// Owner returns the owner of the event component at the moment of the event
+func (ev ComponentEvent) Owner() Component {
+	if len(ev.path) == 0 {
+		return nil
+	}
+	return ev.path[0]
+}
+
+// isIn returns whether the event is for the root or one of its descendants
+func (ev ComponentEvent) isIn(root Component) bool {
+	if root == nil || ev.Comp == root {
+		return true
+	}
+	for _, o := range ev.path {
+		if o == root {
+			return true
+		}
+	}
+	return false
+}
+
+// notify queues the event ev for comp with the path of its owners at the moment. The events are
+// delivered to the listeners from the controller go-routine
+func (c *controller) notify(comp Component, ev ComponentEvent) {
+	ev.Comp = comp
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.componentListeners) == 0 {
+		return
+	}
+	// the owners are changed under the lock, so the path is consistent
+	for o := comp.box().owner; o != nil; o = o.box().owner {
+		ev.path = append(ev.path, o)
+	}
+	c.events = append(c.events, ev)
+}
+
+// deliverEvents sends the queued events to the listeners
+func (c *controller) deliverEvents() {
+	c.lock.Lock()
+	events := c.events
+	c.events = nil
+	cls := c.componentListeners
+	c.lock.Unlock()
+	for _, ev := range events {
+		for _, h := range cls {
+			if ev.isIn(h.root) {
+				h.cl(ev)
+			}
+		}
+	}
+}
+
+func (c *controller) subscribe(root Component, cl ComponentListener) func() {
+	h := &componentListenerHolder{root: root, cl: cl}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.componentListeners = append(c.componentListeners[:len(c.componentListeners):len(c.componentListeners)], h)
+	return func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		cls := make([]*componentListenerHolder, 0, len(c.componentListeners))
+		for _, h1 := range c.componentListeners {
+			if h1 != h {
+				cls = append(cls, h1)
+			}
+		}
+		c.componentListeners = cls
+	}
+}
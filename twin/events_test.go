@@ -0,0 +1,119 @@
+package twin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newEventsBox(t *testing.T, owner Component) *testBox {
+	tb := &testBox{}
+	assert.Nil(t, tb.Init(owner, tb))
+	if owner == Root() {
+		t.Cleanup(func() {
+			Close(tb)
+			c.onLoop()
+		})
+	}
+	return tb
+}
+
+func TestSubscribe_Root(t *testing.T) {
+	r1 := newEventsBox(t, Root())
+	r2 := newEventsBox(t, Root())
+
+	var in1, all []Component
+	unsubscribe1 := Subscribe(r1, func(ev ComponentEvent) {
+		in1 = append(in1, ev.Comp)
+	})
+	defer unsubscribe1()
+	unsubscribeAll := Subscribe(nil, func(ev ComponentEvent) {
+		all = append(all, ev.Comp)
+	})
+	defer unsubscribeAll()
+
+	ch1 := newEventsBox(t, r1)
+	gch1 := newEventsBox(t, ch1)
+	ch2 := newEventsBox(t, r2)
+	c.deliverEvents()
+	assert.Equal(t, []Component{ch1, gch1}, in1)
+	assert.Equal(t, []Component{ch1, gch1, ch2}, all)
+
+	// the root events are delivered to its listener as well
+	in1, all = nil, nil
+	r1.SetVisible(false)
+	r2.SetVisible(false)
+	newEventsBox(t, r2)
+	c.deliverEvents()
+	assert.Equal(t, []Component{r1}, in1)
+	assert.Len(t, all, 3)
+}
+
+func TestSubscribe_ClosedOwner(t *testing.T) {
+	r := newEventsBox(t, Root())
+	ch := newEventsBox(t, r)
+	gch := newEventsBox(t, ch)
+
+	var closed []ComponentEvent
+	unsubscribe := Subscribe(r, func(ev ComponentEvent) {
+		if ev.Type == ComponentClosed {
+			closed = append(closed, ev)
+		}
+	})
+	defer unsubscribe()
+
+	// the owners are removed on close, but the events keep the path of the moment they happen
+	Close(ch)
+	c.onLoop()
+	assert.Len(t, closed, 2)
+	for _, ev := range closed {
+		switch ev.Comp {
+		case ch:
+			assert.Equal(t, Component(r), ev.Owner())
+		case gch:
+			assert.Equal(t, Component(ch), ev.Owner())
+		default:
+			assert.Fail(t, "unexpected component", ev.Comp)
+		}
+	}
+	assert.Nil(t, ch.box().owner)
+}
+
+func TestSubscribe_UnsubscribeOnDelivery(t *testing.T) {
+	r := newEventsBox(t, Root())
+
+	var cnt1, cnt2 int
+	var unsubscribe1 func()
+	unsubscribe1 = Subscribe(r, func(ev ComponentEvent) {
+		cnt1++
+		unsubscribe1()
+	})
+	unsubscribe2 := Subscribe(r, func(ev ComponentEvent) {
+		cnt2++
+	})
+	defer unsubscribe2()
+
+	newEventsBox(t, r)
+	c.deliverEvents()
+	assert.Equal(t, 1, cnt1)
+	assert.Equal(t, 1, cnt2)
+
+	newEventsBox(t, r)
+	c.deliverEvents()
+	assert.Equal(t, 1, cnt1)
+	assert.Equal(t, 2, cnt2)
+}
+
+func TestNotify_NoListeners(t *testing.T) {
+	c.lock.Lock()
+	assert.Empty(t, c.componentListeners)
+	c.lock.Unlock()
+
+	r := newEventsBox(t, Root())
+	newEventsBox(t, r)
+	r.SetVisible(false)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	assert.Empty(t, c.events)
+}
@@ -0,0 +1,12 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// the tests run without the terminal
+func init() {
+	if err := SetScreen(tcell.NewSimulationScreen("UTF-8")); err != nil {
+		panic(err)
+	}
+}
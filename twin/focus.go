@@ -0,0 +1,269 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// FocusListener is notified when the focus is moved from the prev to the cur component.
+// Any of them may be nil, if no component was or is focused.
+type FocusListener func(prev, cur Component)
+
+type focusMoveEvent struct {
+	tcell.EventTime
+	dir int
+}
+
+type focusListenerHolder struct {
+	fl FocusListener
+}
+
+// SetTabOrder defines the order in which the focus traverses the component descendants.
+// The components from the order go first, all other children follow in their natural order.
+// The order may contain not only the direct children, but any descendants of the component.
+func (b *Box) SetTabOrder(order []Component) {
+	b.tabOrder.Store(append([]Component(nil), order...))
+}
+
+// SetFocusScope makes the component a focus scope: the focus traversal (Tab, Backtab) doesn't
+// leave the scope if a component inside it is focused, and doesn't enter the scope from outside.
+// The modal pads are focus scopes always.
+func (b *Box) SetFocusScope(scope bool) {
+	b.focusScope.Store(scope)
+}
+
+// tabOrdered returns the children in the tab order, the closed components from the order are skipped
+func (b *Box) tabOrdered() []Component {
+	children := b.children()
+	v, _ := b.tabOrder.Load().([]Component)
+	if len(v) == 0 {
+		return children
+	}
+	res := make([]Component, 0, len(v)+len(children))
+	for _, comp := range v {
+		if !comp.box().isClosed() {
+			res = append(res, comp)
+		}
+	}
+	return append(res, children...)
+}
+
+// focused returns the deepest active component, or the root if no one is active
+func (c *controller) focused() Component {
+	var comp Component = c.root
+	for {
+		_, chld := comp.box().getActiveChild()
+		if chld == nil {
+			return comp
+		}
+		comp = chld
+	}
+}
+
+// focusScope returns the nearest focus scope, which contains comp
+func (c *controller) focusScope(comp Component) Component {
+	for comp != nil {
+		if comp.box().focusScope.Load() {
+			return comp
+		}
+		comp = comp.box().owner
+	}
+	return c.root
+}
+
+// focusChain collects the focusable components of the scope in the depth-first order. A
+// container is added to the chain only if it has no focusable descendants. It returns whether
+// the comp or its descendants are in the chain.
+func (c *controller) focusChain(scope, comp Component, visited map[Component]bool, res []Component) ([]Component, bool) {
+	if visited[comp] {
+		// the component is collected already, the descendants from the tab order may go before their owner
+		return res, true
+	}
+	if !canHoldFocus(comp) || comp != scope && comp.box().focusScope.Load() {
+		return res, false
+	}
+	visited[comp] = true
+	found := false
+	for _, chld := range comp.box().tabOrdered() {
+		if !isReachable(chld, comp) {
+			continue
+		}
+		var ok bool
+		res, ok = c.focusChain(scope, chld, visited, res)
+		found = found || ok
+	}
+	if !found && comp != scope {
+		res = append(res, comp)
+	}
+	return res, true
+}
+
+// canHoldFocus returns whether the comp may be active
+func canHoldFocus(comp Component) bool {
+	return comp.IsVisible() && comp.IsEnabled() && comp.CanBeFocused() && !comp.box().isClosed()
+}
+
+// isReachable returns whether the comp is the owner descendant, and all the components between
+// them may hold the focus and are not focus scopes
+func isReachable(comp, owner Component) bool {
+	o := comp.box().owner
+	for ; o != nil && o != owner; o = o.box().owner {
+		if !canHoldFocus(o) || o.box().focusScope.Load() {
+			return false
+		}
+	}
+	return o != nil
+}
+
+// focusNext moves the focus to the next (dir > 0) or the previous (dir < 0) component in the
+// focus chain of the current scope with the wrap-around. Must be called from the controller go-routine.
+func (c *controller) focusNext(dir int) bool {
+	cur := c.focused()
+	scope := c.focusScope(cur)
+	chain, _ := c.focusChain(scope, scope, map[Component]bool{}, nil)
+	n := len(chain)
+	if n == 0 {
+		return false
+	}
+	idx := childIndex(chain, cur)
+	if idx == n {
+		// the current one is a container, or it can't be focused anymore, the components around
+		// its place in the traversal order are the next and the previous ones
+		idx = c.chainIndexAfter(scope, cur, chain)
+		if dir > 0 {
+			return c.focusTo(chain[idx%n])
+		}
+	}
+	if dir > 0 {
+		idx = (idx + 1) % n
+	} else {
+		idx = (idx - 1 + n) % n
+	}
+	return c.focusTo(chain[idx])
+}
+
+// focusSibling moves the focus from the active child of owner to the next (dir > 0) or the
+// previous (dir < 0) child, which may be focused, in the tab order. There is no wrap-around, so
+// it returns false at the ends and when no child is active. Must be called from the controller
+// go-routine.
+func (c *controller) focusSibling(owner Component, dir int) bool {
+	var siblings []Component
+	seen := make(map[Component]bool)
+	for _, chld := range owner.box().tabOrdered() {
+		if !seen[chld] && chld.box().owner == owner && canHoldFocus(chld) {
+			seen[chld] = true
+			siblings = append(siblings, chld)
+		}
+	}
+	_, act := owner.box().getActiveChild()
+	idx := childIndex(siblings, act)
+	if idx == len(siblings) {
+		// the owner itself is focused, the key is for its owner then
+		return false
+	}
+	idx += dir
+	if idx < 0 || idx >= len(siblings) {
+		return false
+	}
+	return c.focusTo(siblings[idx])
+}
+
+// chainIndexAfter returns the index of the first chain component, which follows comp in the
+// traversal order, or len(chain), if there is no one
+func (c *controller) chainIndexAfter(scope, comp Component, chain []Component) int {
+	order := make(map[Component]int)
+	traversalOrder(scope, scope, order)
+	pos, ok := order[comp]
+	if !ok {
+		return 0
+	}
+	for i, chc := range chain {
+		if order[chc] > pos {
+			return i
+		}
+	}
+	return len(chain)
+}
+
+// traversalOrder collects the positions of the scope components in the focus traversal order,
+// regardless of whether they can be focused
+func traversalOrder(scope, comp Component, order map[Component]int) {
+	if _, ok := order[comp]; ok {
+		return
+	}
+	order[comp] = len(order)
+	if comp != scope && comp.box().focusScope.Load() {
+		return
+	}
+	for _, chld := range comp.box().tabOrdered() {
+		if isOwnedBy(chld, comp) {
+			traversalOrder(scope, chld, order)
+		}
+	}
+}
+
+// focusTo makes the comp active and scrolls its owners to show it
+func (c *controller) focusTo(comp Component) bool {
+	if !c.setActive(comp) {
+		return false
+	}
+	ScrollIntoView(comp)
+	return true
+}
+
+// checkFocus notifies the focus listeners if the focused component is changed. If the focused
+// component is disabled, the focus is moved to the next one.
+func (c *controller) checkFocus() {
+	cur := c.focused()
+	if !IsEnabled(cur) {
+		if !c.focusNext(1) {
+			setActiveFalse(c.root)
+		}
+		cur = c.focused()
+	}
+	if cur == Component(c.root) {
+		cur = nil
+	}
+	if cur == c.lastFocused {
+		return
+	}
+	prev := c.lastFocused
+	c.lastFocused = cur
+	c.lock.Lock()
+	fls := c.focusListeners
+	c.lock.Unlock()
+	for _, h := range fls {
+		h.fl(prev, cur)
+	}
+}
+
+func (c *controller) addFocusListener(fl FocusListener) func() {
+	h := &focusListenerHolder{fl: fl}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.focusListeners = append(c.focusListeners[:len(c.focusListeners):len(c.focusListeners)], h)
+	return func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		idx := -1
+		for i, h1 := range c.focusListeners {
+			if h1 == h {
+				idx = i
+			}
+		}
+		if idx >= 0 {
+			fls := make([]*focusListenerHolder, 0, len(c.focusListeners)-1)
+			fls = append(fls, c.focusListeners[:idx]...)
+			c.focusListeners = append(fls, c.focusListeners[idx+1:]...)
+		}
+	}
+}
+
+// isOwnedBy returns whether the comp is the owner descendant
+func isOwnedBy(comp, owner Component) bool {
+	for o := comp.box().owner; o != nil; o = o.box().owner {
+		if o == owner {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newFocusScope(t *testing.T) *scrollerBox {
+	s := &scrollerBox{}
+	assert.Nil(t, s.Init(Root(), s))
+	s.SetFocusScope(true)
+	t.Cleanup(func() {
+		Close(s)
+		c.onLoop()
+	})
+	return s
+}
+
+func newInput(t *testing.T, owner Component) *inputBox {
+	ib := &inputBox{}
+	assert.Nil(t, ib.Init(owner, ib))
+	return ib
+}
+
+func focusChainOf(scope Component) []Component {
+	res, _ := c.focusChain(scope, scope, map[Component]bool{}, nil)
+	return res
+}
+
+func TestFocusChain(t *testing.T) {
+	s := newFocusScope(t)
+	a := newInput(t, s)
+	cont := &scrollerBox{}
+	assert.Nil(t, cont.Init(s, cont))
+	g1 := newInput(t, cont)
+	g2 := newInput(t, cont)
+	empty := &scrollerBox{}
+	assert.Nil(t, empty.Init(s, empty))
+	assert.Equal(t, []Component{a, g1, g2, empty}, focusChainOf(s))
+
+	g2.SetEnabled(false)
+	assert.Equal(t, []Component{a, g1, empty}, focusChainOf(s))
+	g1.SetVisible(false)
+	// the container without the focusable descendants is the tab stop itself
+	assert.Equal(t, []Component{a, cont, empty}, focusChainOf(s))
+
+	// the nested scope is not entered
+	inner := &scrollerBox{}
+	assert.Nil(t, inner.Init(s, inner))
+	inner.SetFocusScope(true)
+	newInput(t, inner)
+	assert.Equal(t, []Component{a, cont, empty}, focusChainOf(s))
+}
+
+func TestFocusChain_TabOrder(t *testing.T) {
+	s := newFocusScope(t)
+	a := newInput(t, s)
+	cont := &scrollerBox{}
+	assert.Nil(t, cont.Init(s, cont))
+	g := newInput(t, cont)
+	// the descendants of the not focusable owner can't be active
+	plain := &testBox{}
+	assert.Nil(t, plain.Init(s, plain))
+	h := newInput(t, plain)
+	other := newInput(t, Root())
+	t.Cleanup(func() { Close(other) })
+
+	s.SetTabOrder([]Component{g, h, other, a})
+	// the grandchild from the tab order doesn't make its container a tab stop
+	assert.Equal(t, []Component{g, a}, focusChainOf(s))
+	assert.False(t, c.setActive(h))
+
+	Close(g)
+	assert.Equal(t, []Component{h, other, a}, s.tabOrdered()[:3])
+	assert.Equal(t, []Component{a, cont}, focusChainOf(s))
+
+	assert.True(t, c.setActive(a))
+	assert.True(t, c.focusNext(1))
+	assert.Equal(t, Component(cont), c.focused())
+	assert.True(t, c.focusNext(1))
+	assert.Equal(t, Component(a), c.focused())
+}
+
+func TestCheckFocus_Disabled(t *testing.T) {
+	s := newFocusScope(t)
+	a := newInput(t, s)
+	cont := &scrollerBox{}
+	assert.Nil(t, cont.Init(s, cont))
+	g1 := newInput(t, cont)
+	newInput(t, cont)
+	b := newInput(t, s)
+	z := newInput(t, s)
+
+	// the focus goes to the next one after the disabled component place
+	assert.True(t, c.setActive(b))
+	b.SetEnabled(false)
+	c.checkFocus()
+	assert.Equal(t, Component(z), c.focused())
+
+	z.SetEnabled(false)
+	c.checkFocus()
+	assert.Equal(t, Component(a), c.focused())
+
+	b.SetEnabled(true)
+	z.SetEnabled(true)
+	assert.True(t, c.setActive(g1))
+	cont.SetEnabled(false)
+	c.checkFocus()
+	assert.Equal(t, Component(b), c.focused())
+
+	// the previous one is found by the place as well
+	cont.SetEnabled(true)
+	assert.True(t, c.setActive(g1))
+	cont.SetVisible(false)
+	assert.True(t, c.focusNext(-1))
+	assert.Equal(t, Component(a), c.focused())
+}
+
+func TestBox_OnKeyPressed(t *testing.T) {
+	s := newFocusScope(t)
+	newFocusable := func(owner Component) *scrollerBox {
+		sb := &scrollerBox{}
+		assert.Nil(t, sb.Init(owner, sb))
+		return sb
+	}
+	a := newFocusable(s)
+	cont := newFocusable(s)
+	g1 := newFocusable(cont)
+	g2 := newFocusable(cont)
+	b := newFocusable(s)
+	key := func(k tcell.Key) bool {
+		return c.onKeyPressed(c.root, tcell.NewEventKey(k, 0, tcell.ModNone))
+	}
+
+	assert.True(t, c.setActive(g1))
+	assert.True(t, key(tcell.KeyDown))
+	assert.Equal(t, Component(g2), c.focused())
+	// the container has no next child, so its owner moves the focus to the container sibling
+	assert.True(t, key(tcell.KeyDown))
+	assert.Equal(t, Component(b), c.focused())
+	// Down and Up don't wrap around, the key is not handled at the ends
+	assert.False(t, key(tcell.KeyDown))
+	assert.Equal(t, Component(b), c.focused())
+	assert.True(t, key(tcell.KeyUp))
+	assert.Equal(t, Component(cont), c.focused())
+	assert.True(t, key(tcell.KeyUp))
+	assert.Equal(t, Component(a), c.focused())
+	assert.False(t, key(tcell.KeyUp))
+
+	// Tab and Backtab traverse the whole scope with the wrap-around
+	assert.True(t, key(tcell.KeyBacktab))
+	assert.Equal(t, Component(b), c.focused())
+	assert.True(t, key(tcell.KeyTab))
+	assert.Equal(t, Component(a), c.focused())
+}
+
+func TestModalPad_OnKeyPressed(t *testing.T) {
+	m := &modalPad{}
+	assert.Nil(t, m.Init(Root(), m))
+	m.SetFocusScope(true)
+	t.Cleanup(func() {
+		Close(m)
+		c.onLoop()
+	})
+	ib := newInput(t, m)
+	assert.True(t, c.setActive(m))
+
+	// nothing is focused on the pad, so the key goes to the first component, which can be focused
+	assert.True(t, c.onKeyPressed(c.root, tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone)))
+	assert.Equal(t, Component(ib), c.focused())
+	assert.Equal(t, []tcell.Key{tcell.KeyRune}, ib.keys)
+}
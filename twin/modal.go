@@ -6,6 +6,15 @@ import (
 
 type modalPad struct {
 	Box
+	// backdrop and alpha define how the components under the pad are dimmed
+	backdrop tcell.Color
+	alpha    float64
+}
+
+func (m *modalPad) OnDraw(cc *CanvasContext) {
+	if m.alpha > 0 {
+		cc.BlendRectangle(m.Bounds().Normalized(), m.backdrop, m.alpha)
+	}
 }
 
 func (m *modalPad) CanBeFocused() bool { return true }
@@ -23,14 +32,12 @@ func (m *modalPad) OnKeyPressed(ke *tcell.EventKey) bool {
 	}
 	_, chld := m.getActiveChild()
 	if chld == nil {
-		chldrn := m.children()
-		if len(chldrn) == 0 {
+		// nothing is focused on the pad, the first component of its focus chain gets the key
+		if !c.focusNext(1) {
 			return true
 		}
-		chld = chldrn[0]
-		chld.box().setActive(true)
+		c.onKeyPressed(m.this, ke)
 	}
-	chld.OnKeyPressed(ke)
 	return true
 }
 
@@ -0,0 +1,88 @@
+package twin
+
+import (
+	"fmt"
+	"golang.org/x/sys/unix"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// StartPty starts the cmd in the new session with the pty of the size sz as its controlling
+// terminal, stdin, stdout and stderr. It returns the pty master, the cmd output is read from
+// it and the input is written to it. TERM is set to xterm-256color, if the cmd environment
+// doesn't define it (see VTerm).
+func StartPty(cmd *exec.Cmd, sz Size) (*os.File, error) {
+	ptm, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not open the pty: %w", err)
+	}
+	var n uint32
+	err = ptyControl(ptm, func(fd int) error {
+		if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+			return err
+		}
+		n, err = unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+		return err
+	})
+	if err != nil {
+		ptm.Close()
+		return nil, fmt.Errorf("could not unlock the pty: %w", err)
+	}
+	pts, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		ptm.Close()
+		return nil, fmt.Errorf("could not open the pty slave: %w", err)
+	}
+	defer pts.Close()
+	if err := SetPtySize(ptm, sz); err != nil {
+		ptm.Close()
+		return nil, err
+	}
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = pts, pts, pts
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if cmd.Env == nil {
+		cmd.Env = append(os.Environ(), "TERM=xterm-256color")
+	} else if !hasEnv(cmd.Env, "TERM") {
+		cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+	}
+	if err := cmd.Start(); err != nil {
+		ptm.Close()
+		return nil, fmt.Errorf("could not start %s: %w", cmd.Path, err)
+	}
+	return ptm, nil
+}
+
+// SetPtySize sets the pty window size, the program running in the pty receives SIGWINCH
+func SetPtySize(pty *os.File, sz Size) error {
+	err := ptyControl(pty, func(fd int) error {
+		return unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, &unix.Winsize{Row: uint16(sz.Height), Col: uint16(sz.Width)})
+	})
+	if err != nil {
+		return fmt.Errorf("could not set the pty size to %v: %w", sz, err)
+	}
+	return nil
+}
+
+// ptyControl calls f with the pty file descriptor, without switching the file to the blocking mode
+func ptyControl(pty *os.File, f func(fd int) error) error {
+	rc, err := pty.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var ferr error
+	if err := rc.Control(func(fd uintptr) { ferr = f(int(fd)) }); err != nil {
+		return err
+	}
+	return ferr
+}
+
+func hasEnv(env []string, name string) bool {
+	for _, kv := range env {
+		if strings.HasPrefix(kv, name+"=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package twin
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestStartPty(t *testing.T) {
+	cmd := exec.Command("/bin/sh")
+	pty, err := StartPty(cmd, Size{Width: 40, Height: 10})
+	assert.Nil(t, err)
+	defer pty.Close()
+
+	v := NewVTerm(Size{Width: 40, Height: 10})
+	v.SetReply(pty)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(v, pty)
+	}()
+	assert.Nil(t, SetPtySize(pty, Size{Width: 33, Height: 7}))
+	_, err = pty.Write([]byte("stty size; echo \"$TERM\" hello-$((1+2)); exit 3\n"))
+	assert.Nil(t, err)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shell is not finished")
+	}
+	// the prompt may precede the output
+	assert.Regexp(t, `(?m)7 33 *$`, v.String())
+	assert.Regexp(t, `(?m)^xterm-256color hello-3 *$`, v.String())
+	var ee *exec.ExitError
+	assert.ErrorAs(t, cmd.Wait(), &ee)
+	assert.Equal(t, 3, ee.ExitCode())
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package twin
+
+import (
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"os"
+	"os/exec"
+)
+
+func StartPty(cmd *exec.Cmd, sz Size) (*os.File, error) {
+	return nil, fmt.Errorf("the pty: %w", errors.ErrUnimplemented)
+}
+
+func SetPtySize(pty *os.File, sz Size) error {
+	return fmt.Errorf("the pty: %w", errors.ErrUnimplemented)
+}
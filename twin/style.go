@@ -0,0 +1,242 @@
+package twin
+
+import (
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/gdamore/tcell/v2"
+	"strings"
+	"sync/atomic"
+)
+
+// StyleState is the component state the style is selected for
+type StyleState int
+
+const (
+	StateNormal = StyleState(iota)
+	StateFocused
+	StateDisabled
+	StateHovered
+	StatePressed
+	stateAny
+)
+
+// StyleRule defines the style for the components matching the Selector. The selector has
+// the CSS-like form "type.class:state", where any part may be omitted, for example:
+// "button", "button:focused", ".primary", "button.primary:pressed", "*". The type is the component
+// style type (see Box.SetStyleType()), the class is one of the component classes (see
+// Box.SetStyleClass()) and the state is one of normal, focused, disabled, hovered or pressed.
+type StyleRule struct {
+	Selector string
+	Style    tcell.Style
+}
+
+// StyleSheet is the compiled list of style rules
+type StyleSheet struct {
+	rules []styleRule
+}
+
+type styleRule struct {
+	tp    string
+	class string
+	state StyleState
+	style tcell.Style
+}
+
+type compHolder struct {
+	comp Component
+}
+
+var (
+	styleSheet atomic.Pointer[StyleSheet]
+
+	stateNames = map[string]StyleState{
+		"normal":   StateNormal,
+		"focused":  StateFocused,
+		"disabled": StateDisabled,
+		"hovered":  StateHovered,
+		"pressed":  StatePressed,
+	}
+)
+
+func (s StyleState) String() string {
+	for n, st := range stateNames {
+		if st == s {
+			return n
+		}
+	}
+	return "any"
+}
+
+// NewStyleSheet compiles the rules. If several rules with the same specificity match a component,
+// the last one wins.
+func NewStyleSheet(rules []StyleRule) (*StyleSheet, error) {
+	ss := &StyleSheet{rules: make([]styleRule, 0, len(rules))}
+	for _, r := range rules {
+		sr, err := parseSelector(r.Selector)
+		if err != nil {
+			return nil, err
+		}
+		sr.style = r.Style
+		ss.rules = append(ss.rules, sr)
+	}
+	return ss, nil
+}
+
+// SetStyleSheet sets the style sheet, which is used for resolving the components styles,
+// and redraws all the components
+func SetStyleSheet(ss *StyleSheet) {
+	styleSheet.Store(ss)
+	c.invalidateAllCaches(c.root)
+	Redraw(Root())
+}
+
+// StyleOf returns the style of comp for the state. The style is resolved in the order:
+//   - the comp style for the state set by Box.SetStateStyle()
+//   - the most specific style sheet rule for the state
+//   - the comp style set for StateNormal
+//   - the most specific style sheet rule without the state
+//   - the comp owner effective style
+func StyleOf(comp Component, state StyleState) tcell.Style {
+	return resolveStyle(comp.box(), []StyleState{state})
+}
+
+// EffectiveStyle returns the style of comp for its current states. If the component is in
+// several states at the same time (see States()), the first state, which has a style defined,
+// is used.
+func EffectiveStyle(comp Component) tcell.Style {
+	return resolveStyle(comp.box(), States(comp))
+}
+
+// States returns the current comp states in the priority order: disabled, pressed, hovered,
+// focused, normal. The component is disabled if it or any of its owners is disabled.
+func States(comp Component) []StyleState {
+	res := make([]StyleState, 0, 4)
+	if !IsEnabled(comp) {
+		return append(res, StateDisabled, StateNormal)
+	}
+	if h, _ := c.pressed.Load().(compHolder); h.comp == comp {
+		res = append(res, StatePressed)
+	}
+	if h, _ := c.hovered.Load().(compHolder); h.comp == comp {
+		res = append(res, StateHovered)
+	}
+	if comp.box().isActive() {
+		res = append(res, StateFocused)
+	}
+	return append(res, StateNormal)
+}
+
+func resolveStyle(b *Box, states []StyleState) tcell.Style {
+	ss := styleSheet.Load()
+	for _, state := range states {
+		if s, ok := b.stateStyle(state); ok {
+			return s
+		}
+		if s, ok := ss.match(b, state); ok {
+			return s
+		}
+	}
+	if s, ok := b.stateStyle(StateNormal); ok {
+		return s
+	}
+	if s, ok := ss.match(b, stateAny); ok {
+		return s
+	}
+	if b.owner != nil {
+		return EffectiveStyle(b.owner)
+	}
+	return tcell.StyleDefault
+}
+
+// SetStyleType sets the component type the style sheet rules are matched by
+func (b *Box) SetStyleType(tp string) {
+	b.styleType.Store(tp)
+}
+
+// SetStyleClass sets the space separated list of the component classes, the style sheet
+// rules are matched by
+func (b *Box) SetStyleClass(class string) {
+	b.styleClasses.Store(strings.Fields(class))
+}
+
+// SetStateStyle defines the component style for the state, it overrides the style sheet rules
+func (b *Box) SetStateStyle(state StyleState, style tcell.Style) {
+	b.styleLock.Lock()
+	defer b.styleLock.Unlock()
+	if b.stateStyles == nil {
+		b.stateStyles = make(map[StyleState]tcell.Style)
+	}
+	b.stateStyles[state] = style
+}
+
+func (b *Box) stateStyle(state StyleState) (tcell.Style, bool) {
+	b.styleLock.Lock()
+	defer b.styleLock.Unlock()
+	s, ok := b.stateStyles[state]
+	return s, ok
+}
+
+func (b *Box) hasStyleClass(class string) bool {
+	classes, _ := b.styleClasses.Load().([]string)
+	for _, cl := range classes {
+		if cl == class {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the most specific rule style for the box and the state. The rule without
+// the state is matched only if state is stateAny
+func (ss *StyleSheet) match(b *Box, state StyleState) (tcell.Style, bool) {
+	if ss == nil {
+		return tcell.StyleDefault, false
+	}
+	tp, _ := b.styleType.Load().(string)
+	best := -1
+	var res tcell.Style
+	for _, r := range ss.rules {
+		if r.state != state || (r.tp != "" && r.tp != tp) || (r.class != "" && !b.hasStyleClass(r.class)) {
+			continue
+		}
+		spec := 0
+		if r.tp != "" {
+			spec++
+		}
+		if r.class != "" {
+			spec += 2
+		}
+		if spec >= best {
+			best = spec
+			res = r.style
+		}
+	}
+	return res, best >= 0
+}
+
+func parseSelector(sel string) (styleRule, error) {
+	sr := styleRule{state: stateAny}
+	s := strings.TrimSpace(sel)
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		st, ok := stateNames[strings.ToLower(s[i+1:])]
+		if !ok {
+			return sr, fmt.Errorf("unknown state in the selector %q: %w", sel, errors.ErrInvalid)
+		}
+		sr.state = st
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		sr.class = s[i+1:]
+		if sr.class == "" || strings.ContainsAny(sr.class, ". ") {
+			return sr, fmt.Errorf("invalid class in the selector %q: %w", sel, errors.ErrInvalid)
+		}
+		s = s[:i]
+	}
+	if s != "*" {
+		sr.tp = s
+	}
+	if strings.ContainsAny(sr.tp, " *") {
+		return sr, fmt.Errorf("invalid type in the selector %q: %w", sel, errors.ErrInvalid)
+	}
+	return sr, nil
+}
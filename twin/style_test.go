@@ -0,0 +1,136 @@
+package twin
+
+import (
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	for sel, want := range map[string]styleRule{
+		"*":                      {state: stateAny},
+		"button":                 {tp: "button", state: stateAny},
+		" button ":               {tp: "button", state: stateAny},
+		".primary":               {class: "primary", state: stateAny},
+		"*.primary":              {class: "primary", state: stateAny},
+		"button.primary:Pressed": {tp: "button", class: "primary", state: StatePressed},
+		":focused":               {state: StateFocused},
+		"button:normal":          {tp: "button", state: StateNormal},
+	} {
+		sr, err := parseSelector(sel)
+		assert.Nil(t, err, sel)
+		assert.Equal(t, want, sr, sel)
+	}
+	for _, sel := range []string{"button:active", "button.", ".a.b", "but ton", "b*", "button:"} {
+		_, err := parseSelector(sel)
+		assert.ErrorIs(t, err, errors.ErrInvalid, sel)
+	}
+	_, err := NewStyleSheet([]StyleRule{{Selector: "button"}, {Selector: "button:unknown"}})
+	assert.ErrorIs(t, err, errors.ErrInvalid)
+}
+
+// styledBox is the test component, which may be focused, if focusable is true
+type styledBox struct {
+	Box
+	focusable bool
+}
+
+func (sb *styledBox) CanBeFocused() bool { return sb.focusable }
+
+func TestStyleOf(t *testing.T) {
+	prev := styleSheet.Load()
+	t.Cleanup(func() { SetStyleSheet(prev) })
+	styles := make([]tcell.Style, 10)
+	for i := range styles {
+		styles[i] = tcell.StyleDefault.Foreground(tcell.PaletteColor(i + 1))
+	}
+	ss, err := NewStyleSheet([]StyleRule{
+		{Selector: "button.primary", Style: styles[9]},
+		{Selector: "*", Style: styles[0]},
+		{Selector: "button", Style: styles[1]},
+		{Selector: ".primary", Style: styles[2]},
+		{Selector: "button.primary", Style: styles[3]},
+		{Selector: "button:focused", Style: styles[4]},
+		{Selector: ".primary:focused", Style: styles[5]},
+		{Selector: "button:disabled", Style: styles[6]},
+		{Selector: "win", Style: styles[7]},
+	})
+	assert.Nil(t, err)
+	SetStyleSheet(ss)
+
+	owner := &styledBox{}
+	assert.Nil(t, owner.Init(Root(), owner))
+	t.Cleanup(func() {
+		Close(owner)
+		c.onLoop()
+	})
+	owner.SetStyleType("win")
+	btn := &styledBox{focusable: true}
+	assert.Nil(t, btn.Init(owner, btn))
+	btn.SetStyleType("button")
+	btn.SetStyleClass(" big  primary ")
+	plain := &styledBox{}
+	assert.Nil(t, plain.Init(btn, plain))
+
+	// the most specific rule wins, the last one of the same specificity
+	assert.Equal(t, styles[3], StyleOf(btn, StateNormal))
+	assert.Equal(t, styles[5], StyleOf(btn, StateFocused))
+	assert.Equal(t, styles[6], StyleOf(btn, StateDisabled))
+	// the state without the rules gets the normal style
+	assert.Equal(t, styles[3], StyleOf(btn, StateHovered))
+	assert.Equal(t, styles[7], StyleOf(owner, StateFocused))
+
+	btn.SetStyleClass("big")
+	assert.Equal(t, styles[1], StyleOf(btn, StateNormal))
+	assert.Equal(t, styles[4], StyleOf(btn, StateFocused))
+	// the component without the type and the classes matches "*"
+	assert.Equal(t, styles[0], StyleOf(plain, StateNormal))
+
+	// the component styles override the sheet
+	btn.SetStateStyle(StateFocused, styles[8])
+	assert.Equal(t, styles[8], StyleOf(btn, StateFocused))
+	btn.SetStateStyle(StateNormal, styles[9])
+	assert.Equal(t, styles[9], StyleOf(btn, StateHovered))
+	assert.Equal(t, styles[6], StyleOf(btn, StateDisabled))
+
+	SetStyleSheet(nil)
+	assert.Equal(t, styles[9], StyleOf(btn, StateDisabled))
+	// the owner style is inherited
+	assert.Equal(t, styles[9], StyleOf(plain, StateNormal))
+	assert.Equal(t, tcell.StyleDefault, StyleOf(owner, StateNormal))
+}
+
+func TestEffectiveStyle(t *testing.T) {
+	prev := styleSheet.Load()
+	t.Cleanup(func() { SetStyleSheet(prev) })
+	normal := tcell.StyleDefault.Foreground(tcell.ColorRed)
+	focused := tcell.StyleDefault.Foreground(tcell.ColorGreen)
+	disabled := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	ss, err := NewStyleSheet([]StyleRule{
+		{Selector: "input", Style: normal},
+		{Selector: "input:focused", Style: focused},
+		{Selector: "input:disabled", Style: disabled},
+	})
+	assert.Nil(t, err)
+	SetStyleSheet(ss)
+
+	owner := &styledBox{focusable: true}
+	assert.Nil(t, owner.Init(Root(), owner))
+	t.Cleanup(func() {
+		Close(owner)
+		c.onLoop()
+	})
+	ib := &styledBox{focusable: true}
+	assert.Nil(t, ib.Init(owner, ib))
+	ib.SetStyleType("input")
+
+	assert.Equal(t, []StyleState{StateNormal}, States(ib))
+	assert.Equal(t, normal, EffectiveStyle(ib))
+	assert.True(t, c.setActive(ib))
+	assert.Equal(t, []StyleState{StateFocused, StateNormal}, States(ib))
+	assert.Equal(t, focused, EffectiveStyle(ib))
+	owner.SetEnabled(false)
+	assert.Equal(t, []StyleState{StateDisabled, StateNormal}, States(ib))
+	assert.Equal(t, disabled, EffectiveStyle(ib))
+}
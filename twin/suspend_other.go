@@ -0,0 +1,15 @@
+//go:build !unix
+
+package twin
+
+import (
+	"context"
+	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
+)
+
+func stopProcess() error {
+	return fmt.Errorf("the process suspending: %w", errors.ErrUnimplemented)
+}
+
+func watchSuspend(ctx context.Context) {}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package twin
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// stopProcess stops the process till it receives SIGCONT (fg in the shell)
+func stopProcess() error {
+	return syscall.Kill(os.Getpid(), syscall.SIGSTOP)
+}
+
+// watchSuspend turns SIGTSTP sent to the process to the suspend event, so the terminal is restored
+// before the process is stopped
+func watchSuspend(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTSTP)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				c.screen().PostEvent(&suspendEvent{})
+			}
+		}
+	}()
+}
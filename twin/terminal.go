@@ -0,0 +1,140 @@
+package twin
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"os"
+	"os/exec"
+)
+
+// TerminalEvent is the terminal state change the application may be notified about
+type TerminalEvent int
+
+const (
+	// TerminalFocusGained is sent when the terminal window (or tab) gets the focus
+	TerminalFocusGained = TerminalEvent(iota)
+	// TerminalFocusLost is sent when the terminal window (or tab) loses the focus
+	TerminalFocusLost
+	// TerminalSuspended is sent before the terminal is released, because the process is
+	// suspended or an external program is run
+	TerminalSuspended
+	// TerminalResumed is sent when the terminal is taken back and the screen is redrawn
+	TerminalResumed
+)
+
+// TerminalListener is notified about the terminal state changes
+type TerminalListener func(te TerminalEvent)
+
+type terminalListenerHolder struct {
+	tl TerminalListener
+}
+
+type suspendEvent struct {
+	tcell.EventTime
+}
+
+type execEvent struct {
+	tcell.EventTime
+	cmd    *exec.Cmd
+	onDone func(err error)
+}
+
+func (te TerminalEvent) String() string {
+	switch te {
+	case TerminalFocusGained:
+		return "TerminalFocusGained"
+	case TerminalFocusLost:
+		return "TerminalFocusLost"
+	case TerminalSuspended:
+		return "TerminalSuspended"
+	case TerminalResumed:
+		return "TerminalResumed"
+	}
+	return fmt.Sprintf("TerminalEvent(%d)", int(te))
+}
+
+func (c *controller) onTerminalFocus(focused bool) {
+	if c.termUnfocused.Swap(!focused) == !focused {
+		return
+	}
+	if focused {
+		c.notifyTerminal(TerminalFocusGained)
+	} else {
+		c.notifyTerminal(TerminalFocusLost)
+	}
+}
+
+// suspend releases the terminal, stops the process till it is continued, and takes the
+// terminal back
+func (c *controller) suspend() {
+	c.releaseTerminal(func() error {
+		return stopProcess()
+	})
+}
+
+// execute runs the cmd with the standard input and output connected to the terminal
+func (c *controller) execute(cmd *exec.Cmd, onDone func(err error)) {
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	err := c.releaseTerminal(cmd.Run)
+	if onDone != nil {
+		onDone(err)
+	}
+}
+
+// releaseTerminal restores the terminal state, calls f, and then takes the terminal back
+// and redraws the whole screen
+func (c *controller) releaseTerminal(f func() error) error {
+	c.notifyTerminal(TerminalSuspended)
+	if err := c.screen().Suspend(); err != nil {
+		return fmt.Errorf("could not release the terminal: %w", err)
+	}
+	err := f()
+	if rerr := c.screen().Resume(); rerr != nil && err == nil {
+		err = fmt.Errorf("could not take the terminal back: %w", rerr)
+	}
+	// the screen content is lost, so everything must be drawn again
+	c.screen().Clear()
+	c.onScreenResize()
+	c.reDrawNeeded(c.root, &tcell.EventTime{})
+	c.notifyTerminal(TerminalResumed)
+	return err
+}
+
+func (c *controller) notifyTerminal(te TerminalEvent) {
+	c.lock.Lock()
+	tls := c.terminalListeners
+	c.lock.Unlock()
+	for _, h := range tls {
+		h.tl(te)
+	}
+}
+
+func (c *controller) addTerminalListener(tl TerminalListener) func() {
+	h := &terminalListenerHolder{tl: tl}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.terminalListeners = append(c.terminalListeners[:len(c.terminalListeners):len(c.terminalListeners)], h)
+	return func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		idx := -1
+		for i, h1 := range c.terminalListeners {
+			if h1 == h {
+				idx = i
+			}
+		}
+		if idx >= 0 {
+			tls := make([]*terminalListenerHolder, 0, len(c.terminalListeners)-1)
+			tls = append(tls, c.terminalListeners[:idx]...)
+			c.terminalListeners = append(tls, c.terminalListeners[idx+1:]...)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"os/exec"
+	"testing"
+)
+
+func TestTerminalFocus(t *testing.T) {
+	var events []TerminalEvent
+	remove := AddTerminalListener(func(te TerminalEvent) { events = append(events, te) })
+	defer remove()
+
+	c.onEvent(tcell.NewEventFocus(false))
+	assert.False(t, IsTerminalFocused())
+	c.onEvent(tcell.NewEventFocus(false))
+	c.onEvent(tcell.NewEventFocus(true))
+	assert.True(t, IsTerminalFocused())
+	assert.Equal(t, []TerminalEvent{TerminalFocusLost, TerminalFocusGained}, events)
+}
+
+func TestExec(t *testing.T) {
+	var events []TerminalEvent
+	remove := AddTerminalListener(func(te TerminalEvent) { events = append(events, te) })
+	defer remove()
+
+	var res []error
+	c.onEvent(&execEvent{cmd: exec.Command("true"), onDone: func(err error) { res = append(res, err) }})
+	c.onEvent(&execEvent{cmd: exec.Command("false"), onDone: func(err error) { res = append(res, err) }})
+	assert.Len(t, res, 2)
+	assert.Nil(t, res[0])
+	assert.NotNil(t, res[1])
+	assert.Equal(t, []TerminalEvent{TerminalSuspended, TerminalResumed, TerminalSuspended, TerminalResumed}, events)
+	// the whole screen is redrawn after the terminal is taken back
+	assert.Contains(t, takeDamage(), c.root.Bounds())
+}
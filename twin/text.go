@@ -0,0 +1,291 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/uniseg"
+	"strings"
+	"unicode/utf8"
+)
+
+// SpanStyle defines how the span style differs from the base style it is printed with.
+// The zero value means the base style as is.
+type SpanStyle struct {
+	// Fg and Bg are the foreground and background colors, tcell.ColorDefault means the base one
+	Fg, Bg tcell.Color
+	// Attrs are added to the base style attributes
+	Attrs tcell.AttrMask
+}
+
+// Span is a part of the text with the same style
+type Span struct {
+	Text  string
+	Style SpanStyle
+	// Link is the URL the span refers to, if any
+	Link string
+	// Hotkey is true if the span is the hotkey char
+	Hotkey bool
+}
+
+// Text is the styled text, which consists of the spans
+type Text []Span
+
+// attrLetters maps the markup attribute letters to the attributes
+var attrLetters = map[rune]tcell.AttrMask{
+	'b': tcell.AttrBold,
+	'd': tcell.AttrDim,
+	'i': tcell.AttrItalic,
+	'l': tcell.AttrBlink,
+	'r': tcell.AttrReverse,
+	's': tcell.AttrStrikeThrough,
+	'u': tcell.AttrUnderline,
+}
+
+// Apply returns the base style modified by the span style
+func (ss SpanStyle) Apply(base tcell.Style) tcell.Style {
+	if ss.Fg != tcell.ColorDefault {
+		base = base.Foreground(ss.Fg)
+	}
+	if ss.Bg != tcell.ColorDefault {
+		base = base.Background(ss.Bg)
+	}
+	if ss.Attrs != 0 {
+		_, _, attrs := base.Decompose()
+		base = base.Attributes(attrs | ss.Attrs)
+		if ss.Attrs&tcell.AttrUnderline != 0 {
+			// tcell draws the underline by the underline style, not by the attribute
+			base = base.Underline(true)
+		}
+	}
+	return base
+}
+
+// PlainText returns the text of one span with the base style
+func PlainText(s string) Text {
+	if s == "" {
+		return nil
+	}
+	return Text{{Text: s}}
+}
+
+// ParseMarkup parses the text with the markup tags. The tag "[fg:bg:attrs:link]" changes the style
+// of the text which follows it, any part of the tag may be omitted or be empty, which means the
+// part is not changed, and "-" resets the part to the base style, for example:
+//
+//	"[red]error[-] in [::b]main.go[-]: [yellow:blue:bu]warning[-]"
+//
+// The colors are the tcell color names or "#rrggbb", the attributes are the letters: b - bold,
+// d - dim, i - italic, l - blink, r - reverse, s - strikethrough, u - underline. "[-]" resets
+// the whole style. The char after '&' is the hotkey, it is underlined. "[[" and "&&" are for the
+// literal '[' and '&'. The tags, which cannot be parsed, are left in the text as is.
+func ParseMarkup(markup string) Text {
+	var res Text
+	var cur Span
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			cur.Text = sb.String()
+			res = append(res, cur)
+			sb.Reset()
+		}
+	}
+	for i := 0; i < len(markup); i++ {
+		ch := markup[i]
+		switch {
+		case ch == '[' && strings.HasPrefix(markup[i:], "[["):
+			sb.WriteByte('[')
+			i++
+		case ch == '&' && strings.HasPrefix(markup[i:], "&&"):
+			sb.WriteByte('&')
+			i++
+		case ch == '[':
+			end := strings.IndexByte(markup[i:], ']')
+			if end < 0 {
+				sb.WriteByte(ch)
+				continue
+			}
+			span, ok := parseTag(cur, markup[i+1:i+end])
+			if !ok {
+				sb.WriteByte(ch)
+				continue
+			}
+			flush()
+			cur = span
+			i += end
+		case ch == '&' && i+1 < len(markup) && markup[i+1] != ' ':
+			flush()
+			_, n := utf8.DecodeRuneInString(markup[i+1:])
+			hk := cur
+			hk.Text, hk.Hotkey = markup[i+1:i+1+n], true
+			hk.Style.Attrs |= tcell.AttrUnderline
+			res = append(res, hk)
+			i += n
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	flush()
+	return res
+}
+
+// parseTag applies the tag to the span, it returns false if the tag cannot be parsed
+func parseTag(span Span, tag string) (Span, bool) {
+	if tag == "-" {
+		return Span{}, true
+	}
+	parts := strings.SplitN(tag, ":", 4)
+	clrs := []*tcell.Color{&span.Style.Fg, &span.Style.Bg}
+	for i, p := range parts {
+		switch {
+		case p == "":
+		case p == "-" && i < 3:
+			if i < 2 {
+				*clrs[i] = tcell.ColorDefault
+			} else {
+				span.Style.Attrs = 0
+			}
+		case i < 2:
+			clr := tcell.GetColor(strings.ToLower(p))
+			if clr == tcell.ColorDefault {
+				return span, false
+			}
+			*clrs[i] = clr
+		case i == 2:
+			var attrs tcell.AttrMask
+			for _, l := range p {
+				a, ok := attrLetters[l]
+				if !ok {
+					return span, false
+				}
+				attrs |= a
+			}
+			span.Style.Attrs = attrs
+		default:
+			span.Link = p
+			if p == "-" {
+				span.Link = ""
+			}
+		}
+	}
+	return span, true
+}
+
+// String returns the text without the styles
+func (t Text) String() string {
+	var sb strings.Builder
+	for _, s := range t {
+		sb.WriteString(s.Text)
+	}
+	return sb.String()
+}
+
+// Width returns the number of the screen cells the text takes
+func (t Text) Width() int {
+	w := 0
+	for _, s := range t {
+		w += StringWidth(s.Text)
+	}
+	return w
+}
+
+// Hotkey returns the first hotkey rune in the text, or 0 if there is no one
+func (t Text) Hotkey() rune {
+	for _, s := range t {
+		if s.Hotkey {
+			r, _ := utf8.DecodeRuneInString(s.Text)
+			return r
+		}
+	}
+	return 0
+}
+
+// Lines splits the text by the '\n' chars
+func (t Text) Lines() []Text {
+	res := []Text{nil}
+	for _, s := range t {
+		parts := strings.Split(s.Text, "\n")
+		for i, p := range parts {
+			if i > 0 {
+				res = append(res, nil)
+			}
+			if p != "" {
+				s1 := s
+				s1.Text = p
+				res[len(res)-1] = append(res[len(res)-1], s1)
+			}
+		}
+	}
+	return res
+}
+
+// TrimSpace returns the text without the leading and trailing white spaces
+func (t Text) TrimSpace() Text {
+	res := append(Text(nil), t...)
+	for len(res) > 0 {
+		res[0].Text = strings.TrimLeft(res[0].Text, " \t\r")
+		if res[0].Text != "" {
+			break
+		}
+		res = res[1:]
+	}
+	for len(res) > 0 {
+		last := len(res) - 1
+		res[last].Text = strings.TrimRight(res[last].Text, " \t\r")
+		if res[last].Text != "" {
+			break
+		}
+		res = res[:last]
+	}
+	return res
+}
+
+// Truncate returns the text, which takes no more than w cells
+func (t Text) Truncate(w int) Text {
+	var res Text
+	for _, s := range t {
+		sw := StringWidth(s.Text)
+		if sw > w {
+			s.Text = truncate(s.Text, w)
+			if s.Text != "" {
+				res = append(res, s)
+			}
+			return res
+		}
+		w -= sw
+		res = append(res, s)
+	}
+	return res
+}
+
+// StringWidth returns the number of the screen cells the string takes, it is the same number
+// of cells CanvasContext.Print uses for printing s
+func StringWidth(s string) int {
+	w, state := 0, -1
+	for s != "" {
+		var cl string
+		cl, s, _, state = uniseg.FirstGraphemeClusterInString(s, state)
+		w += ClusterWidth(cl)
+	}
+	return w
+}
+
+// ClusterWidth returns the number of the screen cells the grapheme cluster cl takes
+func ClusterWidth(cl string) int {
+	mainc, _ := clusterRunes(cl)
+	return runeWidth(mainc)
+}
+
+// truncate returns the prefix of s, which takes no more than w cells
+func truncate(s string, w int) string {
+	n, state := 0, -1
+	for rest := s; rest != ""; {
+		var cl string
+		cl, rest, _, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		cw := ClusterWidth(cl)
+		if cw > w {
+			break
+		}
+		w -= cw
+		n += len(cl)
+	}
+	return s[:n]
+}
@@ -0,0 +1,75 @@
+package twin
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseMarkup(t *testing.T) {
+	red := SpanStyle{Fg: tcell.ColorRed}
+	ul := SpanStyle{Attrs: tcell.AttrUnderline}
+	tests := []struct {
+		markup string
+		want   Text
+	}{
+		{"", nil},
+		{"plain", Text{{Text: "plain"}}},
+		{"[red]error[-] in [::b]main.go[-]: [yellow:blue:bu]warning[-]", Text{
+			{Text: "error", Style: red},
+			{Text: " in "},
+			{Text: "main.go", Style: SpanStyle{Attrs: tcell.AttrBold}},
+			{Text: ": "},
+			{Text: "warning", Style: SpanStyle{Fg: tcell.ColorYellow, Bg: tcell.ColorBlue, Attrs: tcell.AttrBold | tcell.AttrUnderline}},
+		}},
+		{"[red]a[:green]b", Text{{Text: "a", Style: red}, {Text: "b", Style: SpanStyle{Fg: tcell.ColorRed, Bg: tcell.ColorGreen}}}},
+		{"[red:blue]a[-:]b", Text{{Text: "a", Style: SpanStyle{Fg: tcell.ColorRed, Bg: tcell.ColorBlue}}, {Text: "b", Style: SpanStyle{Bg: tcell.ColorBlue}}}},
+		{"[#FF0000::u]a[::-]b", Text{{Text: "a", Style: SpanStyle{Fg: tcell.NewHexColor(0xff0000), Attrs: tcell.AttrUnderline}},
+			{Text: "b", Style: SpanStyle{Fg: tcell.NewHexColor(0xff0000)}}}},
+		{"see [:::https://x.org/a:b]docs[:::-]!", Text{{Text: "see "}, {Text: "docs", Link: "https://x.org/a:b"}, {Text: "!"}}},
+		{"[[not a tag]", Text{{Text: "[not a tag]"}}},
+		{"[nocolor]x", Text{{Text: "[nocolor]x"}}},
+		{"[::z]x", Text{{Text: "[::z]x"}}},
+		{"a[b", Text{{Text: "a[b"}}},
+		{"&Save", Text{{Text: "S", Style: ul, Hotkey: true}, {Text: "ave"}}},
+		{"[red]&Open", Text{{Text: "O", Style: SpanStyle{Fg: tcell.ColorRed, Attrs: tcell.AttrUnderline}, Hotkey: true}, {Text: "pen", Style: red}}},
+		{"&Жук", Text{{Text: "Ж", Style: ul, Hotkey: true}, {Text: "ук"}}},
+		{"a && b & c&", Text{{Text: "a & b & c&"}}},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, ParseMarkup(tc.markup), tc.markup)
+	}
+	assert.Equal(t, 'S', ParseMarkup("x &Save").Hotkey())
+	assert.Equal(t, rune(0), ParseMarkup("Save").Hotkey())
+}
+
+func TestSpanStyle_Apply(t *testing.T) {
+	base := tcell.StyleDefault.Foreground(tcell.ColorWhite).Background(tcell.ColorBlack).Bold(true)
+	assert.Equal(t, base, SpanStyle{}.Apply(base))
+	assert.Equal(t, base.Foreground(tcell.ColorRed).Underline(true),
+		SpanStyle{Fg: tcell.ColorRed, Attrs: tcell.AttrUnderline}.Apply(base))
+	assert.Equal(t, base.Background(tcell.ColorBlue), SpanStyle{Bg: tcell.ColorBlue}.Apply(base))
+}
+
+func TestText_Lines(t *testing.T) {
+	bold := SpanStyle{Attrs: tcell.AttrBold}
+	txt := Text{{Text: "a\nb"}, {Text: "c\n\nd\n", Style: bold}}
+	assert.Equal(t, []Text{
+		{{Text: "a"}},
+		{{Text: "b"}, {Text: "c", Style: bold}},
+		nil,
+		{{Text: "d", Style: bold}},
+		nil,
+	}, txt.Lines())
+	assert.Equal(t, []Text{nil}, Text(nil).Lines())
+}
+
+func TestText_TrimSpace(t *testing.T) {
+	bold := SpanStyle{Attrs: tcell.AttrBold}
+	txt := Text{{Text: "  "}, {Text: " a ", Style: bold}, {Text: "b \t"}, {Text: " "}}
+	assert.Equal(t, Text{{Text: "a ", Style: bold}, {Text: "b"}}, txt.TrimSpace())
+	// the original text is not changed
+	assert.Equal(t, " a ", txt[1].Text)
+	assert.Empty(t, Text{{Text: " \t "}}.TrimSpace())
+	assert.Equal(t, 3, Text{{Text: "a"}, {Text: "世"}}.Width())
+}
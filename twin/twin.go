@@ -3,7 +3,12 @@ package twin
 import (
 	"context"
 	"fmt"
+	"github.com/dspasibenko/twin-go/pkg/golibs/errors"
 	"github.com/gdamore/tcell/v2"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
 )
 
 // Component is the interface which all the twin objects should implement. So as the
@@ -20,6 +25,11 @@ type Component interface {
 	// IsVisible returns whether the component is visible or not
 	IsVisible() bool
 
+	// IsEnabled returns whether the component is enabled or not. The disabled component
+	// cannot be focused and doesn't receive the mouse events. Disabling a container disables
+	// all its descendants.
+	IsEnabled() bool
+
 	// Bounds returns the position and size of the component. The position is defined relative to the region
 	// of the parent component.
 	Bounds() Rectangle
@@ -55,6 +65,11 @@ type Component interface {
 	// handled and can be try by other component, it returns false
 	OnKeyPressed(ke *tcell.EventKey) bool
 
+	// OnPaste is called when the text is pasted to the terminal (bracketed paste). It must return
+	// true if the text is handled, otherwise the owner is tried, and if no component handles it,
+	// the pasted text is delivered as the key events.
+	OnPaste(text string) bool
+
 	// OnOwnerResized called if the owner is resized
 	OnOwnerResized()
 
@@ -65,6 +80,18 @@ type Component interface {
 
 	OnMouseWheel(p Point, wheel MouseWheel) bool
 
+	// OnMouseDrag notifies about the mouse button pressed (MouseDragStarted) on the point p, the
+	// mouse moved while the button is held (MouseDragMoved), and the button released
+	// (MouseDragEnded). The point is relative to the component top left corner, and it may be
+	// outside of the component while dragging. If the component returns false for
+	// MouseDragStarted, its owner is tried, otherwise the component receives the rest of the drag.
+	OnMouseDrag(p Point, state MouseDragState) bool
+
+	// OnLinkActivated is called when the hyperlink printed by the component (see
+	// CanvasContext.PrintText) is clicked, or Enter is pressed when the component is focused.
+	// If it returns false, the component owner is tried.
+	OnLinkActivated(url string) bool
+
 	// OnFocus is called when the component receives or loses the focus
 	OnFocus(focused bool)
 
@@ -79,6 +106,12 @@ type Component interface {
 	box() *Box
 }
 
+// Scroller is implemented by the components, which scroll their children, see ScrollIntoView()
+type Scroller interface {
+	// ScrollIntoView scrolls the component to make its descendant comp visible
+	ScrollIntoView(comp Component)
+}
+
 type MouseWheel int
 
 const (
@@ -88,6 +121,15 @@ const (
 	MouseWheelRight = MouseWheel(tcell.WheelRight)
 )
 
+// MouseDragState is the state of the mouse button, see Component.OnMouseDrag
+type MouseDragState int
+
+const (
+	MouseDragStarted = MouseDragState(iota)
+	MouseDragMoved
+	MouseDragEnded
+)
+
 type Rectangle struct {
 	X      int
 	Y      int
@@ -142,6 +184,39 @@ func (r Rectangle) Normalized() Rectangle {
 	return Rectangle{X: 0, Y: 0, Width: r.Width, Height: r.Height}
 }
 
+// IsEmpty returns true if the rectangle has no cells
+func (r Rectangle) IsEmpty() bool {
+	return r.Width <= 0 || r.Height <= 0
+}
+
+// Intersection returns the common part of r and o, or the empty rectangle if they don't overlap
+func (r Rectangle) Intersection(o Rectangle) Rectangle {
+	x1, y1 := max(r.X, o.X), max(r.Y, o.Y)
+	x2, y2 := min(r.X+r.Width, o.X+o.Width), min(r.Y+r.Height, o.Y+o.Height)
+	if x2 <= x1 || y2 <= y1 {
+		return Rectangle{}
+	}
+	return Rectangle{X: x1, Y: y1, Width: x2 - x1, Height: y2 - y1}
+}
+
+// Union returns the smallest rectangle, which contains both r and o. The empty rectangles are ignored.
+func (r Rectangle) Union(o Rectangle) Rectangle {
+	if r.IsEmpty() {
+		return o
+	}
+	if o.IsEmpty() {
+		return r
+	}
+	x1, y1 := min(r.X, o.X), min(r.Y, o.Y)
+	x2, y2 := max(r.X+r.Width, o.X+o.Width), max(r.Y+r.Height, o.Y+o.Height)
+	return Rectangle{X: x1, Y: y1, Width: x2 - x1, Height: y2 - y1}
+}
+
+// ContainsRect returns true if all cells of o are within r
+func (r Rectangle) ContainsRect(o Rectangle) bool {
+	return o.X >= r.X && o.Y >= r.Y && o.X+o.Width <= r.X+r.Width && o.Y+o.Height <= r.Y+r.Height
+}
+
 func (r Rectangle) String() string {
 	return fmt.Sprintf("Rectangle{X:%d, Y:%d, Width:%d, Height:%d}", r.X, r.Y, r.Width, r.Height)
 }
@@ -168,6 +243,16 @@ func Done() <-chan struct{} {
 	return c.done
 }
 
+// SetMaxFrameRate limits the number of the screen updates per second, 60 by default. The changes
+// made between the frames are drawn together in the next frame. fps <= 0 removes the limit.
+func SetMaxFrameRate(fps int) {
+	var interval int64
+	if fps > 0 {
+		interval = int64(time.Second) / int64(fps)
+	}
+	c.frameInterval.Store(interval)
+}
+
 // Root returns the root container for the all elements
 func Root() Component {
 	return c.root
@@ -183,6 +268,16 @@ func Close(comp Component) {
 	comp.box().close()
 }
 
+// IsEnabled returns whether the comp and all its owners are enabled
+func IsEnabled(comp Component) bool {
+	for ; comp != nil; comp = comp.box().owner {
+		if !comp.IsEnabled() {
+			return false
+		}
+	}
+	return true
+}
+
 // IsActive returns whether the comp is active or not
 func IsActive(comp Component) bool {
 	return comp.box().isActive()
@@ -200,16 +295,173 @@ func Owner(comp Component) Component {
 	return comp.box().owner
 }
 
+// Children returns the children of the comp
+func Children(comp Component) []Component {
+	return comp.box().children()
+}
+
+// RelativeBounds returns the comp bounds in the coordinates of the owner children, the owner
+// must be one of the comp owners (not necessarily the direct one)
+func RelativeBounds(comp, owner Component) Rectangle {
+	r := comp.Bounds()
+	for o := comp.box().owner; o != nil && o != owner; o = o.box().owner {
+		cb := o.ChildrenCanvasBounds()
+		vo := o.VirtualOffset()
+		r.X += cb.X - vo.X
+		r.Y += cb.Y - vo.Y
+	}
+	return r
+}
+
+// ScrollIntoView asks the owners of the comp, which implement Scroller, to scroll so the comp
+// becomes visible. The focus traversal calls it for the newly focused component.
+func ScrollIntoView(comp Component) {
+	for o := comp.box().owner; o != nil; o = o.box().owner {
+		if s, ok := o.(Scroller); ok {
+			s.ScrollIntoView(comp)
+		}
+	}
+}
+
 // NewModalPad creates a transparent component as an owner for a modal component. As soon as
 // a component put on the modal pad, call SetActive() for it to make the component behavior as modal one
-// The modal pad is closed by ESC button.
+// The modal pad is closed by ESC button. The modal pad is the focus scope, so the focus cannot leave it
+// by Tab or Backtab.
 func NewModalPad() Component {
 	m := &modalPad{}
 	_ = m.Init(c.root, m)
+	m.SetFocusScope(true)
+	m.SetBounds(c.root.Bounds())
+	return m
+}
+
+// NewDimmedModalPad creates the modal pad (see NewModalPad), which dims the components under it
+// by blending them with the backdrop color. alpha is in (0..1] range, the bigger the value the
+// closer the components colors to backdrop.
+func NewDimmedModalPad(backdrop tcell.Color, alpha float64) Component {
+	m := &modalPad{backdrop: backdrop, alpha: alpha}
+	_ = m.Init(c.root, m)
+	m.SetFocusScope(true)
 	m.SetBounds(c.root.Bounds())
 	return m
 }
 
+// SetActive is the same as Focus
 func SetActive(comp Component) {
-	c.s.PostEvent(&activateEvent{comp: comp})
+	Focus(comp)
+}
+
+// Focus moves the focus to comp. The comp and all its owners should be visible and
+// be able to be focused, otherwise the call is ignored.
+func Focus(comp Component) {
+	c.screen().PostEvent(&activateEvent{comp: comp})
+}
+
+// Focused returns the focused component, the deepest active one in the tree. It returns nil
+// if no component is focused.
+func Focused() Component {
+	comp := c.focused()
+	if comp == Component(c.root) {
+		return nil
+	}
+	return comp
+}
+
+// FocusNext moves the focus to the next component in the focus chain of the current focus scope
+func FocusNext() {
+	c.screen().PostEvent(&focusMoveEvent{dir: 1})
+}
+
+// FocusPrev moves the focus to the previous component in the focus chain of the current focus scope
+func FocusPrev() {
+	c.screen().PostEvent(&focusMoveEvent{dir: -1})
+}
+
+// AddFocusListener registers the fl to be notified when the focused component is changed. The
+// listener is called from the twin go-routine. The returned function removes the listener.
+func AddFocusListener(fl FocusListener) func() {
+	return c.addFocusListener(fl)
+}
+
+// Subscribe registers the cl to receive the tree-wide events (focus, add, close, bounds and
+// visibility changes) for the comp and all its descendants. If comp is nil, the events for all the components
+// are delivered. The listener is called from the twin go-routine. The returned function removes
+// the listener.
+func Subscribe(comp Component, cl ComponentListener) func() {
+	return c.subscribe(comp, cl)
+}
+
+// SetScreen makes twin draw to the screen s instead of the terminal, for example to the
+// tcell.NewSimulationScreen() in the tests. It must be called before the screen is used by
+// Run() or any other function, otherwise it returns an error.
+func SetScreen(s tcell.Screen) error {
+	set := false
+	c.screenOnce.Do(func() {
+		set = true
+		c.initScreen(s)
+	})
+	if !set {
+		return fmt.Errorf("the screen is initialized already: %w", errors.ErrExist)
+	}
+	return nil
+}
+
+// Invoke calls f from the twin go-routine, so f may change the components state the same way
+// the event handlers do. The functions are called in the order they are passed, none of them
+// is lost, even if the event queue is full.
+func Invoke(f func()) {
+	c.invoke(f)
+}
+
+// Animate registers the af to be called from the twin go-routine once per frame (see
+// SetMaxFrameRate), till it returns false. The returned function removes the animation.
+func Animate(af AnimationFunc) func() {
+	return c.addAnimation(af)
+}
+
+// SetTitle sets the terminal window title
+func SetTitle(title string) {
+	c.screen().SetTitle(title)
+}
+
+// AddTerminalListener registers the tl to be notified when the terminal window gains or loses
+// the focus, and when the terminal is released and taken back (see Suspend and Exec). The listener
+// is called from the twin go-routine. The returned function removes the listener.
+func AddTerminalListener(tl TerminalListener) func() {
+	return c.addTerminalListener(tl)
+}
+
+// IsTerminalFocused returns whether the terminal window has the focus. The terminals, which
+// don't report the focus changes, are considered always focused.
+func IsTerminalFocused() bool {
+	return !c.termUnfocused.Load()
+}
+
+// Suspend restores the terminal state and stops the process, as Ctrl+Z does in the shell. When the
+// process is continued (fg), the screen is redrawn. Ctrl+Z, which is not handled by the focused
+// component, suspends the process as well.
+func Suspend() {
+	c.screen().PostEvent(&suspendEvent{})
+}
+
+// Exec hands the terminal to the external program cmd, for example an editor. The nil cmd
+// standard streams are connected to the terminal. twin doesn't process the events while cmd
+// runs, and redraws the screen when it is finished. onDone, if not nil, is called with the cmd
+// result from the twin go-routine.
+func Exec(cmd *exec.Cmd, onDone func(err error)) {
+	c.screen().PostEvent(&execEvent{cmd: cmd, onDone: onDone})
+}
+
+// EditFile opens the file in the editor defined by $VISUAL or $EDITOR environment variable,
+// or vi if they are not set (see Exec)
+func EditFile(path string, onDone func(err error)) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := strings.Fields(editor)
+	Exec(exec.Command(args[0], append(args[1:], path)...), onDone)
 }
@@ -0,0 +1,796 @@
+package twin
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/uniseg"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// VTerm is the VT100/xterm terminal emulator. The program output written to it is parsed
+// into the cell grid, which may be drawn by Draw(). It supports the cursor movements, the
+// erasing, the scroll region, the SGR colors and attributes (16, 256 and RGB colors), the
+// alternate screen, the window title and OSC 8 hyperlinks. The unsupported sequences are
+// ignored. VTerm may be used from any go-routine.
+type VTerm struct {
+	lock sync.Mutex
+	size Size
+	// scr is the current screen, main or alt
+	scr, main, alt *CellBuffer
+	cur            Point
+	style          tcell.Style
+	link           string
+	// wrapNext is true when the char is printed in the last column, the next char is printed
+	// on the next line
+	wrapNext bool
+	saved    vtCursor
+	// top and bottom are the scroll region lines, bottom is exclusive
+	top, bottom   int
+	autoWrap      bool
+	cursorVisible bool
+	appCursor     bool
+	bracketed     bool
+	title         string
+	reply         io.Writer
+	// answers are written to the reply after the output is parsed
+	answers []byte
+
+	// the parser state
+	state  vtState
+	seq    []byte
+	pend   []byte
+	oscEsc bool
+}
+
+// vtCursor is the cursor state saved by DECSC
+type vtCursor struct {
+	cur   Point
+	style tcell.Style
+}
+
+type vtState int
+
+const (
+	vtGround = vtState(iota)
+	vtEscape
+	// vtCharset skips the charset designation char
+	vtCharset
+	vtCSI
+	vtOSC
+	// vtString skips DCS, SOS, PM and APC strings
+	vtString
+)
+
+// vtMaxSeq limits the length of the sequence, the longer ones are dropped
+const vtMaxSeq = 4096
+
+// NewVTerm creates the terminal of the size sz
+func NewVTerm(sz Size) *VTerm {
+	v := &VTerm{}
+	v.reset(sz)
+	return v
+}
+
+// SetReply sets the writer for the terminal answers to the program requests (the cursor
+// position report, the device attributes), it is usually the pty
+func (v *VTerm) SetReply(w io.Writer) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.reply = w
+}
+
+func (v *VTerm) reset(sz Size) {
+	sz.Width, sz.Height = max(1, sz.Width), max(1, sz.Height)
+	v.size = sz
+	v.main, v.alt = NewCellBuffer(sz), NewCellBuffer(sz)
+	v.main.Clear(tcell.StyleDefault)
+	v.alt.Clear(tcell.StyleDefault)
+	v.scr = v.main
+	v.cur, v.style, v.link, v.wrapNext = Point{}, tcell.StyleDefault, "", false
+	v.saved = vtCursor{style: tcell.StyleDefault}
+	v.top, v.bottom = 0, sz.Height
+	v.autoWrap, v.cursorVisible, v.appCursor, v.bracketed = true, true, false, false
+}
+
+// Size returns the terminal size
+func (v *VTerm) Size() Size {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.size
+}
+
+// Resize changes the terminal size, the content is kept from the top left corner
+func (v *VTerm) Resize(sz Size) {
+	sz.Width, sz.Height = max(1, sz.Width), max(1, sz.Height)
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if sz == v.size {
+		return
+	}
+	resize := func(cb *CellBuffer) *CellBuffer {
+		res := NewCellBuffer(sz)
+		res.Clear(tcell.StyleDefault)
+		for y := 0; y < min(sz.Height, v.size.Height); y++ {
+			copy(res.cells[y*sz.Width:y*sz.Width+min(sz.Width, v.size.Width)], cb.cells[y*v.size.Width:])
+			if last := &res.cells[y*sz.Width+sz.Width-1]; last.Width == 2 {
+				// the wide char is cut
+				*last = Cell{Main: ' ', Style: last.Style, Width: 1}
+			}
+		}
+		return res
+	}
+	alt := v.scr == v.alt
+	v.main, v.alt = resize(v.main), resize(v.alt)
+	v.scr = v.main
+	if alt {
+		v.scr = v.alt
+	}
+	v.size = sz
+	v.top, v.bottom = 0, sz.Height
+	v.cur = v.clamp(v.cur)
+	v.saved.cur = v.clamp(v.saved.cur)
+	v.wrapNext = false
+}
+
+// clamp returns the point p moved to the screen
+func (v *VTerm) clamp(p Point) Point {
+	return Point{X: max(0, min(p.X, v.size.Width-1)), Y: max(0, min(p.Y, v.size.Height-1))}
+}
+
+// restoreCursor restores the cursor saved by DECSC, it is kept on the screen, which could
+// be resized since then
+func (v *VTerm) restoreCursor() {
+	v.cur, v.style, v.wrapNext = v.clamp(v.saved.cur), v.saved.style, false
+}
+
+// Cursor returns the cursor position and whether it is visible
+func (v *VTerm) Cursor() (Point, bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.cur, v.cursorVisible
+}
+
+// Title returns the window title set by the program
+func (v *VTerm) Title() string {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.title
+}
+
+// Cell returns the screen cell at x, y
+func (v *VTerm) Cell(x, y int) Cell {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.scr.Cell(x, y)
+}
+
+// String returns the screen text, the lines are separated by '\n'
+func (v *VTerm) String() string {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.scr.String()
+}
+
+// Draw puts the screen to the canvas at the point p. The default colors of the cells are
+// replaced by the base style ones. If cursor is true, the visible cursor is drawn as the
+// reversed cell.
+func (v *VTerm) Draw(cc *CanvasContext, p Point, base tcell.Style, cursor bool) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	scr := v.scr
+	if base != tcell.StyleDefault {
+		scr = &CellBuffer{size: v.size, cells: make([]Cell, len(v.scr.cells))}
+		bfg, bbg, _ := base.Decompose()
+		for i, cl := range v.scr.cells {
+			fg, bg, _ := cl.Style.Decompose()
+			if fg == tcell.ColorDefault {
+				cl.Style = cl.Style.Foreground(bfg)
+			}
+			if bg == tcell.ColorDefault {
+				cl.Style = cl.Style.Background(bbg)
+			}
+			scr.cells[i] = cl
+		}
+	}
+	cc.DrawBuffer(p, scr)
+	if cursor && v.cursorVisible {
+		cl := scr.Cell(v.cur.X, v.cur.Y)
+		pp := cc.physicalPointXY(Point{X: p.X + v.cur.X, Y: p.Y + v.cur.Y})
+		if cl.Width > 0 && cc.physicalRegion().Contains(pp) {
+			_, _, attrs := cl.Style.Decompose()
+			cc.setContent(pp, cl.Main, cl.Comb, cl.Style.Reverse(attrs&tcell.AttrReverse == 0))
+		}
+	}
+}
+
+// AppCursorKeys returns whether the program turned the application cursor keys mode on
+// (DECCKM), the arrow keys are sent as SS3 sequences then
+func (v *VTerm) AppCursorKeys() bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.appCursor
+}
+
+// BracketedPaste returns whether the program expects the pasted text to be bracketed by
+// ESC [ 200 ~ and ESC [ 201 ~
+func (v *VTerm) BracketedPaste() bool {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	return v.bracketed
+}
+
+// Write parses the program output p, it returns the reply writer error only
+func (v *VTerm) Write(p []byte) (int, error) {
+	v.lock.Lock()
+	for _, b := range p {
+		v.parse(b)
+	}
+	answers, reply := v.answers, v.reply
+	v.answers = nil
+	v.lock.Unlock()
+	if len(answers) > 0 && reply != nil {
+		if _, err := reply.Write(answers); err != nil {
+			return len(p), fmt.Errorf("could not write the terminal answer: %w", err)
+		}
+	}
+	return len(p), nil
+}
+
+func (v *VTerm) parse(b byte) {
+	switch v.state {
+	case vtEscape:
+		v.escape(b)
+		return
+	case vtCharset:
+		v.state = vtGround
+		return
+	case vtCSI:
+		switch {
+		case b >= 0x40 && b <= 0x7e:
+			v.state = vtGround
+			v.csi(string(v.seq), b)
+		case b == 0x1b:
+			v.state = vtEscape
+		case b == 0x18 || b == 0x1a:
+			// CAN and SUB cancel the sequence
+			v.state = vtGround
+		case b < 0x20:
+			v.control(b)
+		case len(v.seq) < vtMaxSeq:
+			v.seq = append(v.seq, b)
+		}
+		return
+	case vtOSC, vtString:
+		switch {
+		case b == 0x07:
+			v.endString()
+		case v.oscEsc && b == '\\':
+			v.endString()
+		case b == 0x1b:
+			v.oscEsc = true
+		default:
+			v.oscEsc = false
+			if len(v.seq) < vtMaxSeq {
+				v.seq = append(v.seq, b)
+			}
+		}
+		return
+	}
+	if len(v.pend) > 0 && b < 0x80 {
+		// the UTF-8 sequence is broken
+		v.pend = v.pend[:0]
+		v.print(utf8.RuneError)
+	}
+	if b >= 0x80 {
+		v.pend = append(v.pend, b)
+		if !utf8.FullRune(v.pend) {
+			return
+		}
+		r, _ := utf8.DecodeRune(v.pend)
+		v.pend = v.pend[:0]
+		v.print(r)
+		return
+	}
+	switch {
+	case b == 0x1b:
+		v.state = vtEscape
+	case b < 0x20 || b == 0x7f:
+		v.control(b)
+	default:
+		v.print(rune(b))
+	}
+}
+
+func (v *VTerm) endString() {
+	if v.state == vtOSC {
+		v.osc(string(v.seq))
+	}
+	v.state, v.oscEsc = vtGround, false
+}
+
+// control executes the C0 control char
+func (v *VTerm) control(b byte) {
+	switch b {
+	case '\b':
+		if v.cur.X > 0 {
+			v.cur.X--
+		}
+		v.wrapNext = false
+	case '\t':
+		v.cur.X = min(v.size.Width-1, (v.cur.X/8+1)*8)
+		v.wrapNext = false
+	case '\n', '\v', '\f':
+		v.lineFeed()
+	case '\r':
+		v.cur.X = 0
+		v.wrapNext = false
+	}
+}
+
+func (v *VTerm) escape(b byte) {
+	v.state = vtGround
+	v.seq = v.seq[:0]
+	switch b {
+	case '[':
+		v.state = vtCSI
+	case ']':
+		v.state = vtOSC
+	case 'P', 'X', '^', '_':
+		v.state = vtString
+	case '(', ')', '*', '+':
+		v.state = vtCharset
+	case '7':
+		v.saved = vtCursor{cur: v.cur, style: v.style}
+	case '8':
+		v.restoreCursor()
+	case 'D':
+		v.lineFeed()
+	case 'E':
+		v.cur.X = 0
+		v.lineFeed()
+	case 'M':
+		v.reverseIndex()
+	case 'c':
+		v.reset(v.size)
+	}
+}
+
+// print puts the rune at the cursor and moves the cursor
+func (v *VTerm) print(r rune) {
+	if r >= 0x300 && uniseg.StringWidth(string(r)) == 0 {
+		// the combining mark is added to the previous cell
+		x := v.cur.X
+		if !v.wrapNext {
+			x--
+		}
+		if x >= 0 {
+			cl := &v.scr.cells[v.cur.Y*v.size.Width+x]
+			if cl.Width == 0 && x > 0 {
+				cl = &v.scr.cells[v.cur.Y*v.size.Width+x-1]
+			}
+			cl.Comb = append(cl.Comb, r)
+		}
+		return
+	}
+	w := runeWidth(r)
+	if v.wrapNext || (w == 2 && v.cur.X == v.size.Width-1) {
+		if v.autoWrap {
+			v.cur.X = 0
+			v.lineFeed()
+		}
+		v.wrapNext = false
+	}
+	if w == 2 && v.cur.X == v.size.Width-1 {
+		// no wrap, the wide char can't be placed
+		return
+	}
+	v.scr.SetContent(v.cur.X, v.cur.Y, r, nil, v.style)
+	if v.link != "" {
+		v.scr.setLink(v.cur.X, v.cur.Y, v.link)
+	}
+	v.cur.X += w
+	if v.cur.X >= v.size.Width {
+		v.cur.X = v.size.Width - 1
+		v.wrapNext = true
+	}
+}
+
+// lineFeed moves the cursor down, the scroll region is scrolled if the cursor is at its bottom
+func (v *VTerm) lineFeed() {
+	v.wrapNext = false
+	if v.cur.Y == v.bottom-1 {
+		v.scrollUp(v.top, 1)
+		return
+	}
+	v.cur.Y = min(v.size.Height-1, v.cur.Y+1)
+}
+
+func (v *VTerm) reverseIndex() {
+	v.wrapNext = false
+	if v.cur.Y == v.top {
+		v.scrollDown(v.top, 1)
+		return
+	}
+	v.cur.Y = max(0, v.cur.Y-1)
+}
+
+// scrollUp moves the lines from the line y to the scroll region bottom up by n lines
+func (v *VTerm) scrollUp(y, n int) {
+	w := v.size.Width
+	if n = min(n, v.bottom-y); n <= 0 {
+		return
+	}
+	copy(v.scr.cells[y*w:v.bottom*w], v.scr.cells[(y+n)*w:v.bottom*w])
+	v.erase(v.bottom-n, 0, v.bottom, 0)
+}
+
+// scrollDown moves the lines from the line y to the scroll region bottom down by n lines
+func (v *VTerm) scrollDown(y, n int) {
+	w := v.size.Width
+	if n = min(n, v.bottom-y); n <= 0 {
+		return
+	}
+	copy(v.scr.cells[(y+n)*w:v.bottom*w], v.scr.cells[y*w:(v.bottom-n)*w])
+	v.erase(y, 0, y+n, 0)
+}
+
+// erase clears the cells from (x1, y1) to (x2, y2) exclusive, line by line
+func (v *VTerm) erase(y1, x1, y2, x2 int) {
+	_, bg, _ := v.style.Decompose()
+	blank := Cell{Main: ' ', Style: tcell.StyleDefault.Background(bg), Width: 1}
+	from := max(0, y1*v.size.Width+x1)
+	to := min(len(v.scr.cells), y2*v.size.Width+x2)
+	for i := from; i < to; i++ {
+		v.scr.cells[i] = blank
+	}
+}
+
+// csi executes the control sequence with the parameters params and the final char
+func (v *VTerm) csi(params string, final byte) {
+	private := byte(0)
+	if params != "" && strings.IndexByte("<=>?", params[0]) >= 0 {
+		private, params = params[0], params[1:]
+	}
+	if i := strings.IndexAny(params, " !\"#$%&'()*+,-./"); i >= 0 {
+		// the intermediate chars select the sequences, which are not supported
+		return
+	}
+	var args []int
+	if params != "" {
+		for _, s := range strings.FieldsFunc(params, func(r rune) bool { return r == ';' || r == ':' }) {
+			n, _ := strconv.Atoi(s)
+			args = append(args, n)
+		}
+	}
+	// arg returns the i-th argument or the default value def, if it is missing or zero
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+	if private != 0 && final != 'h' && final != 'l' && final != 'c' {
+		return
+	}
+	w, h := v.size.Width, v.size.Height
+	// the cursor is always on the screen, the editing sequences below rely on it
+	v.cur = v.clamp(v.cur)
+	cur := &v.cur
+	if strings.IndexByte("mnchl", final) < 0 {
+		v.wrapNext = false
+	}
+	switch final {
+	case '@':
+		n := min(arg(0, 1), w-cur.X)
+		row := v.scr.cells[cur.Y*w : (cur.Y+1)*w]
+		copy(row[cur.X+n:], row[cur.X:])
+		v.erase(cur.Y, cur.X, cur.Y, cur.X+n)
+	case 'P':
+		n := min(arg(0, 1), w-cur.X)
+		row := v.scr.cells[cur.Y*w : (cur.Y+1)*w]
+		copy(row[cur.X:], row[cur.X+n:])
+		v.erase(cur.Y, w-n, cur.Y, w)
+	case 'X':
+		v.erase(cur.Y, cur.X, cur.Y, min(w, cur.X+arg(0, 1)))
+	case 'A':
+		cur.Y = max(v.topLimit(), cur.Y-arg(0, 1))
+	case 'B', 'e':
+		cur.Y = min(v.bottomLimit()-1, cur.Y+arg(0, 1))
+	case 'C', 'a':
+		cur.X = min(w-1, cur.X+arg(0, 1))
+	case 'D':
+		cur.X = max(0, cur.X-arg(0, 1))
+	case 'E':
+		cur.X, cur.Y = 0, min(v.bottomLimit()-1, cur.Y+arg(0, 1))
+	case 'F':
+		cur.X, cur.Y = 0, max(v.topLimit(), cur.Y-arg(0, 1))
+	case 'G', '`':
+		cur.X = min(w-1, arg(0, 1)-1)
+	case 'H', 'f':
+		cur.Y, cur.X = min(h-1, arg(0, 1)-1), min(w-1, arg(1, 1)-1)
+	case 'd':
+		cur.Y = min(h-1, arg(0, 1)-1)
+	case 'J':
+		switch arg(0, 0) {
+		case 0:
+			v.erase(cur.Y, cur.X, h, 0)
+		case 1:
+			v.erase(0, 0, cur.Y, cur.X+1)
+		case 2, 3:
+			v.erase(0, 0, h, 0)
+		}
+	case 'K':
+		switch arg(0, 0) {
+		case 0:
+			v.erase(cur.Y, cur.X, cur.Y+1, 0)
+		case 1:
+			v.erase(cur.Y, 0, cur.Y, cur.X+1)
+		case 2:
+			v.erase(cur.Y, 0, cur.Y+1, 0)
+		}
+	case 'L':
+		if cur.Y >= v.top && cur.Y < v.bottom {
+			v.scrollDown(cur.Y, arg(0, 1))
+			cur.X = 0
+		}
+	case 'M':
+		if cur.Y >= v.top && cur.Y < v.bottom {
+			v.scrollUp(cur.Y, arg(0, 1))
+			cur.X = 0
+		}
+	case 'S':
+		v.scrollUp(v.top, arg(0, 1))
+	case 'T':
+		v.scrollDown(v.top, arg(0, 1))
+	case 'm':
+		v.sgr(args)
+	case 'r':
+		top, bottom := arg(0, 1)-1, min(h, arg(1, h))
+		if top < bottom-1 {
+			v.top, v.bottom = top, bottom
+			*cur = Point{}
+		}
+	case 's':
+		v.saved = vtCursor{cur: *cur, style: v.style}
+	case 'u':
+		v.restoreCursor()
+	case 'h', 'l':
+		if private == '?' {
+			for _, a := range args {
+				v.setMode(a, final == 'h')
+			}
+		}
+	case 'n':
+		switch arg(0, 0) {
+		case 5:
+			v.answer("\x1b[0n")
+		case 6:
+			v.answer(fmt.Sprintf("\x1b[%d;%dR", cur.Y+1, cur.X+1))
+		}
+	case 'c':
+		switch private {
+		case 0:
+			v.answer("\x1b[?62;22c")
+		case '>':
+			v.answer("\x1b[>0;0;0c")
+		}
+	}
+}
+
+// topLimit and bottomLimit return the lines the cursor may be moved between, the scroll
+// region ones if the cursor is in the region
+func (v *VTerm) topLimit() int {
+	if v.cur.Y >= v.top {
+		return v.top
+	}
+	return 0
+}
+
+func (v *VTerm) bottomLimit() int {
+	if v.cur.Y < v.bottom {
+		return v.bottom
+	}
+	return v.size.Height
+}
+
+func (v *VTerm) setMode(mode int, on bool) {
+	switch mode {
+	case 1:
+		v.appCursor = on
+	case 7:
+		v.autoWrap = on
+	case 25:
+		v.cursorVisible = on
+	case 2004:
+		v.bracketed = on
+	case 47, 1047, 1049:
+		if on == (v.scr == v.alt) {
+			return
+		}
+		if mode == 1049 && on {
+			v.saved = vtCursor{cur: v.cur, style: v.style}
+		}
+		if on {
+			v.scr = v.alt
+			v.erase(0, 0, v.size.Height, 0)
+		} else {
+			v.scr = v.main
+		}
+		if mode == 1049 && !on {
+			v.restoreCursor()
+		}
+	}
+}
+
+// sgr sets the style attributes and colors
+func (v *VTerm) sgr(args []int) {
+	if len(args) == 0 {
+		args = []int{0}
+	}
+	s := v.style
+	for i := 0; i < len(args); i++ {
+		switch a := args[i]; {
+		case a == 0:
+			s = tcell.StyleDefault
+		case a == 1:
+			s = s.Bold(true)
+		case a == 2:
+			s = s.Dim(true)
+		case a == 3:
+			s = s.Italic(true)
+		case a == 4:
+			s = s.Underline(true)
+		case a == 5:
+			s = s.Blink(true)
+		case a == 7:
+			s = s.Reverse(true)
+		case a == 9:
+			s = s.StrikeThrough(true)
+		case a == 21 || a == 22:
+			s = s.Bold(false).Dim(false)
+		case a == 23:
+			s = s.Italic(false)
+		case a == 24:
+			s = s.Underline(false)
+		case a == 25:
+			s = s.Blink(false)
+		case a == 27:
+			s = s.Reverse(false)
+		case a == 29:
+			s = s.StrikeThrough(false)
+		case a >= 30 && a <= 37:
+			s = s.Foreground(tcell.PaletteColor(a - 30))
+		case a >= 90 && a <= 97:
+			s = s.Foreground(tcell.PaletteColor(a - 90 + 8))
+		case a == 39:
+			s = s.Foreground(tcell.ColorDefault)
+		case a >= 40 && a <= 47:
+			s = s.Background(tcell.PaletteColor(a - 40))
+		case a >= 100 && a <= 107:
+			s = s.Background(tcell.PaletteColor(a - 100 + 8))
+		case a == 49:
+			s = s.Background(tcell.ColorDefault)
+		case a == 38 || a == 48:
+			var clr tcell.Color
+			clr, i = sgrColor(args, i)
+			if clr == tcell.ColorDefault {
+				continue
+			}
+			if a == 38 {
+				s = s.Foreground(clr)
+			} else {
+				s = s.Background(clr)
+			}
+		}
+	}
+	v.style = s
+}
+
+// sgrColor parses the extended color (5;n or 2;r;g;b) after args[i], it returns the color and
+// the index of its last argument
+func sgrColor(args []int, i int) (tcell.Color, int) {
+	switch {
+	case i+2 < len(args) && args[i+1] == 5:
+		return tcell.PaletteColor(args[i+2] & 0xff), i + 2
+	case i+4 < len(args) && args[i+1] == 2:
+		return tcell.NewRGBColor(int32(args[i+2]), int32(args[i+3]), int32(args[i+4])), i + 4
+	}
+	return tcell.ColorDefault, len(args)
+}
+
+// osc executes the operating system command, only the title and the hyperlinks are supported
+func (v *VTerm) osc(cmd string) {
+	num, arg, _ := strings.Cut(cmd, ";")
+	switch num {
+	case "0", "2":
+		v.title = arg
+	case "8":
+		// the parameters before the URL are ignored
+		_, url, _ := strings.Cut(arg, ";")
+		v.link = url
+	}
+}
+
+// answer adds the reply to the program request
+func (v *VTerm) answer(s string) {
+	v.answers = append(v.answers, s...)
+}
+
+// KeySequence returns the bytes the xterm sends for the key ke, or nil if the key has no sequence
+func (v *VTerm) KeySequence(ke *tcell.EventKey) []byte {
+	mod := ke.Modifiers()
+	seq := ""
+	switch k := ke.Key(); {
+	case k == tcell.KeyRune:
+		r := ke.Rune()
+		if mod&tcell.ModCtrl != 0 && (r >= '@' && r <= '_' || r >= 'a' && r <= 'z') {
+			seq = string(rune(r & 0x1f))
+		} else {
+			seq = string(r)
+		}
+		if mod&tcell.ModAlt != 0 {
+			seq = "\x1b" + seq
+		}
+		return []byte(seq)
+	case k == tcell.KeyBacktab:
+		return []byte("\x1b[Z")
+	case k <= 0x1f || k == tcell.KeyDEL:
+		if mod&tcell.ModAlt != 0 {
+			return []byte{0x1b, byte(k)}
+		}
+		return []byte{byte(k)}
+	}
+	if s, ok := vtCursorKeys[ke.Key()]; ok {
+		switch {
+		case mod&(tcell.ModShift|tcell.ModAlt|tcell.ModCtrl) != 0:
+			return []byte(fmt.Sprintf("\x1b[1;%d%c", vtModifiers(mod), s))
+		case v.AppCursorKeys():
+			return []byte{0x1b, 'O', s}
+		}
+		return []byte{0x1b, '[', s}
+	}
+	if s, ok := vtFunctionKeys[ke.Key()]; ok {
+		if mod&(tcell.ModShift|tcell.ModAlt|tcell.ModCtrl) != 0 {
+			if s[0] == 'O' {
+				return []byte(fmt.Sprintf("\x1b[1;%d%s", vtModifiers(mod), s[1:]))
+			}
+			return []byte(fmt.Sprintf("\x1b[%s;%d~", strings.TrimSuffix(s, "~"), vtModifiers(mod)))
+		}
+		if s[0] == 'O' {
+			return []byte("\x1b" + s)
+		}
+		return []byte("\x1b[" + s)
+	}
+	return nil
+}
+
+// vtModifiers returns the xterm modifiers parameter
+func vtModifiers(mod tcell.ModMask) int {
+	res := 1
+	if mod&tcell.ModShift != 0 {
+		res++
+	}
+	if mod&tcell.ModAlt != 0 {
+		res += 2
+	}
+	if mod&tcell.ModCtrl != 0 {
+		res += 4
+	}
+	return res
+}
+
+var vtCursorKeys = map[tcell.Key]byte{
+	tcell.KeyUp: 'A', tcell.KeyDown: 'B', tcell.KeyRight: 'C', tcell.KeyLeft: 'D',
+	tcell.KeyHome: 'H', tcell.KeyEnd: 'F',
+}
+
+var vtFunctionKeys = map[tcell.Key]string{
+	tcell.KeyInsert: "2~", tcell.KeyDelete: "3~", tcell.KeyPgUp: "5~", tcell.KeyPgDn: "6~",
+	tcell.KeyF1: "OP", tcell.KeyF2: "OQ", tcell.KeyF3: "OR", tcell.KeyF4: "OS",
+	tcell.KeyF5: "15~", tcell.KeyF6: "17~", tcell.KeyF7: "18~", tcell.KeyF8: "19~",
+	tcell.KeyF9: "20~", tcell.KeyF10: "21~", tcell.KeyF11: "23~", tcell.KeyF12: "24~",
+}
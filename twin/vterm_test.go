@@ -0,0 +1,140 @@
+package twin
+
+import (
+	"bytes"
+	"github.com/gdamore/tcell/v2"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func vtWrite(v *VTerm, s string) {
+	_, _ = v.Write([]byte(s))
+}
+
+func TestVTerm_Print(t *testing.T) {
+	v := NewVTerm(Size{Width: 5, Height: 3})
+	vtWrite(v, "ab\r\ncdefgh")
+	assert.Equal(t, "ab   \ncdefg\nh    ", v.String())
+	p, visible := v.Cursor()
+	assert.Equal(t, Point{X: 1, Y: 2}, p)
+	assert.True(t, visible)
+
+	// the last line is scrolled up
+	vtWrite(v, "\n\n")
+	assert.Equal(t, "h    \n     \n     ", v.String())
+
+	// the UTF-8 runes split between the writes, the wide and the combining chars
+	v = NewVTerm(Size{Width: 5, Height: 2})
+	b := []byte("日é́")
+	_, _ = v.Write(b[:2])
+	_, _ = v.Write(b[2:])
+	assert.Equal(t, "日é́  \n     ", v.String())
+	assert.Equal(t, 0, v.Cell(1, 0).Width)
+}
+
+func TestVTerm_CSI(t *testing.T) {
+	v := NewVTerm(Size{Width: 6, Height: 4})
+	vtWrite(v, "aaaaaa\r\nbbbbbb\r\ncccccc\r\ndddddd")
+	vtWrite(v, "\x1b[2;3H\x1b[K")
+	assert.Equal(t, "aaaaaa\nbb    \ncccccc\ndddddd", v.String())
+	vtWrite(v, "\x1b[1J")
+	assert.Equal(t, "      \n      \ncccccc\ndddddd", v.String())
+	vtWrite(v, "\x1b[3;2H\x1b[2P\x1b[@")
+	assert.Equal(t, "      \n      \nc ccc \ndddddd", v.String())
+	vtWrite(v, "\x1b[L")
+	assert.Equal(t, "      \n      \n      \nc ccc ", v.String())
+	vtWrite(v, "\x1b[2J\x1b[Hx\x1b[3Cy\x1b[2Bz\x1b[10Dw")
+	assert.Equal(t, "x   y \n      \nw    z\n      ", v.String())
+}
+
+func TestVTerm_ScrollRegion(t *testing.T) {
+	v := NewVTerm(Size{Width: 3, Height: 4})
+	vtWrite(v, "111\r\n222\r\n333\r\n444")
+	vtWrite(v, "\x1b[2;3r\x1b[3;1H\n")
+	assert.Equal(t, "111\n333\n   \n444", v.String())
+	vtWrite(v, "\x1b[2;1H\x1bM")
+	assert.Equal(t, "111\n   \n333\n444", v.String())
+}
+
+func TestVTerm_SGR(t *testing.T) {
+	v := NewVTerm(Size{Width: 5, Height: 1})
+	vtWrite(v, "\x1b[1;31ma\x1b[38;5;200;48;2;1;2;3mb\x1b[0mc\x1b[7;94md")
+	assert.Equal(t, tcell.StyleDefault.Bold(true).Foreground(tcell.ColorMaroon), v.Cell(0, 0).Style)
+	assert.Equal(t, tcell.StyleDefault.Bold(true).Foreground(tcell.PaletteColor(200)).Background(tcell.NewRGBColor(1, 2, 3)),
+		v.Cell(1, 0).Style)
+	assert.Equal(t, tcell.StyleDefault, v.Cell(2, 0).Style)
+	assert.Equal(t, tcell.StyleDefault.Reverse(true).Foreground(tcell.ColorBlue), v.Cell(3, 0).Style)
+}
+
+func TestVTerm_Modes(t *testing.T) {
+	v := NewVTerm(Size{Width: 3, Height: 2})
+	vtWrite(v, "ab\x1b[?1049h\x1b[?25l\x1b[?1h\x1b[?2004hx")
+	assert.Equal(t, "  x\n   ", v.String())
+	_, visible := v.Cursor()
+	assert.False(t, visible)
+	assert.True(t, v.AppCursorKeys())
+	assert.True(t, v.BracketedPaste())
+	assert.Equal(t, []byte("\x1bOA"), v.KeySequence(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)))
+
+	vtWrite(v, "\x1b[?1049l\x1b]0;title\x07\x1b]8;;http://a\x1b\\d")
+	assert.Equal(t, "abd\n   ", v.String())
+	assert.Equal(t, "title", v.Title())
+	assert.Equal(t, "http://a", v.Cell(2, 0).Link)
+}
+
+func TestVTerm_Answers(t *testing.T) {
+	v := NewVTerm(Size{Width: 10, Height: 5})
+	var out bytes.Buffer
+	v.SetReply(&out)
+	vtWrite(v, "\x1b[3;4H\x1b[6n\x1b[c")
+	assert.Equal(t, "\x1b[3;4R\x1b[?62;22c", out.String())
+}
+
+func TestVTerm_KeySequence(t *testing.T) {
+	v := NewVTerm(Size{Width: 10, Height: 5})
+	for _, tc := range []struct {
+		ke  *tcell.EventKey
+		seq string
+	}{
+		{tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone), "a"},
+		{tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModAlt), "\x1ba"},
+		{tcell.NewEventKey(tcell.KeyCtrlC, 0, tcell.ModCtrl), "\x03"},
+		{tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone), "\r"},
+		{tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone), "\x7f"},
+		{tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone), "\x1b[A"},
+		{tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModCtrl), "\x1b[1;5C"},
+		{tcell.NewEventKey(tcell.KeyPgDn, 0, tcell.ModNone), "\x1b[6~"},
+		{tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModShift), "\x1b[3;2~"},
+		{tcell.NewEventKey(tcell.KeyF1, 0, tcell.ModNone), "\x1bOP"},
+		{tcell.NewEventKey(tcell.KeyF12, 0, tcell.ModNone), "\x1b[24~"},
+	} {
+		assert.Equal(t, tc.seq, string(v.KeySequence(tc.ke)), tc.ke.Name())
+	}
+}
+
+func TestVTerm_Resize(t *testing.T) {
+	v := NewVTerm(Size{Width: 4, Height: 2})
+	vtWrite(v, "abcd\r\nef")
+	v.Resize(Size{Width: 2, Height: 3})
+	assert.Equal(t, strings.Join([]string{"ab", "ef", "  "}, "\n"), v.String())
+	p, _ := v.Cursor()
+	assert.Equal(t, Point{X: 1, Y: 1}, p)
+}
+
+func TestVTerm_ResizeSavedCursor(t *testing.T) {
+	v := NewVTerm(Size{Width: 120, Height: 40})
+	vtWrite(v, "\x1b[30;100H\x1b7")
+	v.Resize(Size{Width: 80, Height: 24})
+	assert.NotPanics(t, func() { vtWrite(v, "\x1b8\x1b[@\x1b[P\x1b[K") })
+	p, _ := v.Cursor()
+	assert.Equal(t, Point{X: 79, Y: 23}, p)
+
+	vtWrite(v, "\x1b[?1049h")
+	v.Resize(Size{Width: 10, Height: 5})
+	assert.NotPanics(t, func() { vtWrite(v, "\x1b[?1049l\x1b[@x\x1b[s") })
+	v.Resize(Size{Width: 4, Height: 2})
+	assert.NotPanics(t, func() { vtWrite(v, "\x1b[u\x1b[2P\x1b[1K") })
+	p, _ = v.Cursor()
+	assert.Equal(t, Point{X: 3, Y: 1}, p)
+}